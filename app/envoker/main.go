@@ -9,6 +9,7 @@ import (
 
 	"github.com/jrazmi/envoker/bridge/repositories/tasksrepobridge"
 	"github.com/jrazmi/envoker/bridge/repositories/usersrepobridge"
+	"github.com/jrazmi/envoker/bridge/scaffolding/metrics"
 	"github.com/jrazmi/envoker/bridge/scaffolding/mid"
 	"github.com/jrazmi/envoker/core/repositories/tasksrepo"
 	"github.com/jrazmi/envoker/core/repositories/tasksrepo/stores/taskspgxstore"
@@ -18,6 +19,7 @@ import (
 	"github.com/jrazmi/envoker/infrastructure/web"
 	"github.com/jrazmi/envoker/sdk/environment"
 	"github.com/jrazmi/envoker/sdk/logger"
+	"github.com/jrazmi/envoker/sdk/promtext"
 	"github.com/jrazmi/envoker/sdk/telemetry"
 )
 
@@ -107,6 +109,11 @@ func run(ctx context.Context, log *logger.Logger) error {
 		Repositories: repositories,
 	})
 
+	// Worker pools register their PrometheusMetrics here too
+	registry := promtext.NewRegistry()
+	registry.Register(metrics.Collector())
+	webHandler.GET("/metrics", metrics.Handler(registry))
+
 	// ==============================================================================
 
 	// WEB SERVER
@@ -232,8 +232,8 @@ func buildFilterBridgeFields(columns []schema.Column) []BridgeField {
 			continue
 		}
 
-		// Skip JSONB fields (not filterable - no schema)
-		if strings.Contains(col.GoType, "json.RawMessage") || strings.Contains(col.DBType, "jsonb") {
+		// Skip JSONB fields (not filterable - no schema) and binary fields
+		if strings.Contains(col.GoType, "json.RawMessage") || strings.Contains(col.DBType, "jsonb") || col.DBType == "bytea" {
 			continue
 		}
 
@@ -164,6 +164,7 @@ func buildUpdateFields(columns []schema.Column, pk schema.PrimaryKeyInfo) []Fiel
 			continue
 		}
 
+		// Slices (e.g. bytea) are already nillable and stay as they are
 		goType := col.GoType
 		if !strings.HasPrefix(goType, "*") && !strings.HasPrefix(goType, "[]") {
 			goType = "*" + goType
@@ -173,7 +174,7 @@ func buildUpdateFields(columns []schema.Column, pk schema.PrimaryKeyInfo) []Fiel
 			Name:       schema.ToPascalCase(col.Name),
 			DBColumn:   col.Name,
 			GoType:     goType,
-			IsPointer:  true,
+			IsPointer:  strings.HasPrefix(goType, "*"),
 			IsNullable: true,
 		}
 		fields = append(fields, field)
@@ -284,8 +285,8 @@ func buildFilterFieldsForFop(columns []schema.Column) []FilterFieldForFop {
 			continue
 		}
 
-		// Skip JSONB columns - filtering entire JSON blobs doesn't make sense
-		if strings.Contains(col.GoType, "json.RawMessage") || strings.Contains(col.DBType, "jsonb") {
+		// Skip JSONB and binary columns - filtering entire blobs doesn't make sense
+		if strings.Contains(col.GoType, "json.RawMessage") || strings.Contains(col.DBType, "jsonb") || col.DBType == "bytea" {
 			continue
 		}
 
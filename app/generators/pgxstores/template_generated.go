@@ -106,7 +106,7 @@ func (s *GeneratedStore) Update(ctx context.Context, {{.PKParamName}} {{.PKGoTyp
 {{- range .UpdateFields}}
 	if input.{{.Name}} != nil {
 		fields = append(fields, "{{.DBColumn}} = @{{.DBColumn}}")
-		args["{{.DBColumn}}"] = *input.{{.Name}}
+		args["{{.DBColumn}}"] = {{if .IsPointer}}*{{end}}input.{{.Name}}
 	}
 {{- end}}
 
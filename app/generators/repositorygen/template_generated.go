@@ -79,7 +79,7 @@ type Generated{{.EntityName}}Filter struct {
 {{- else if eq .Name "updated_at"}}
 	UpdatedAtBefore *time.Time ` + "`" + `json:"updated_at_before,omitempty"` + "`" + ` // Filter by updated_at < value
 	UpdatedAtAfter  *time.Time ` + "`" + `json:"updated_at_after,omitempty"` + "`" + ` // Filter by updated_at > value
-{{- else if and (not .IsPrimaryKey) (not (Contains .GoType "json.RawMessage"))}}
+{{- else if and (not .IsPrimaryKey) (not (Contains .GoType "json.RawMessage")) (ne .DBType "bytea")}}
 	{{ToPascalCase .Name}} *{{TrimPrefix .GoType "*"}} ` + "`" + `json:"{{.Name}},omitempty"` + "`" + ` // Filter by {{.Name}}
 {{- end}}
 {{- end}}
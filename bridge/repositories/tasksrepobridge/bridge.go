@@ -7,7 +7,20 @@
 
 package tasksrepobridge
 
-import "github.com/jrazmi/envoker/core/repositories/tasksrepo"
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+
+	"github.com/jrazmi/envoker/bridge/scaffolding/errs"
+	"github.com/jrazmi/envoker/bridge/scaffolding/fopbridge"
+	"github.com/jrazmi/envoker/core/repositories/tasksrepo"
+	"github.com/jrazmi/envoker/core/scaffolding/fop"
+	"github.com/jrazmi/envoker/infrastructure/web"
+)
 
 // ========================================
 // BRIDGE STRUCT WITH EMBEDDING
@@ -18,6 +31,7 @@ import "github.com/jrazmi/envoker/core/repositories/tasksrepo"
 // Override any method by defining it on this struct.
 type bridge struct {
 	GeneratedBridge
+	payloads PayloadValidator // nil accepts any payload
 }
 
 // newBridge creates a new Task bridge
@@ -28,3 +42,179 @@ func newBridge(taskRepository *tasksrepo.Repository) *bridge {
 		},
 	}
 }
+
+// ========================================
+// DEAD LETTER HANDLERS
+// ========================================
+
+// httpListDead handles GET requests for listing dead-lettered tasks.
+// Accepts the same filters, ordering and pagination as httpList.
+func (b *bridge) httpListDead(ctx context.Context, r *http.Request) web.Encoder {
+	qp := parseGeneratedQueryParams(r)
+
+	page, err := fop.ParsePageStringCursor(qp.Limit, qp.Cursor)
+	if err != nil {
+		return errs.NewFieldErrors("page", err)
+	}
+
+	filter, err := parseGeneratedFilter(qp)
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "invalid filter: %s", err)
+	}
+
+	orderBy := parseGeneratedOrderBy(qp.Order)
+
+	records, pagination, err := b.taskRepository.ListDead(ctx, filter, orderBy, page)
+	if err != nil {
+		return errs.Newf(errs.Internal, "list dead Tasks: %s", err)
+	}
+
+	return fopbridge.NewPaginatedResult(records, pagination)
+}
+
+// httpGetDead handles GET requests for inspecting a dead-lettered task and its error history
+func (b *bridge) httpGetDead(ctx context.Context, r *http.Request) web.Encoder {
+	qpath, err := parseGeneratedPath(r)
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "invalid path arguments: %s", err)
+	}
+
+	record, err := b.taskRepository.GetDead(ctx, qpath.TaskId)
+	if err != nil {
+		return errs.Newf(errs.NotFound, "dead task not found: %v", qpath.TaskId)
+	}
+
+	return fopbridge.NewRecordResponse(record)
+}
+
+// httpRequeueDead handles POST requests for moving a dead-lettered task back to the queue
+func (b *bridge) httpRequeueDead(ctx context.Context, r *http.Request) web.Encoder {
+	qpath, err := parseGeneratedPath(r)
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "invalid path arguments: %s", err)
+	}
+
+	err = b.taskRepository.Requeue(ctx, qpath.TaskId)
+	if err != nil {
+		if errors.Is(err, tasksrepo.ErrTaskNotDead) {
+			return errs.Newf(errs.NotFound, "dead task not found: %v", qpath.TaskId)
+		}
+		return errs.Newf(errs.Internal, "requeue task: %s", err)
+	}
+
+	return fopbridge.NewCodeResponse(errs.OK.String(), "Task requeued successfully")
+}
+
+// httpPurgeDead handles DELETE requests for removing dead-lettered tasks.
+// The optional before query param (RFC3339) only purges tasks dead-lettered before that time.
+func (b *bridge) httpPurgeDead(ctx context.Context, r *http.Request) web.Encoder {
+	before := time.Now()
+	if qp := web.QueryParam(r, "before"); qp != "" {
+		t, err := time.Parse(time.RFC3339, qp)
+		if err != nil {
+			return errs.Newf(errs.InvalidArgument, "invalid before format: %s", qp)
+		}
+		before = t
+	}
+
+	purged, err := b.taskRepository.PurgeDead(ctx, before)
+	if err != nil {
+		return errs.Newf(errs.Internal, "purge dead tasks: %s", err)
+	}
+
+	return fopbridge.NewRecordResponse(PurgeResult{Purged: purged})
+}
+
+// ========================================
+// ENQUEUE HANDLERS
+// ========================================
+
+// httpEnqueue handles POST requests for submitting a typed task. The payload is checked against
+// the handler registered for the task type before the task is created. Responds with a handle
+// whose task_id can be polled through httpStatus.
+func (b *bridge) httpEnqueue(ctx context.Context, r *http.Request) web.Encoder {
+	var input EnqueueRequest
+	if err := web.Decode(r, &input); err != nil {
+		return errs.Newf(errs.InvalidArgument, "decode: %s", err)
+	}
+
+	if b.payloads != nil {
+		if err := b.payloads.ValidatePayload(input.TaskType, input.Payload); err != nil {
+			if errors.Is(err, workers.ErrUnknownTaskType) {
+				return errs.NewFieldErrors("task_type", err)
+			}
+			return errs.NewFieldErrors("payload", err)
+		}
+	}
+
+	handle, err := b.taskRepository.Enqueue(ctx, input.TaskType, input.Payload, input.options()...)
+	if err != nil {
+		if errors.Is(err, tasksrepo.ErrDependencyNotFound) {
+			return errs.NewFieldErrors("depends_on", err)
+		}
+		return errs.Newf(errs.Internal, "enqueue task: %s", err)
+	}
+
+	return fopbridge.NewRecordResponse(handle)
+}
+
+// httpStatus handles GET requests for polling an enqueued task's status
+func (b *bridge) httpStatus(ctx context.Context, r *http.Request) web.Encoder {
+	qpath, err := parseGeneratedPath(r)
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "invalid path arguments: %s", err)
+	}
+
+	status, err := b.taskRepository.Status(ctx, qpath.TaskId)
+	if err != nil {
+		return errs.Newf(errs.NotFound, "task not found: %v", qpath.TaskId)
+	}
+
+	return fopbridge.NewRecordResponse(status)
+}
+
+// httpWorkflowStatus handles GET requests for the status of a workflow and its tasks
+func (b *bridge) httpWorkflowStatus(ctx context.Context, r *http.Request) web.Encoder {
+	workflowId := r.PathValue("workflow_id")
+	if workflowId == "" {
+		return errs.Newf(errs.InvalidArgument, "invalid path arguments: workflow_id is required")
+	}
+
+	status, err := b.taskRepository.WorkflowStatus(ctx, workflowId)
+	if err != nil {
+		if errors.Is(err, tasksrepo.ErrWorkflowNotFound) {
+			return errs.Newf(errs.NotFound, "workflow not found: %v", workflowId)
+		}
+		return errs.Newf(errs.Internal, "workflow status: %s", err)
+	}
+
+	return fopbridge.NewRecordResponse(status)
+}
+
+// ========================================
+// CANCELLATION HANDLERS
+// ========================================
+
+// httpCancel handles POST requests for cancelling a task. A pending task is cancelled right away;
+// a processing task is flagged and its worker cancels it on its next lease heartbeat, so poll
+// httpStatus to see it end.
+func (b *bridge) httpCancel(ctx context.Context, r *http.Request) web.Encoder {
+	qpath, err := parseGeneratedPath(r)
+	if err != nil {
+		return errs.Newf(errs.InvalidArgument, "invalid path arguments: %s", err)
+	}
+
+	status, err := b.taskRepository.RequestCancel(ctx, qpath.TaskId)
+	if err != nil {
+		if errors.Is(err, tasksrepo.ErrTaskNotCancellable) {
+			return errs.Newf(errs.FailedPrecondition, "task not pending or processing: %v", qpath.TaskId)
+		}
+		return errs.Newf(errs.Internal, "cancel task: %s", err)
+	}
+
+	result := CancelResult{TaskId: qpath.TaskId, Status: CancelCancelled}
+	if status != tasksrepo.StatusCancelled {
+		result.Status = CancelRequested
+	}
+	return fopbridge.NewRecordResponse(result)
+}
@@ -28,15 +28,26 @@ type generatedQueryParams struct {
 	Cursor string
 	Order  string
 	// Filter fields
-	SearchTerm       string
-	ProcessingStatus string
-	TaskType         string
-	Priority         string
-	MaxRetries       string
-	RetryCount       string
-	ErrorMessage     string
-	ProcessingTimeMs string
-	LastRunAt        string
+	SearchTerm           string
+	ProcessingStatus     string
+	TaskType             string
+	Priority             string
+	MaxRetries           string
+	RetryCount           string
+	ErrorMessage         string
+	ProcessingTimeMs     string
+	LastRunAt            string
+	LockedBy             string
+	LeaseExpiresAt       string
+	DeadAt               string
+	RunAt                string
+	IdempotencyKey       string
+	IdempotencyExpiresAt string
+	WorkflowId           string
+	CancelRequestedAt    string
+	ProgressPercent      string
+	ProgressStep         string
+	ProgressUpdatedAt    string
 }
 
 // generatedPathParams holds path parameter values (parsed to their actual types)
@@ -48,18 +59,29 @@ type generatedPathParams struct {
 func parseGeneratedQueryParams(r *http.Request) generatedQueryParams {
 	q := r.URL.Query()
 	return generatedQueryParams{
-		Limit:            q.Get("limit"),
-		Cursor:           q.Get("cursor"),
-		Order:            q.Get("order"),
-		SearchTerm:       q.Get("search_term"),
-		ProcessingStatus: q.Get("processing_status"),
-		TaskType:         q.Get("task_type"),
-		Priority:         q.Get("priority"),
-		MaxRetries:       q.Get("max_retries"),
-		RetryCount:       q.Get("retry_count"),
-		ErrorMessage:     q.Get("error_message"),
-		ProcessingTimeMs: q.Get("processing_time_ms"),
-		LastRunAt:        q.Get("last_run_at"),
+		Limit:                q.Get("limit"),
+		Cursor:               q.Get("cursor"),
+		Order:                q.Get("order"),
+		SearchTerm:           q.Get("search_term"),
+		ProcessingStatus:     q.Get("processing_status"),
+		TaskType:             q.Get("task_type"),
+		Priority:             q.Get("priority"),
+		MaxRetries:           q.Get("max_retries"),
+		RetryCount:           q.Get("retry_count"),
+		ErrorMessage:         q.Get("error_message"),
+		ProcessingTimeMs:     q.Get("processing_time_ms"),
+		LastRunAt:            q.Get("last_run_at"),
+		LockedBy:             q.Get("locked_by"),
+		LeaseExpiresAt:       q.Get("lease_expires_at"),
+		DeadAt:               q.Get("dead_at"),
+		RunAt:                q.Get("run_at"),
+		IdempotencyKey:       q.Get("idempotency_key"),
+		IdempotencyExpiresAt: q.Get("idempotency_expires_at"),
+		WorkflowId:           q.Get("workflow_id"),
+		CancelRequestedAt:    q.Get("cancel_requested_at"),
+		ProgressPercent:      q.Get("progress_percent"),
+		ProgressStep:         q.Get("progress_step"),
+		ProgressUpdatedAt:    q.Get("progress_updated_at"),
 	}
 }
 
@@ -123,6 +145,78 @@ func parseGeneratedFilter(qp generatedQueryParams) (tasksrepo.TaskFilter, error)
 			return filter, fmt.Errorf("invalid last_run_at format: %s", qp.LastRunAt)
 		}
 	}
+	// LockedBy - string filter
+	if qp.LockedBy != "" {
+		filter.LockedBy = &qp.LockedBy
+	}
+	// LeaseExpiresAt - timestamp filter
+	if qp.LeaseExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, qp.LeaseExpiresAt); err == nil {
+			filter.LeaseExpiresAt = &t
+		} else {
+			return filter, fmt.Errorf("invalid lease_expires_at format: %s", qp.LeaseExpiresAt)
+		}
+	}
+	// DeadAt - timestamp filter
+	if qp.DeadAt != "" {
+		if t, err := time.Parse(time.RFC3339, qp.DeadAt); err == nil {
+			filter.DeadAt = &t
+		} else {
+			return filter, fmt.Errorf("invalid dead_at format: %s", qp.DeadAt)
+		}
+	}
+	// RunAt - timestamp filter
+	if qp.RunAt != "" {
+		if t, err := time.Parse(time.RFC3339, qp.RunAt); err == nil {
+			filter.RunAt = &t
+		} else {
+			return filter, fmt.Errorf("invalid run_at format: %s", qp.RunAt)
+		}
+	}
+	// IdempotencyKey - string filter
+	if qp.IdempotencyKey != "" {
+		filter.IdempotencyKey = &qp.IdempotencyKey
+	}
+	// IdempotencyExpiresAt - timestamp filter
+	if qp.IdempotencyExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, qp.IdempotencyExpiresAt); err == nil {
+			filter.IdempotencyExpiresAt = &t
+		} else {
+			return filter, fmt.Errorf("invalid idempotency_expires_at format: %s", qp.IdempotencyExpiresAt)
+		}
+	}
+	// WorkflowId - string filter
+	if qp.WorkflowId != "" {
+		filter.WorkflowId = &qp.WorkflowId
+	}
+	// CancelRequestedAt - timestamp filter
+	if qp.CancelRequestedAt != "" {
+		if t, err := time.Parse(time.RFC3339, qp.CancelRequestedAt); err == nil {
+			filter.CancelRequestedAt = &t
+		} else {
+			return filter, fmt.Errorf("invalid cancel_requested_at format: %s", qp.CancelRequestedAt)
+		}
+	}
+	// ProgressPercent - float64 filter
+	if qp.ProgressPercent != "" {
+		if val, err := strconv.ParseFloat(qp.ProgressPercent, 64); err == nil {
+			filter.ProgressPercent = &val
+		} else {
+			return filter, fmt.Errorf("invalid progress_percent: %s", qp.ProgressPercent)
+		}
+	}
+	// ProgressStep - string filter
+	if qp.ProgressStep != "" {
+		filter.ProgressStep = &qp.ProgressStep
+	}
+	// ProgressUpdatedAt - timestamp filter
+	if qp.ProgressUpdatedAt != "" {
+		if t, err := time.Parse(time.RFC3339, qp.ProgressUpdatedAt); err == nil {
+			filter.ProgressUpdatedAt = &t
+		} else {
+			return filter, fmt.Errorf("invalid progress_updated_at format: %s", qp.ProgressUpdatedAt)
+		}
+	}
 
 	return filter, nil
 }
@@ -143,17 +237,28 @@ func parseGeneratedPath(r *http.Request) (generatedPathParams, error) {
 
 // orderByFields maps URL-friendly field names to repository OrderBy constants
 var orderByFields = map[string]string{
-	"task_id":            tasksrepo.OrderByPK,
-	"created_at":         tasksrepo.OrderByCreatedAt,
-	"updated_at":         tasksrepo.OrderByUpdatedAt,
-	"processing_status":  tasksrepo.OrderByProcessingStatus,
-	"task_type":          tasksrepo.OrderByTaskType,
-	"priority":           tasksrepo.OrderByPriority,
-	"max_retries":        tasksrepo.OrderByMaxRetries,
-	"retry_count":        tasksrepo.OrderByRetryCount,
-	"error_message":      tasksrepo.OrderByErrorMessage,
-	"processing_time_ms": tasksrepo.OrderByProcessingTimeMs,
-	"last_run_at":        tasksrepo.OrderByLastRunAt,
+	"task_id":                tasksrepo.OrderByPK,
+	"created_at":             tasksrepo.OrderByCreatedAt,
+	"updated_at":             tasksrepo.OrderByUpdatedAt,
+	"processing_status":      tasksrepo.OrderByProcessingStatus,
+	"task_type":              tasksrepo.OrderByTaskType,
+	"priority":               tasksrepo.OrderByPriority,
+	"max_retries":            tasksrepo.OrderByMaxRetries,
+	"retry_count":            tasksrepo.OrderByRetryCount,
+	"error_message":          tasksrepo.OrderByErrorMessage,
+	"processing_time_ms":     tasksrepo.OrderByProcessingTimeMs,
+	"last_run_at":            tasksrepo.OrderByLastRunAt,
+	"locked_by":              tasksrepo.OrderByLockedBy,
+	"lease_expires_at":       tasksrepo.OrderByLeaseExpiresAt,
+	"dead_at":                tasksrepo.OrderByDeadAt,
+	"run_at":                 tasksrepo.OrderByRunAt,
+	"idempotency_key":        tasksrepo.OrderByIdempotencyKey,
+	"idempotency_expires_at": tasksrepo.OrderByIdempotencyExpiresAt,
+	"workflow_id":            tasksrepo.OrderByWorkflowId,
+	"cancel_requested_at":    tasksrepo.OrderByCancelRequestedAt,
+	"progress_percent":       tasksrepo.OrderByProgressPercent,
+	"progress_step":          tasksrepo.OrderByProgressStep,
+	"progress_updated_at":    tasksrepo.OrderByProgressUpdatedAt,
 }
 
 // parseGeneratedOrderBy converts order query param to fop.By with validation
@@ -15,12 +15,17 @@ type Config struct {
 	Log        *logger.Logger
 	Repository *tasksrepo.Repository
 	Middleware []web.Middleware
+
+	// Payloads checks enqueued payloads against the registered handlers, e.g. the pool's
+	// workers.TaskMux. Without it any task type and JSON payload is accepted.
+	Payloads PayloadValidator
 }
 
 // AddHttpRoutes registers all HTTP routes for Task
 // See http_gen.go for available handler methods and suggested routes
 func AddHttpRoutes(group *web.RouteGroup, cfg Config) {
 	b := newBridge(cfg.Repository)
+	b.payloads = cfg.Payloads
 
 	// Standard CRUD routes
 	group.GET("/tasks", b.httpList)
@@ -28,4 +33,16 @@ func AddHttpRoutes(group *web.RouteGroup, cfg Config) {
 	group.POST("/tasks", b.httpCreate)
 	group.PUT("/tasks/{task_id}", b.httpUpdate)
 	group.DELETE("/tasks/{task_id}", b.httpDelete)
+
+	// Enqueue routes
+	group.POST("/tasks/enqueue", b.httpEnqueue)
+	group.GET("/tasks/status/{task_id}", b.httpStatus)
+	group.GET("/tasks/workflows/{workflow_id}", b.httpWorkflowStatus)
+	group.POST("/tasks/{task_id}/cancel", b.httpCancel)
+
+	// Dead letter routes
+	group.GET("/tasks/dead", b.httpListDead)
+	group.GET("/tasks/dead/{task_id}", b.httpGetDead)
+	group.POST("/tasks/dead/{task_id}/requeue", b.httpRequeueDead)
+	group.DELETE("/tasks/dead", b.httpPurgeDead)
 }
@@ -17,4 +17,119 @@
 
 package tasksrepobridge
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jrazmi/envoker/core/repositories/tasksrepo"
+)
+
 // Add your custom bridge types here
+
+// PurgeResult reports how many dead-lettered tasks were deleted
+type PurgeResult struct {
+	Purged int `json:"purged"`
+}
+
+// CancelResult reports what a cancel request did: a pending task is cancelled right away, a
+// processing task is left to its worker to cancel
+type CancelResult struct {
+	TaskId string `json:"task_id"`
+	Status string `json:"status"` // cancelled or cancel_requested
+}
+
+// Cancel request outcomes
+const (
+	CancelCancelled = "cancelled"
+	CancelRequested = "cancel_requested"
+)
+
+// PayloadValidator checks a payload against the handler registered for its task type.
+// workers.TaskMux implements it.
+type PayloadValidator interface {
+	ValidatePayload(taskType string, payload []byte) error
+}
+
+// EnqueueRequest is the body of an enqueue request
+type EnqueueRequest struct {
+	TaskType       string          `json:"task_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Priority       *int            `json:"priority"`
+	MaxRetries     *int            `json:"max_retries"`
+	Delay          string          `json:"delay"` // Go duration, e.g. "90s"
+	RunAt          *time.Time      `json:"run_at"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	UniqueFor      string          `json:"unique_for"` // Go duration the key stays taken, see tasksrepo.WithUniqueFor
+
+	WorkflowId      string   `json:"workflow_id"`
+	DependsOn       []string `json:"depends_on"`        // Task ids that must complete first
+	OnParentFailure string   `json:"on_parent_failure"` // cancel (default) or skip, see tasksrepo.Dependency
+}
+
+// Validate checks the request before it is enqueued
+func (e EnqueueRequest) Validate() error {
+	if e.TaskType == "" {
+		return errors.New("task_type is required")
+	}
+	if e.Delay != "" {
+		delay, err := time.ParseDuration(e.Delay)
+		if err != nil || delay < 0 {
+			return fmt.Errorf("invalid delay %q", e.Delay)
+		}
+		if e.RunAt != nil {
+			return errors.New("set either delay or run_at, not both")
+		}
+	}
+	if e.UniqueFor != "" {
+		uniqueFor, err := time.ParseDuration(e.UniqueFor)
+		if err != nil || uniqueFor < 0 {
+			return fmt.Errorf("invalid unique_for %q", e.UniqueFor)
+		}
+		if e.IdempotencyKey == "" {
+			return errors.New("unique_for requires an idempotency_key")
+		}
+	}
+	switch e.OnParentFailure {
+	case "", tasksrepo.OnFailureCancel, tasksrepo.OnFailureSkip:
+	default:
+		return fmt.Errorf("invalid on_parent_failure %q", e.OnParentFailure)
+	}
+	for _, taskId := range e.DependsOn {
+		if taskId == "" {
+			return errors.New("depends_on contains an empty task id")
+		}
+	}
+	return nil
+}
+
+// options turns the request into Enqueue options
+func (e EnqueueRequest) options() []tasksrepo.EnqueueOption {
+	var opts []tasksrepo.EnqueueOption
+	if e.Priority != nil {
+		opts = append(opts, tasksrepo.WithPriority(*e.Priority))
+	}
+	if e.MaxRetries != nil {
+		opts = append(opts, tasksrepo.WithMaxRetries(*e.MaxRetries))
+	}
+	if delay, err := time.ParseDuration(e.Delay); err == nil {
+		opts = append(opts, tasksrepo.WithDelay(delay))
+	}
+	if e.RunAt != nil {
+		opts = append(opts, tasksrepo.WithRunAt(*e.RunAt))
+	}
+	if e.IdempotencyKey != "" {
+		opts = append(opts, tasksrepo.WithIdempotencyKey(e.IdempotencyKey))
+	}
+	if uniqueFor, err := time.ParseDuration(e.UniqueFor); err == nil {
+		opts = append(opts, tasksrepo.WithUniqueFor(uniqueFor))
+	}
+	if e.WorkflowId != "" {
+		opts = append(opts, tasksrepo.WithWorkflow(e.WorkflowId))
+	}
+	for _, taskId := range e.DependsOn {
+		opts = append(opts, tasksrepo.WithDependency(taskId, e.OnParentFailure))
+	}
+	return opts
+}
@@ -0,0 +1,284 @@
+package tasksrepobridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jrazmi/envoker/core/repositories/tasksrepo"
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/sdk/logger"
+)
+
+// ========================================
+// WORKER QUEUE
+// ========================================
+
+// DefaultLeaseDuration is the lease used by Checkout when the pool doesn't pick one
+const DefaultLeaseDuration = 5 * time.Minute
+
+// partitionsRefresh is how long a fair Queue reuses the list of partitions with ready tasks
+const partitionsRefresh = time.Second
+
+// Queue adapts the task repository to workers.Queue so a WorkerPool can work the tasks table.
+// Checkout uses FOR UPDATE SKIP LOCKED, so any number of workers and processes can share it.
+// Queue implements workers.Leaser: checked out tasks are leased to the worker, and tasks whose
+// lease lapses (e.g. the worker crashed) are reclaimed by the pool's reaper. The lease heartbeat
+// also picks up cancellations requested through Repository.RequestCancel, see workers.Canceller.
+// Progress reported through workers.ProgressFrom is saved on the task row, see SaveProgress.
+// Pools in batch mode claim each batch in a single statement, see CheckoutBatch.
+//
+// Failed runs are re-queued by the database until the task's max_retries are used up. Queue is a
+// workers.RetryingQueue, so the pool makes a single attempt per checkout and the table is the one
+// source of retries; passing workers.WithMaxRetries adds in-process retries on top.
+// Tasks that run out of retries are dead-lettered with their error history, see Repository.ListDead.
+//
+// To pick up new tasks without waiting for the next idle poll, run the pool with
+// workers.WithNotifier(taskspgxstore.NewListener(log, pool)).
+//
+// By default Checkout takes the next task by priority across the whole table, so a flood of one
+// task type delays every other. WithFairScheduling shares checkouts between task types (or
+// tenants) by weight instead, and WithConcurrencyLimits caps how many tasks of a type run at once.
+type Queue struct {
+	log        *logger.Logger
+	repository *tasksrepo.Repository
+
+	partitionKey string
+	fair         *workers.FairScheduler // nil takes tasks by priority only
+	limits       map[string]int
+
+	partitionsMu sync.Mutex
+	partitions   []string // Partitions with ready tasks, see partitionsRefresh
+	partitionsAt time.Time
+}
+
+// QueueOption configures a Queue
+type QueueOption func(*Queue)
+
+// WithFairScheduling shares checkouts between partitions in proportion to their weights, see
+// workers.FairScheduler. partitionKey is the metadata key tasks are partitioned by, e.g.
+// "tenant_id"; empty partitions by task_type. Priority still orders tasks within a partition.
+// Fairness is kept per Queue, processes sharing the table each share their own checkouts.
+func WithFairScheduling(partitionKey string, weights map[string]int) QueueOption {
+	return func(q *Queue) {
+		q.partitionKey = partitionKey
+		q.fair = workers.NewFairScheduler(weights)
+	}
+}
+
+// WithConcurrencyLimits caps the tasks of each listed task_type that may be processing at once.
+// The caps hold across every worker and process that shares the tasks table.
+func WithConcurrencyLimits(limits map[string]int) QueueOption {
+	return func(q *Queue) {
+		q.limits = make(map[string]int, len(limits))
+		for taskType, limit := range limits {
+			q.limits[taskType] = limit
+		}
+	}
+}
+
+// NewQueue creates a worker queue backed by the tasks table
+func NewQueue(log *logger.Logger, repository *tasksrepo.Repository, opts ...QueueOption) *Queue {
+	q := &Queue{
+		log:        log,
+		repository: repository,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// NewProcessor creates a workers.Processor that checks tasks out of the tasks table and
+// runs them through handler. Pass a workers.TaskMux[tasksrepo.Task] to route on task_type
+// with the metadata column decoded per type.
+func NewProcessor(log *logger.Logger, repository *tasksrepo.Repository, handler workers.Handler[tasksrepo.Task], opts ...QueueOption) *workers.QueueProcessor[tasksrepo.Task] {
+	return workers.NewQueueProcessor(NewQueue(log, repository, opts...), handler)
+}
+
+// Checkout claims the next pending task with the default lease
+func (q *Queue) Checkout(ctx context.Context, workerID string) (tasksrepo.Task, error) {
+	return q.CheckoutLeased(ctx, workerID, DefaultLeaseDuration)
+}
+
+// CheckoutLeased claims the next pending task and leases it to workerID
+func (q *Queue) CheckoutLeased(ctx context.Context, workerID string, lease time.Duration) (tasksrepo.Task, error) {
+	opts := tasksrepo.CheckoutOptions{
+		ConcurrencyLimits: q.limits,
+	}
+	if q.fair != nil {
+		partitions, err := q.readyPartitions(ctx)
+		if err != nil {
+			return tasksrepo.Task{}, err
+		}
+		if len(partitions) == 0 {
+			return tasksrepo.Task{}, workers.ErrNoWorkAvailable
+		}
+		opts.PartitionKey = q.partitionKey
+		opts.Partitions = q.fair.Order(partitions)
+	}
+
+	task, err := q.repository.CheckoutWith(ctx, workerID, lease, opts)
+	if err != nil {
+		if errors.Is(err, tasksrepo.ErrNoTaskAvailable) {
+			q.forgetPartitions()
+			return tasksrepo.Task{}, workers.ErrNoWorkAvailable
+		}
+		return tasksrepo.Task{}, err
+	}
+	if q.fair != nil {
+		q.fair.Served(task.Partition(q.partitionKey))
+	}
+
+	q.log.DebugContext(ctx, "task checked out", "task_id", task.TaskId, "task_type", task.TaskType, "worker_id", workerID)
+	return task, nil
+}
+
+// CheckoutBatch claims up to size pending tasks in one statement and leases them to workerID,
+// see tasksrepo.Repository.CheckoutBatch. Queue implements workers.BatchQueue. With fair
+// scheduling or concurrency limits the tasks are checked out one at a time instead, so each
+// checkout honours them.
+func (q *Queue) CheckoutBatch(ctx context.Context, workerID string, size int, lease time.Duration) ([]tasksrepo.Task, error) {
+	if q.fair != nil || len(q.limits) > 0 {
+		var tasks []tasksrepo.Task
+		for len(tasks) < size {
+			task, err := q.CheckoutLeased(ctx, workerID, lease)
+			if err != nil {
+				if len(tasks) == 0 {
+					return nil, err
+				}
+				// The tasks already claimed are leased to the worker, they have to be processed
+				if !errors.Is(err, workers.ErrNoWorkAvailable) {
+					q.log.ErrorContext(ctx, "checkout failed, returning a partial batch", "tasks", len(tasks), "worker_id", workerID, "error", err)
+				}
+				break
+			}
+			tasks = append(tasks, task)
+		}
+		return tasks, nil
+	}
+
+	tasks, err := q.repository.CheckoutBatch(ctx, workerID, size, lease)
+	if err != nil {
+		if errors.Is(err, tasksrepo.ErrNoTaskAvailable) {
+			return nil, workers.ErrNoWorkAvailable
+		}
+		return nil, err
+	}
+
+	q.log.DebugContext(ctx, "task batch checked out", "tasks", len(tasks), "worker_id", workerID)
+	return tasks, nil
+}
+
+// readyPartitions returns the partitions with ready tasks, refreshed every partitionsRefresh
+func (q *Queue) readyPartitions(ctx context.Context) ([]string, error) {
+	q.partitionsMu.Lock()
+	defer q.partitionsMu.Unlock()
+
+	if q.partitions != nil && time.Since(q.partitionsAt) < partitionsRefresh {
+		return q.partitions, nil
+	}
+
+	partitions, err := q.repository.ReadyPartitions(ctx, q.partitionKey)
+	if err != nil {
+		return nil, err
+	}
+	q.partitions = partitions
+	q.partitionsAt = time.Now()
+	return partitions, nil
+}
+
+// forgetPartitions drops the cached partitions so the next checkout looks again
+func (q *Queue) forgetPartitions() {
+	q.partitionsMu.Lock()
+	defer q.partitionsMu.Unlock()
+	q.partitions = nil
+}
+
+// ExtendLease keeps the task leased to workerID
+func (q *Queue) ExtendLease(ctx context.Context, task tasksrepo.Task, workerID string, lease time.Duration) error {
+	return leaseError(q.repository.ExtendLease(ctx, task.TaskId, workerID, lease))
+}
+
+// ReapExpired returns tasks with expired leases to the queue
+func (q *Queue) ReapExpired(ctx context.Context) (int, error) {
+	return q.repository.ReapExpired(ctx)
+}
+
+// Complete marks the task as completed
+func (q *Queue) Complete(ctx context.Context, task tasksrepo.Task, processingTimeMS int) error {
+	return leaseError(q.repository.Complete(ctx, task.TaskId, lockedBy(task), processingTimeMS))
+}
+
+// Release puts a task the pool abandoned at its drain deadline back to pending, without using
+// up one of its retries. Queue implements workers.Releaser.
+func (q *Queue) Release(ctx context.Context, task tasksrepo.Task) error {
+	return leaseError(q.repository.Release(ctx, task.TaskId, lockedBy(task)))
+}
+
+// Cancel marks a task whose cancellation was requested as cancelled, so it isn't retried.
+// Queue implements workers.Canceller.
+func (q *Queue) Cancel(ctx context.Context, task tasksrepo.Task) error {
+	return leaseError(q.repository.Cancel(ctx, task.TaskId, lockedBy(task)))
+}
+
+// SaveProgress records the progress reported by the task's processor, with its checkpoint when
+// one was saved. Checkpoints outlive the run, so a retry resumes from the last one (see
+// tasksrepo.Task.GetCheckpoint). Queue implements workers.ProgressSaver.
+func (q *Queue) SaveProgress(ctx context.Context, task tasksrepo.Task, report workers.ProgressReport) error {
+	return leaseError(q.repository.SaveProgress(ctx, task.TaskId, lockedBy(task), tasksrepo.TaskProgress{
+		Percent:    report.Percent,
+		Step:       report.Step,
+		Checkpoint: report.Checkpoint,
+	}))
+}
+
+// RetriesFailedTasks reports that failed tasks are retried by the table, see workers.RetryingQueue
+func (q *Queue) RetriesFailedTasks() bool {
+	return true
+}
+
+// Fail records the failed run with its attempts and lets the table decide whether the task is
+// retried or dead-lettered. Errors that are not retryable (see workers.IsRetryable) dead-letter
+// the task straight away.
+func (q *Queue) Fail(ctx context.Context, task tasksrepo.Task, err error) error {
+	input := tasksrepo.FailTask{
+		ErrorMessage: err.Error(),
+		Permanent:    !workers.IsRetryable(err),
+	}
+	for _, attempt := range workers.AttemptsOf(err) {
+		startedAt := attempt.StartedAt.UTC()
+		input.Attempts = append(input.Attempts, tasksrepo.Attempt{
+			Attempt:   attempt.Number,
+			StartedAt: &startedAt,
+			FailedAt:  attempt.FailedAt.UTC(),
+			Error:     attempt.Error,
+		})
+	}
+
+	if input.Permanent {
+		q.log.WarnContext(ctx, "task failed permanently, dead-lettering", "task_id", task.TaskId, "error", err)
+	}
+	return leaseError(q.repository.Fail(ctx, task.TaskId, lockedBy(task), input))
+}
+
+// lockedBy returns the worker a checked out task is leased to
+func lockedBy(task tasksrepo.Task) string {
+	if task.LockedBy == nil {
+		return ""
+	}
+	return *task.LockedBy
+}
+
+// leaseError translates a lost lease, or a requested cancellation, into the error the worker
+// pool understands
+func leaseError(err error) error {
+	if errors.Is(err, tasksrepo.ErrLeaseLost) {
+		return workers.ErrLeaseLost
+	}
+	if errors.Is(err, tasksrepo.ErrCancelRequested) {
+		return workers.ErrTaskCancelled
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package tasksrepobridge
+
+import (
+	"context"
+
+	"github.com/jrazmi/envoker/core/repositories/tasksrepo"
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/sdk/logger"
+)
+
+// ========================================
+// SCHEDULED TASKS
+// ========================================
+
+// ScheduleEnqueuer adapts the task repository to workers.Enqueuer so a workers.Scheduler can
+// materialize recurring tasks into the tasks table. Each run becomes a task keyed by
+// ScheduledRun.Key() with run_at set to the activation time; the insert is a no-op when another
+// replica already created it.
+type ScheduleEnqueuer struct {
+	log        *logger.Logger
+	repository *tasksrepo.Repository
+	templates  map[string]tasksrepo.CreateTask
+}
+
+// NewScheduleEnqueuer creates an enqueuer backed by the tasks table. templates holds the task to
+// create per schedule name (task_id, processing_status and run_at are filled in per run).
+// Schedules without a template create a task whose task_type is the schedule name.
+func NewScheduleEnqueuer(log *logger.Logger, repository *tasksrepo.Repository, templates map[string]tasksrepo.CreateTask) *ScheduleEnqueuer {
+	return &ScheduleEnqueuer{
+		log:        log,
+		repository: repository,
+		templates:  templates,
+	}
+}
+
+// NewScheduler creates a workers.Scheduler that enqueues into the tasks table
+func NewScheduler(log *logger.Logger, repository *tasksrepo.Repository, templates map[string]tasksrepo.CreateTask, opts ...workers.SchedulerOption) (*workers.Scheduler, error) {
+	return workers.NewScheduler(NewScheduleEnqueuer(log, repository, templates), opts...)
+}
+
+// Enqueue creates the task for a scheduled run unless it already exists
+func (e *ScheduleEnqueuer) Enqueue(ctx context.Context, run workers.ScheduledRun) (bool, error) {
+	input, ok := e.templates[run.Schedule]
+	if !ok {
+		input = tasksrepo.CreateTask{TaskType: run.Schedule}
+	}
+
+	runAt := run.At.UTC()
+	input.TaskId = run.Key()
+	input.ProcessingStatus = tasksrepo.StatusPending
+	input.RunAt = &runAt
+
+	_, created, err := e.repository.CreateOnce(ctx, input)
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		e.log.DebugContext(ctx, "scheduled task created", "task_id", input.TaskId, "task_type", input.TaskType, "run_at", runAt)
+	}
+	return created, nil
+}
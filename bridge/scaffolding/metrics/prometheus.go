@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+
+	"github.com/jrazmi/envoker/infrastructure/web"
+	"github.com/jrazmi/envoker/sdk/promtext"
+)
+
+// Collector exposes the request metrics to Prometheus. The goroutine count is read at scrape
+// time rather than from the sampled expvar.
+func Collector() promtext.Collector {
+	return promtext.CollectorFunc(func(w *promtext.Writer) {
+		w.Counter("http_requests_total", "HTTP requests handled.", float64(m.requests.Value()))
+		w.Counter("http_errors_total", "HTTP requests that ended in an error.", float64(m.errors.Value()))
+		w.Counter("http_panics_total", "Panics recovered while handling HTTP requests.", float64(m.panics.Value()))
+		w.Gauge("go_goroutines", "Goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	})
+}
+
+// Handler serves the metrics of registry in the Prometheus text format, mount it on /metrics.
+func Handler(registry *promtext.Registry) web.HandlerFunc {
+	return func(ctx context.Context, r *http.Request) web.Encoder {
+		var buf bytes.Buffer
+		registry.WriteTo(&buf)
+		return web.NewRaw(buf.Bytes(), promtext.ContentType)
+	}
+}
@@ -0,0 +1,103 @@
+package workersbridge
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/jrazmi/envoker/bridge/scaffolding/errs"
+	"github.com/jrazmi/envoker/bridge/scaffolding/fopbridge"
+	"github.com/jrazmi/envoker/infrastructure/web"
+)
+
+// bridge provides HTTP handlers for worker pools
+type bridge struct {
+	pools []Pool
+}
+
+// newBridge creates a new worker pool bridge
+func newBridge(pools []Pool) *bridge {
+	return &bridge{pools: pools}
+}
+
+// pool returns the pool named in the path
+func (b *bridge) pool(r *http.Request) (Pool, web.Encoder) {
+	name := web.Param(r, "name")
+	if name == "" {
+		return nil, errs.Newf(errs.InvalidArgument, "invalid path arguments: name is required")
+	}
+	for _, pool := range b.pools {
+		if pool.Name() == name {
+			return pool, nil
+		}
+	}
+	return nil, errs.Newf(errs.NotFound, "pool not found: %v", name)
+}
+
+// httpList handles GET requests for the status of every pool
+func (b *bridge) httpList(ctx context.Context, r *http.Request) web.Encoder {
+	statuses := make([]PoolStatus, 0, len(b.pools))
+	for _, pool := range b.pools {
+		statuses = append(statuses, poolStatus(pool))
+	}
+	return fopbridge.NewRecordResponse(statuses)
+}
+
+// httpGet handles GET requests for the status and metrics snapshot of a pool
+func (b *bridge) httpGet(ctx context.Context, r *http.Request) web.Encoder {
+	pool, errResp := b.pool(r)
+	if errResp != nil {
+		return errResp
+	}
+	return fopbridge.NewRecordResponse(poolStatus(pool))
+}
+
+// httpWorkers handles GET requests for the state of a pool's workers
+func (b *bridge) httpWorkers(ctx context.Context, r *http.Request) web.Encoder {
+	pool, errResp := b.pool(r)
+	if errResp != nil {
+		return errResp
+	}
+	return fopbridge.NewRecordResponse(pool.Workers())
+}
+
+// httpFailures handles GET requests for the last failed tasks of a pool, newest first.
+// The optional limit query param caps how many are returned.
+func (b *bridge) httpFailures(ctx context.Context, r *http.Request) web.Encoder {
+	pool, errResp := b.pool(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	limit := 0
+	if qp := web.QueryParam(r, "limit"); qp != "" {
+		n, err := strconv.Atoi(qp)
+		if err != nil || n < 0 {
+			return errs.Newf(errs.InvalidArgument, "invalid limit: %s", qp)
+		}
+		limit = n
+	}
+
+	return fopbridge.NewRecordResponse(pool.RecentFailures(limit))
+}
+
+// httpPause handles POST requests for pausing a pool: workers stop checking out tasks and
+// in-flight tasks run to completion
+func (b *bridge) httpPause(ctx context.Context, r *http.Request) web.Encoder {
+	pool, errResp := b.pool(r)
+	if errResp != nil {
+		return errResp
+	}
+	pool.Pause()
+	return fopbridge.NewCodeResponse(errs.OK.String(), "Pool paused successfully")
+}
+
+// httpResume handles POST requests for resuming a paused pool
+func (b *bridge) httpResume(ctx context.Context, r *http.Request) web.Encoder {
+	pool, errResp := b.pool(r)
+	if errResp != nil {
+		return errResp
+	}
+	pool.Resume()
+	return fopbridge.NewCodeResponse(errs.OK.String(), "Pool resumed successfully")
+}
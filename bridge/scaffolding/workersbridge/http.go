@@ -0,0 +1,35 @@
+// Package workersbridge mounts status endpoints for running worker pools: the metrics snapshot,
+// the state of every worker and the last failures, plus pause and resume as admin actions.
+package workersbridge
+
+import (
+	"github.com/jrazmi/envoker/infrastructure/web"
+	"github.com/jrazmi/envoker/sdk/logger"
+)
+
+// Config holds configuration for the worker pool bridge
+type Config struct {
+	Log        *logger.Logger
+	Pools      []Pool
+	Middleware []web.Middleware
+
+	// AdminMiddleware guards the routes that change a pool (pause and resume), e.g. an auth check.
+	// It runs after Middleware.
+	AdminMiddleware []web.Middleware
+}
+
+// AddHttpRoutes registers the worker pool routes. Pools are addressed by name.
+func AddHttpRoutes(group *web.RouteGroup, cfg Config) {
+	b := newBridge(cfg.Pools)
+	admin := append(append([]web.Middleware(nil), cfg.Middleware...), cfg.AdminMiddleware...)
+
+	// Read-only routes
+	group.GET("/pools", b.httpList, cfg.Middleware...)
+	group.GET("/pools/{name}", b.httpGet, cfg.Middleware...)
+	group.GET("/pools/{name}/workers", b.httpWorkers, cfg.Middleware...)
+	group.GET("/pools/{name}/failures", b.httpFailures, cfg.Middleware...)
+
+	// Admin routes
+	group.POST("/pools/{name}/pause", b.httpPause, admin...)
+	group.POST("/pools/{name}/resume", b.httpResume, admin...)
+}
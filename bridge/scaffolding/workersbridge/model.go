@@ -0,0 +1,36 @@
+package workersbridge
+
+import "github.com/jrazmi/envoker/infrastructure/workers"
+
+// Pool is a worker pool as seen by the bridge. *workers.WorkerPool[T] implements it for any T.
+type Pool interface {
+	Name() string
+	Running() bool
+	Paused() bool
+	WorkerCount() int
+	GetMetrics() workers.MetricsSnapshot
+	Workers() []workers.WorkerStatus
+	RecentFailures(n int) []workers.FailureRecord
+	Pause()
+	Resume()
+}
+
+// PoolStatus is the summary of a pool returned by the bridge
+type PoolStatus struct {
+	Name        string                  `json:"name"`
+	Running     bool                    `json:"running"`
+	Paused      bool                    `json:"paused"`
+	WorkerCount int                     `json:"worker_count"`
+	Metrics     workers.MetricsSnapshot `json:"metrics"`
+}
+
+// poolStatus summarizes pool
+func poolStatus(pool Pool) PoolStatus {
+	return PoolStatus{
+		Name:        pool.Name(),
+		Running:     pool.Running(),
+		Paused:      pool.Paused(),
+		WorkerCount: pool.WorkerCount(),
+		Metrics:     pool.GetMetrics(),
+	}
+}
@@ -0,0 +1,266 @@
+package tasksrepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jrazmi/envoker/sdk/cryptids"
+)
+
+// ========================================
+// ENQUEUE
+// ========================================
+
+// enqueueOptions holds the settings of a single Enqueue call
+type enqueueOptions struct {
+	priority       *int
+	maxRetries     *int
+	runAt          *time.Time
+	delay          time.Duration
+	idempotencyKey string
+	uniqueFor      time.Duration
+	workflowId     string
+	dependencies   []Dependency
+}
+
+// EnqueueOption configures a task created by Enqueue
+type EnqueueOption func(*enqueueOptions)
+
+// WithPriority sets the task's priority, higher runs first
+func WithPriority(priority int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.priority = &priority
+	}
+}
+
+// WithMaxRetries sets how many failed runs the task gets before it is dead-lettered
+func WithMaxRetries(maxRetries int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxRetries = &maxRetries
+	}
+}
+
+// WithDelay keeps the task from running until delay has passed
+func WithDelay(delay time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.delay = delay
+	}
+}
+
+// WithRunAt keeps the task from running before t
+func WithRunAt(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = &t
+	}
+}
+
+// WithIdempotencyKey makes the enqueue safe to repeat: while the key is taken, another Enqueue of
+// the same task type and key returns the existing task instead of creating one. The key is taken
+// while the task is pending or processing, see WithUniqueFor to hold it longer.
+func WithIdempotencyKey(key string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// WithUniqueFor keeps the idempotency key taken for d after the enqueue, even once the task has
+// finished. Only applies together with WithIdempotencyKey.
+func WithUniqueFor(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.uniqueFor = d
+	}
+}
+
+// TaskHandle identifies an enqueued task. Poll Status to follow it.
+type TaskHandle struct {
+	TaskId           string     `json:"task_id"`
+	TaskType         string     `json:"task_type"`
+	ProcessingStatus string     `json:"processing_status"`
+	WorkflowId       *string    `json:"workflow_id,omitempty"`
+	RunAt            *time.Time `json:"run_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	Duplicate        bool       `json:"duplicate"` // The idempotency key matched an existing task
+
+	repository *Repository
+}
+
+// Status fetches the task's current status
+func (h TaskHandle) Status(ctx context.Context) (TaskStatus, error) {
+	if h.repository == nil {
+		return TaskStatus{}, errors.New("task handle has no repository")
+	}
+	return h.repository.Status(ctx, h.TaskId)
+}
+
+// TaskStatus is where a task stands, as reported to whoever enqueued it
+type TaskStatus struct {
+	TaskId           string     `json:"task_id"`
+	TaskType         string     `json:"task_type"`
+	ProcessingStatus string     `json:"processing_status"`
+	WorkflowId       *string    `json:"workflow_id,omitempty"`
+	RetryCount       int        `json:"retry_count"`
+	ErrorMessage     *string    `json:"error_message,omitempty"`
+	RunAt            *time.Time `json:"run_at,omitempty"`
+	CancelRequested  bool       `json:"cancel_requested,omitempty"` // Processing, waiting for its worker to cancel it
+	Progress         *Progress  `json:"progress,omitempty"`         // Once its worker has reported progress
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Progress is the last progress reported by the task's worker. It is kept once the run ends, so
+// a retried task shows where its last run got to.
+type Progress struct {
+	Percent      float64   `json:"percent"`
+	Step         string    `json:"step,omitempty"`
+	Checkpointed bool      `json:"checkpointed"` // A checkpoint was saved to resume from
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Enqueue creates a pending task of taskType with payload encoded as JSON into metadata.
+// A payload that is already JSON ([]byte or json.RawMessage) is stored as is. A task enqueued
+// with dependencies is only checked out once they have completed.
+func (r *Repository) Enqueue(ctx context.Context, taskType string, payload any, opts ...EnqueueOption) (TaskHandle, error) {
+	if taskType == "" {
+		return TaskHandle{}, errors.New("enqueue task: task type is required")
+	}
+
+	var o enqueueOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	metadata, err := encodePayload(payload)
+	if err != nil {
+		return TaskHandle{}, fmt.Errorf("enqueue %s task: %w", taskType, err)
+	}
+
+	input := CreateTask{
+		TaskType:         taskType,
+		ProcessingStatus: StatusPending,
+		Metadata:         metadata,
+		Priority:         o.priority,
+		MaxRetries:       o.maxRetries,
+		RunAt:            o.runAt,
+	}
+	if input.RunAt == nil && o.delay > 0 {
+		runAt := time.Now().UTC().Add(o.delay)
+		input.RunAt = &runAt
+	}
+	if input.RunAt != nil {
+		runAt := input.RunAt.UTC()
+		input.RunAt = &runAt
+	}
+
+	if o.workflowId != "" {
+		input.WorkflowId = &o.workflowId
+	}
+	if o.idempotencyKey != "" {
+		input.IdempotencyKey = &o.idempotencyKey
+		if o.uniqueFor > 0 {
+			expiresAt := time.Now().UTC().Add(o.uniqueFor)
+			input.IdempotencyExpiresAt = &expiresAt
+		}
+	}
+
+	if input.TaskId, err = cryptids.GenerateID(); err != nil {
+		return TaskHandle{}, fmt.Errorf("enqueue %s task: generate id: %w", taskType, err)
+	}
+
+	// All of them also fill in the column defaults for unset options
+	var task Task
+	var created bool
+	switch {
+	case len(o.dependencies) > 0:
+		task, created, err = r.CreateWithDependencies(ctx, input, o.dependencies)
+	case input.IdempotencyKey != nil:
+		task, created, err = r.CreateUnique(ctx, input)
+	default:
+		task, created, err = r.CreateOnce(ctx, input)
+	}
+	if err == nil && !created && input.IdempotencyKey == nil {
+		err = fmt.Errorf("task id %v already taken", input.TaskId)
+	}
+	if err != nil {
+		return TaskHandle{}, fmt.Errorf("enqueue %s task: %w", taskType, err)
+	}
+
+	return TaskHandle{
+		TaskId:           task.TaskId,
+		TaskType:         task.TaskType,
+		ProcessingStatus: task.ProcessingStatus,
+		WorkflowId:       task.WorkflowId,
+		RunAt:            task.RunAt,
+		CreatedAt:        task.CreatedAt,
+		Duplicate:        !created,
+		repository:       r,
+	}, nil
+}
+
+// Status returns where a task stands
+func (r *Repository) Status(ctx context.Context, taskId string) (TaskStatus, error) {
+	task, err := r.Get(ctx, taskId)
+	if err != nil {
+		return TaskStatus{}, err
+	}
+	return taskStatus(task), nil
+}
+
+// taskStatus reports a task as a TaskStatus
+func taskStatus(task Task) TaskStatus {
+	status := TaskStatus{
+		TaskId:           task.TaskId,
+		TaskType:         task.TaskType,
+		ProcessingStatus: task.ProcessingStatus,
+		WorkflowId:       task.WorkflowId,
+		ErrorMessage:     task.ErrorMessage,
+		RunAt:            task.RunAt,
+		CancelRequested:  task.CancelRequestedAt != nil && task.ProcessingStatus == StatusProcessing,
+		CreatedAt:        task.CreatedAt,
+		UpdatedAt:        task.UpdatedAt,
+	}
+	if task.RetryCount != nil {
+		status.RetryCount = *task.RetryCount
+	}
+	if task.ProgressUpdatedAt != nil {
+		status.Progress = &Progress{
+			Checkpointed: task.Checkpoint != nil,
+			UpdatedAt:    *task.ProgressUpdatedAt,
+		}
+		if task.ProgressPercent != nil {
+			status.Progress.Percent = *task.ProgressPercent
+		}
+		if task.ProgressStep != nil {
+			status.Progress.Step = *task.ProgressStep
+		}
+	}
+	return status
+}
+
+// encodePayload turns an Enqueue payload into the metadata column
+func encodePayload(payload any) (*json.RawMessage, error) {
+	var raw json.RawMessage
+	switch p := payload.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		raw = p
+	case []byte:
+		raw = p
+	default:
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encode payload: %w", err)
+		}
+		raw = encoded
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if !json.Valid(raw) {
+		return nil, errors.New("payload is not valid JSON")
+	}
+	return &raw, nil
+}
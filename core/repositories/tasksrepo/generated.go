@@ -22,49 +22,88 @@ import (
 // GeneratedTask represents a task entity from the database.
 // Use the type alias in repository.go to reference this type, or embed it to extend.
 type GeneratedTask struct {
-	TaskId           string           `json:"task_id" db:"task_id" validate:"required"`
-	ProcessingStatus string           `json:"processing_status" db:"processing_status" validate:"required,max=50"`
-	CreatedAt        time.Time        `json:"created_at" db:"created_at" validate:"required"`
-	UpdatedAt        time.Time        `json:"updated_at" db:"updated_at" validate:"required"`
-	TaskType         string           `json:"task_type" db:"task_type" validate:"required,max=100"`
-	Metadata         *json.RawMessage `json:"metadata" db:"metadata"`
-	Priority         *int             `json:"priority" db:"priority"`
-	MaxRetries       *int             `json:"max_retries" db:"max_retries"`
-	RetryCount       *int             `json:"retry_count" db:"retry_count"`
-	ErrorMessage     *string          `json:"error_message" db:"error_message"`
-	ProcessingTimeMs *int             `json:"processing_time_ms" db:"processing_time_ms"`
-	LastRunAt        *time.Time       `json:"last_run_at" db:"last_run_at"`
+	TaskId               string           `json:"task_id" db:"task_id" validate:"required"`
+	ProcessingStatus     string           `json:"processing_status" db:"processing_status" validate:"required,max=50"`
+	CreatedAt            time.Time        `json:"created_at" db:"created_at" validate:"required"`
+	UpdatedAt            time.Time        `json:"updated_at" db:"updated_at" validate:"required"`
+	TaskType             string           `json:"task_type" db:"task_type" validate:"required,max=100"`
+	Metadata             *json.RawMessage `json:"metadata" db:"metadata"`
+	Priority             *int             `json:"priority" db:"priority"`
+	MaxRetries           *int             `json:"max_retries" db:"max_retries"`
+	RetryCount           *int             `json:"retry_count" db:"retry_count"`
+	ErrorMessage         *string          `json:"error_message" db:"error_message"`
+	ProcessingTimeMs     *int             `json:"processing_time_ms" db:"processing_time_ms"`
+	LastRunAt            *time.Time       `json:"last_run_at" db:"last_run_at"`
+	LockedBy             *string          `json:"locked_by" db:"locked_by" validate:"max=255"`
+	LeaseExpiresAt       *time.Time       `json:"lease_expires_at" db:"lease_expires_at"`
+	ErrorHistory         *json.RawMessage `json:"error_history" db:"error_history"`
+	DeadAt               *time.Time       `json:"dead_at" db:"dead_at"`
+	RunAt                *time.Time       `json:"run_at" db:"run_at"`
+	IdempotencyKey       *string          `json:"idempotency_key" db:"idempotency_key" validate:"max=255"`
+	IdempotencyExpiresAt *time.Time       `json:"idempotency_expires_at" db:"idempotency_expires_at"`
+	WorkflowId           *string          `json:"workflow_id" db:"workflow_id" validate:"max=255"`
+	CancelRequestedAt    *time.Time       `json:"cancel_requested_at" db:"cancel_requested_at"`
+	ProgressPercent      *float64         `json:"progress_percent" db:"progress_percent"`
+	ProgressStep         *string          `json:"progress_step" db:"progress_step" validate:"max=255"`
+	ProgressUpdatedAt    *time.Time       `json:"progress_updated_at" db:"progress_updated_at"`
+	Checkpoint           []byte           `json:"checkpoint" db:"checkpoint"`
 }
 
 // GeneratedCreateTask contains the data needed to create a new task.
 // Use the type alias in repository.go to reference this type, or embed it to add custom fields.
 type GeneratedCreateTask struct {
-	TaskId           string           `json:"task_id" db:"task_id" validate:"required"`
-	ProcessingStatus string           `json:"processing_status" db:"processing_status" validate:"required,max=50"`
-	TaskType         string           `json:"task_type" db:"task_type" validate:"required,max=100"`
-	Metadata         *json.RawMessage `json:"metadata" db:"metadata"`
-	Priority         *int             `json:"priority" db:"priority"`
-	MaxRetries       *int             `json:"max_retries" db:"max_retries"`
-	RetryCount       *int             `json:"retry_count" db:"retry_count"`
-	ErrorMessage     *string          `json:"error_message" db:"error_message"`
-	ProcessingTimeMs *int             `json:"processing_time_ms" db:"processing_time_ms"`
-	LastRunAt        *time.Time       `json:"last_run_at" db:"last_run_at"`
+	TaskId               string           `json:"task_id" db:"task_id" validate:"required"`
+	ProcessingStatus     string           `json:"processing_status" db:"processing_status" validate:"required,max=50"`
+	TaskType             string           `json:"task_type" db:"task_type" validate:"required,max=100"`
+	Metadata             *json.RawMessage `json:"metadata" db:"metadata"`
+	Priority             *int             `json:"priority" db:"priority"`
+	MaxRetries           *int             `json:"max_retries" db:"max_retries"`
+	RetryCount           *int             `json:"retry_count" db:"retry_count"`
+	ErrorMessage         *string          `json:"error_message" db:"error_message"`
+	ProcessingTimeMs     *int             `json:"processing_time_ms" db:"processing_time_ms"`
+	LastRunAt            *time.Time       `json:"last_run_at" db:"last_run_at"`
+	LockedBy             *string          `json:"locked_by" db:"locked_by" validate:"max=255"`
+	LeaseExpiresAt       *time.Time       `json:"lease_expires_at" db:"lease_expires_at"`
+	ErrorHistory         *json.RawMessage `json:"error_history" db:"error_history"`
+	DeadAt               *time.Time       `json:"dead_at" db:"dead_at"`
+	RunAt                *time.Time       `json:"run_at" db:"run_at"`
+	IdempotencyKey       *string          `json:"idempotency_key" db:"idempotency_key" validate:"max=255"`
+	IdempotencyExpiresAt *time.Time       `json:"idempotency_expires_at" db:"idempotency_expires_at"`
+	WorkflowId           *string          `json:"workflow_id" db:"workflow_id" validate:"max=255"`
+	CancelRequestedAt    *time.Time       `json:"cancel_requested_at" db:"cancel_requested_at"`
+	ProgressPercent      *float64         `json:"progress_percent" db:"progress_percent"`
+	ProgressStep         *string          `json:"progress_step" db:"progress_step" validate:"max=255"`
+	ProgressUpdatedAt    *time.Time       `json:"progress_updated_at" db:"progress_updated_at"`
+	Checkpoint           []byte           `json:"checkpoint" db:"checkpoint"`
 }
 
 // GeneratedUpdateTask contains the data for updating an existing task.
 // All fields are optional (pointers) to support partial updates.
 // Use the type alias in repository.go to reference this type, or embed it to add custom fields.
 type GeneratedUpdateTask struct {
-	ProcessingStatus *string          `json:"processing_status" db:"processing_status"`
-	TaskType         *string          `json:"task_type" db:"task_type"`
-	Metadata         *json.RawMessage `json:"metadata" db:"metadata"`
-	Priority         *int             `json:"priority" db:"priority"`
-	MaxRetries       *int             `json:"max_retries" db:"max_retries"`
-	RetryCount       *int             `json:"retry_count" db:"retry_count"`
-	ErrorMessage     *string          `json:"error_message" db:"error_message"`
-	ProcessingTimeMs *int             `json:"processing_time_ms" db:"processing_time_ms"`
-	LastRunAt        *time.Time       `json:"last_run_at" db:"last_run_at"`
-	UpdatedAt        *time.Time       `json:"updated_at" db:"updated_at"` // Optional override for updated_at
+	ProcessingStatus     *string          `json:"processing_status" db:"processing_status"`
+	TaskType             *string          `json:"task_type" db:"task_type"`
+	Metadata             *json.RawMessage `json:"metadata" db:"metadata"`
+	Priority             *int             `json:"priority" db:"priority"`
+	MaxRetries           *int             `json:"max_retries" db:"max_retries"`
+	RetryCount           *int             `json:"retry_count" db:"retry_count"`
+	ErrorMessage         *string          `json:"error_message" db:"error_message"`
+	ProcessingTimeMs     *int             `json:"processing_time_ms" db:"processing_time_ms"`
+	LastRunAt            *time.Time       `json:"last_run_at" db:"last_run_at"`
+	LockedBy             *string          `json:"locked_by" db:"locked_by"`
+	LeaseExpiresAt       *time.Time       `json:"lease_expires_at" db:"lease_expires_at"`
+	ErrorHistory         *json.RawMessage `json:"error_history" db:"error_history"`
+	DeadAt               *time.Time       `json:"dead_at" db:"dead_at"`
+	RunAt                *time.Time       `json:"run_at" db:"run_at"`
+	IdempotencyKey       *string          `json:"idempotency_key" db:"idempotency_key"`
+	IdempotencyExpiresAt *time.Time       `json:"idempotency_expires_at" db:"idempotency_expires_at"`
+	WorkflowId           *string          `json:"workflow_id" db:"workflow_id"`
+	CancelRequestedAt    *time.Time       `json:"cancel_requested_at" db:"cancel_requested_at"`
+	ProgressPercent      *float64         `json:"progress_percent" db:"progress_percent"`
+	ProgressStep         *string          `json:"progress_step" db:"progress_step"`
+	ProgressUpdatedAt    *time.Time       `json:"progress_updated_at" db:"progress_updated_at"`
+	Checkpoint           []byte           `json:"checkpoint" db:"checkpoint"`
+	UpdatedAt            *time.Time       `json:"updated_at" db:"updated_at"` // Optional override for updated_at
 }
 
 // ========================================
@@ -73,18 +112,31 @@ type GeneratedUpdateTask struct {
 
 // OrderBy constants for sorting
 const (
-	OrderByPK               = "task_id"
-	OrderByCreatedAt        = "created_at"
-	OrderByUpdatedAt        = "updated_at"
-	OrderByProcessingStatus = "processing_status"
-	OrderByTaskType         = "task_type"
-	OrderByMetadata         = "metadata"
-	OrderByPriority         = "priority"
-	OrderByMaxRetries       = "max_retries"
-	OrderByRetryCount       = "retry_count"
-	OrderByErrorMessage     = "error_message"
-	OrderByProcessingTimeMs = "processing_time_ms"
-	OrderByLastRunAt        = "last_run_at"
+	OrderByPK                   = "task_id"
+	OrderByCreatedAt            = "created_at"
+	OrderByUpdatedAt            = "updated_at"
+	OrderByProcessingStatus     = "processing_status"
+	OrderByTaskType             = "task_type"
+	OrderByMetadata             = "metadata"
+	OrderByPriority             = "priority"
+	OrderByMaxRetries           = "max_retries"
+	OrderByRetryCount           = "retry_count"
+	OrderByErrorMessage         = "error_message"
+	OrderByProcessingTimeMs     = "processing_time_ms"
+	OrderByLastRunAt            = "last_run_at"
+	OrderByLockedBy             = "locked_by"
+	OrderByLeaseExpiresAt       = "lease_expires_at"
+	OrderByErrorHistory         = "error_history"
+	OrderByDeadAt               = "dead_at"
+	OrderByRunAt                = "run_at"
+	OrderByIdempotencyKey       = "idempotency_key"
+	OrderByIdempotencyExpiresAt = "idempotency_expires_at"
+	OrderByWorkflowId           = "workflow_id"
+	OrderByCancelRequestedAt    = "cancel_requested_at"
+	OrderByProgressPercent      = "progress_percent"
+	OrderByProgressStep         = "progress_step"
+	OrderByProgressUpdatedAt    = "progress_updated_at"
+	OrderByCheckpoint           = "checkpoint"
 )
 
 // DefaultOrderBy specifies the default sort order
@@ -93,19 +145,30 @@ var DefaultOrderBy = fop.NewBy(OrderByCreatedAt, fop.DESC)
 // GeneratedTaskFilter holds the available fields a query can be filtered on.
 // Use the type alias in repository.go to reference this type, or embed it to add custom filters.
 type GeneratedTaskFilter struct {
-	SearchTerm       *string    `json:"search_term,omitempty"`        // Search across text fields
-	ProcessingStatus *string    `json:"processing_status,omitempty"`  // Filter by processing_status
-	CreatedAtBefore  *time.Time `json:"created_at_before,omitempty"`  // Filter by created_at < value
-	CreatedAtAfter   *time.Time `json:"created_at_after,omitempty"`   // Filter by created_at > value
-	UpdatedAtBefore  *time.Time `json:"updated_at_before,omitempty"`  // Filter by updated_at < value
-	UpdatedAtAfter   *time.Time `json:"updated_at_after,omitempty"`   // Filter by updated_at > value
-	TaskType         *string    `json:"task_type,omitempty"`          // Filter by task_type
-	Priority         *int       `json:"priority,omitempty"`           // Filter by priority
-	MaxRetries       *int       `json:"max_retries,omitempty"`        // Filter by max_retries
-	RetryCount       *int       `json:"retry_count,omitempty"`        // Filter by retry_count
-	ErrorMessage     *string    `json:"error_message,omitempty"`      // Filter by error_message
-	ProcessingTimeMs *int       `json:"processing_time_ms,omitempty"` // Filter by processing_time_ms
-	LastRunAt        *time.Time `json:"last_run_at,omitempty"`        // Filter by last_run_at
+	SearchTerm           *string    `json:"search_term,omitempty"`            // Search across text fields
+	ProcessingStatus     *string    `json:"processing_status,omitempty"`      // Filter by processing_status
+	CreatedAtBefore      *time.Time `json:"created_at_before,omitempty"`      // Filter by created_at < value
+	CreatedAtAfter       *time.Time `json:"created_at_after,omitempty"`       // Filter by created_at > value
+	UpdatedAtBefore      *time.Time `json:"updated_at_before,omitempty"`      // Filter by updated_at < value
+	UpdatedAtAfter       *time.Time `json:"updated_at_after,omitempty"`       // Filter by updated_at > value
+	TaskType             *string    `json:"task_type,omitempty"`              // Filter by task_type
+	Priority             *int       `json:"priority,omitempty"`               // Filter by priority
+	MaxRetries           *int       `json:"max_retries,omitempty"`            // Filter by max_retries
+	RetryCount           *int       `json:"retry_count,omitempty"`            // Filter by retry_count
+	ErrorMessage         *string    `json:"error_message,omitempty"`          // Filter by error_message
+	ProcessingTimeMs     *int       `json:"processing_time_ms,omitempty"`     // Filter by processing_time_ms
+	LastRunAt            *time.Time `json:"last_run_at,omitempty"`            // Filter by last_run_at
+	LockedBy             *string    `json:"locked_by,omitempty"`              // Filter by locked_by
+	LeaseExpiresAt       *time.Time `json:"lease_expires_at,omitempty"`       // Filter by lease_expires_at
+	DeadAt               *time.Time `json:"dead_at,omitempty"`                // Filter by dead_at
+	RunAt                *time.Time `json:"run_at,omitempty"`                 // Filter by run_at
+	IdempotencyKey       *string    `json:"idempotency_key,omitempty"`        // Filter by idempotency_key
+	IdempotencyExpiresAt *time.Time `json:"idempotency_expires_at,omitempty"` // Filter by idempotency_expires_at
+	WorkflowId           *string    `json:"workflow_id,omitempty"`            // Filter by workflow_id
+	CancelRequestedAt    *time.Time `json:"cancel_requested_at,omitempty"`    // Filter by cancel_requested_at
+	ProgressPercent      *float64   `json:"progress_percent,omitempty"`       // Filter by progress_percent
+	ProgressStep         *string    `json:"progress_step,omitempty"`          // Filter by progress_step
+	ProgressUpdatedAt    *time.Time `json:"progress_updated_at,omitempty"`    // Filter by progress_updated_at
 }
 
 // TaskCursor for cursor-based pagination
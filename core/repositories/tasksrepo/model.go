@@ -12,6 +12,12 @@
 
 package tasksrepo
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // ========================================
 // MODEL TYPE ALIASES
 // ========================================
@@ -29,3 +35,88 @@ type CreateTask = GeneratedCreateTask
 // All fields are optional (pointers) to support partial updates.
 // Change to struct embedding if you need to add custom fields or validation.
 type UpdateTask = GeneratedUpdateTask
+
+// ========================================
+// PROCESSING STATUSES
+// ========================================
+
+// Processing statuses a task moves through while it is worked as a queue.
+// A task that uses up its retries, or fails permanently, is dead-lettered: it stays in the
+// table with its error_history until it is requeued or purged.
+// A task whose parent failed never runs, it is cancelled or skipped per its Dependency.
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusDead       = "dead"
+	StatusCancelled  = "cancelled"
+	StatusSkipped    = "skipped"
+)
+
+// GetID returns the task's primary key so tasks can be run through a worker pool.
+func (t GeneratedTask) GetID() string {
+	return t.TaskId
+}
+
+// GetTaskType returns task_type so tasks can be routed by a workers.TaskMux.
+func (t GeneratedTask) GetTaskType() string {
+	return t.TaskType
+}
+
+// GetPayload returns the metadata column, the payload TaskMux handlers decode.
+func (t GeneratedTask) GetPayload() []byte {
+	if t.Metadata == nil {
+		return nil
+	}
+	return *t.Metadata
+}
+
+// GetCheckpoint returns the checkpoint saved by the task's last run, so a retry resumes from it.
+func (t GeneratedTask) GetCheckpoint() []byte {
+	return t.Checkpoint
+}
+
+// ========================================
+// DEAD LETTERS
+// ========================================
+
+// Attempt is one failed run of a task as recorded in error_history.
+// Run counts checkouts of the task; Attempt counts in-process retries within a run.
+type Attempt struct {
+	Run       int        `json:"run"`
+	Attempt   int        `json:"attempt"`
+	WorkerId  string     `json:"worker_id,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	FailedAt  time.Time  `json:"failed_at"`
+	Error     string     `json:"error"`
+}
+
+// FailTask describes a failed run of a task
+type FailTask struct {
+	ErrorMessage string
+	Attempts     []Attempt // Attempts made during the run, appended to error_history
+	Permanent    bool      // Dead-letter the task right away instead of retrying it
+}
+
+// ========================================
+// PROGRESS
+// ========================================
+
+// TaskProgress is how far a processing task has got, as reported by its worker.
+type TaskProgress struct {
+	Percent    float64
+	Step       string // Empty keeps the step saved before
+	Checkpoint []byte // Nil keeps the checkpoint saved before
+}
+
+// Attempts decodes the failed attempts recorded in error_history
+func (t GeneratedTask) Attempts() ([]Attempt, error) {
+	if t.ErrorHistory == nil {
+		return nil, nil
+	}
+	var attempts []Attempt
+	if err := json.Unmarshal(*t.ErrorHistory, &attempts); err != nil {
+		return nil, fmt.Errorf("decode error history task[%v]: %w", t.TaskId, err)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,74 @@
+package tasksrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ========================================
+// PARTITIONED CHECKOUT
+// ========================================
+
+// CheckoutOptions narrows what Checkout claims. The zero value claims the next due task of any type.
+type CheckoutOptions struct {
+	// PartitionKey is the metadata key tasks are partitioned by, e.g. "tenant_id". Empty
+	// partitions by task_type. Tasks without the key share the "" partition.
+	PartitionKey string
+
+	// Partitions, when set, restricts the checkout to these partitions, tried in order
+	Partitions []string
+
+	// ConcurrencyLimits caps the tasks of a task_type that may be processing at once, across
+	// every worker and process sharing the table. Types at their cap are skipped.
+	ConcurrencyLimits map[string]int
+}
+
+// CheckoutWith claims the next due task like Checkout, restricted by opts.
+// Returns ErrNoTaskAvailable when none of the partitions has a task that can run.
+func (r *Repository) CheckoutWith(ctx context.Context, workerId string, lease time.Duration, opts CheckoutOptions) (Task, error) {
+	now := time.Now().UTC()
+	task, err := r.storer.Checkout(ctx, workerId, opts, now.Add(lease), now)
+	if err != nil {
+		return Task{}, fmt.Errorf("checkout task: %w", err)
+	}
+	return task, nil
+}
+
+// ReadyPartitions lists the partitions (see CheckoutOptions.PartitionKey) that have tasks ready
+// to be checked out.
+func (r *Repository) ReadyPartitions(ctx context.Context, partitionKey string) ([]string, error) {
+	partitions, err := r.storer.ReadyPartitions(ctx, partitionKey, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ready partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// Partition returns the partition the task falls in for partitionKey, matching the partitions
+// reported by ReadyPartitions.
+func (t GeneratedTask) Partition(partitionKey string) string {
+	if partitionKey == "" {
+		return t.TaskType
+	}
+	if t.Metadata == nil {
+		return ""
+	}
+
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(*t.Metadata, &metadata); err != nil {
+		return ""
+	}
+	raw, ok := metadata[partitionKey]
+	if !ok || string(raw) == "null" {
+		return ""
+	}
+
+	// Strings without their quotes, anything else as JSON, like ->> does
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value
+	}
+	return string(raw)
+}
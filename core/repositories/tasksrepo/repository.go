@@ -28,9 +28,36 @@
 package tasksrepo
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jrazmi/envoker/core/scaffolding/fop"
 	"github.com/jrazmi/envoker/sdk/logger"
 )
 
+// Queue errors
+var (
+	// ErrNoTaskAvailable is returned by Checkout when no pending task can be claimed.
+	ErrNoTaskAvailable = errors.New("no task available")
+
+	// ErrLeaseLost is returned when a worker no longer holds the lease on a task,
+	// e.g. because it expired and the task was reclaimed.
+	ErrLeaseLost = errors.New("task lease lost")
+
+	// ErrTaskNotDead is returned by dead letter operations on a task that isn't dead-lettered.
+	ErrTaskNotDead = errors.New("task is not dead-lettered")
+
+	// ErrCancelRequested is returned by ExtendLease when the task was asked to cancel. The lease
+	// is still extended; the worker is expected to stop and call Cancel.
+	ErrCancelRequested = errors.New("task cancellation requested")
+
+	// ErrTaskNotCancellable is returned by RequestCancel for a task that doesn't exist or has
+	// already finished.
+	ErrTaskNotCancellable = errors.New("task is not pending or processing")
+)
+
 // ========================================
 // STORER INTERFACE
 // ========================================
@@ -47,6 +74,60 @@ type Storer interface {
 	// Example:
 	// GetActiveTasks(ctx context.Context) ([]Task, error)
 	// FindByTaskPrefix(ctx context.Context, prefix string) ([]Task, error)
+
+	// CreateOnce inserts a task unless its task_id already exists, reporting whether it was created
+	CreateOnce(ctx context.Context, input CreateTask) (Task, bool, error)
+
+	// CreateUnique inserts a task unless another task holds its idempotency key
+	CreateUnique(ctx context.Context, input CreateTask, now time.Time) (Task, bool, error)
+
+	// CreateWithDependencies inserts a task together with the dependencies it waits on
+	CreateWithDependencies(ctx context.Context, input CreateTask, dependencies []Dependency, now time.Time) (Task, bool, error)
+
+	// ListWorkflow lists the tasks of a workflow, oldest first
+	ListWorkflow(ctx context.Context, workflowId string) ([]Task, error)
+
+	// ListDependencies lists the dependencies of the given tasks
+	ListDependencies(ctx context.Context, taskIds []string) ([]Dependency, error)
+
+	// Checkout atomically claims the next pending task allowed by opts and leases it to workerId
+	Checkout(ctx context.Context, workerId string, opts CheckoutOptions, leaseExpiresAt time.Time, now time.Time) (Task, error)
+
+	// CheckoutBatch atomically claims up to size pending tasks and leases them to workerId
+	CheckoutBatch(ctx context.Context, workerId string, size int, leaseExpiresAt time.Time, now time.Time) ([]Task, error)
+
+	// ReadyPartitions lists the partitions that have tasks ready to be checked out
+	ReadyPartitions(ctx context.Context, partitionKey string, now time.Time) ([]string, error)
+
+	// ExtendLease moves the lease expiry of a task held by workerId
+	ExtendLease(ctx context.Context, taskId string, workerId string, leaseExpiresAt time.Time, now time.Time) error
+
+	// SaveProgress records the progress, and optionally a checkpoint, of a task held by workerId
+	SaveProgress(ctx context.Context, taskId string, workerId string, input TaskProgress, now time.Time) error
+
+	// ReapExpired returns processing tasks with an expired lease to the queue
+	ReapExpired(ctx context.Context, now time.Time) (int, error)
+
+	// Complete marks a task held by workerId as completed
+	Complete(ctx context.Context, taskId string, workerId string, processingTimeMs int, now time.Time) error
+
+	// Release hands a task held by workerId back to pending without counting a failed run
+	Release(ctx context.Context, taskId string, workerId string, now time.Time) error
+
+	// Fail records a failed run, re-queueing the task until its retries are used up
+	Fail(ctx context.Context, taskId string, workerId string, input FailTask, now time.Time) error
+
+	// RequestCancel cancels a pending task, or flags a processing one for its worker to cancel
+	RequestCancel(ctx context.Context, taskId string, now time.Time) (string, error)
+
+	// Cancel marks a task held by workerId as cancelled
+	Cancel(ctx context.Context, taskId string, workerId string, now time.Time) error
+
+	// Requeue moves a dead task back to pending with a fresh retry budget
+	Requeue(ctx context.Context, taskId string, now time.Time) error
+
+	// PurgeDead deletes dead tasks that were dead-lettered before the given time
+	PurgeDead(ctx context.Context, before time.Time) (int, error)
 }
 
 // ========================================
@@ -108,3 +189,192 @@ func NewRepository(log *logger.Logger, storer Storer) *Repository {
 //     // Custom logic here
 //     return nil
 // }
+
+// ========================================
+// QUEUE OPERATIONS
+// ========================================
+
+// CreateOnce creates a task unless one with the same task_id exists and reports whether it was
+// created. With a deterministic task_id it makes enqueueing idempotent, e.g. for scheduled runs.
+// Set RunAt to delay the task. An empty ProcessingStatus defaults to pending.
+func (r *Repository) CreateOnce(ctx context.Context, input CreateTask) (Task, bool, error) {
+	if input.ProcessingStatus == "" {
+		input.ProcessingStatus = StatusPending
+	}
+	task, created, err := r.storer.CreateOnce(ctx, input)
+	if err != nil {
+		return Task{}, false, fmt.Errorf("create once task[%v]: %w", input.TaskId, err)
+	}
+	return task, created, nil
+}
+
+// CreateUnique creates a task unless its idempotency key (input.IdempotencyKey, scoped to the
+// task type) is taken. A key is taken by a task that is pending or processing, and also until
+// its IdempotencyExpiresAt if set. Returns the task holding the key and false for a duplicate.
+func (r *Repository) CreateUnique(ctx context.Context, input CreateTask) (Task, bool, error) {
+	if input.IdempotencyKey == nil || *input.IdempotencyKey == "" {
+		return Task{}, false, fmt.Errorf("create unique task: idempotency key is required")
+	}
+	if input.ProcessingStatus == "" {
+		input.ProcessingStatus = StatusPending
+	}
+	task, created, err := r.storer.CreateUnique(ctx, input, time.Now().UTC())
+	if err != nil {
+		return Task{}, false, fmt.Errorf("create unique task[%v]: %w", *input.IdempotencyKey, err)
+	}
+	return task, created, nil
+}
+
+// Checkout claims the next pending task that is due (run_at unset or passed) and whose dependencies
+// have completed, ordered by priority (highest first) and then age, and leases it to workerId for
+// the given duration. Concurrent callers never receive the same task.
+// Returns ErrNoTaskAvailable when the queue is empty.
+func (r *Repository) Checkout(ctx context.Context, workerId string, lease time.Duration) (Task, error) {
+	return r.CheckoutWith(ctx, workerId, lease, CheckoutOptions{})
+}
+
+// CheckoutBatch claims up to size tasks the way Checkout picks them, in a single statement, and
+// leases them all to workerId for the given duration.
+// Returns ErrNoTaskAvailable when the queue is empty.
+func (r *Repository) CheckoutBatch(ctx context.Context, workerId string, size int, lease time.Duration) ([]Task, error) {
+	now := time.Now().UTC()
+	tasks, err := r.storer.CheckoutBatch(ctx, workerId, size, now.Add(lease), now)
+	if err != nil {
+		return nil, fmt.Errorf("checkout batch: %w", err)
+	}
+	return tasks, nil
+}
+
+// ExtendLease pushes the lease on a task held by workerId out to now + lease.
+// Returns ErrLeaseLost if the worker no longer holds the task, and ErrCancelRequested if the
+// task was asked to cancel.
+func (r *Repository) ExtendLease(ctx context.Context, taskId string, workerId string, lease time.Duration) error {
+	now := time.Now().UTC()
+	if err := r.storer.ExtendLease(ctx, taskId, workerId, now.Add(lease), now); err != nil {
+		return fmt.Errorf("extend lease task[%v]: %w", taskId, err)
+	}
+	return nil
+}
+
+// ReapExpired returns tasks whose lease has expired to pending and bumps their retry_count.
+// Tasks that have used up their retries are dead-lettered instead, settling their dependents as Fail does.
+func (r *Repository) ReapExpired(ctx context.Context) (int, error) {
+	count, err := r.storer.ReapExpired(ctx, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("reap expired tasks: %w", err)
+	}
+	return count, nil
+}
+
+// SaveProgress records how far a task held by workerId has got. A checkpoint saved with it is
+// kept across runs, so a retry picks up from it. Returns ErrLeaseLost if workerId no longer holds it.
+func (r *Repository) SaveProgress(ctx context.Context, taskId string, workerId string, input TaskProgress) error {
+	if err := r.storer.SaveProgress(ctx, taskId, workerId, input, time.Now().UTC()); err != nil {
+		return fmt.Errorf("save progress task[%v]: %w", taskId, err)
+	}
+	return nil
+}
+
+// Complete marks a task held by workerId as completed and records how long it took.
+func (r *Repository) Complete(ctx context.Context, taskId string, workerId string, processingTimeMs int) error {
+	if err := r.storer.Complete(ctx, taskId, workerId, processingTimeMs, time.Now().UTC()); err != nil {
+		return fmt.Errorf("complete task[%v]: %w", taskId, err)
+	}
+	return nil
+}
+
+// Release returns a task held by workerId to pending without using up a retry, e.g. when a
+// worker pool abandons it at its drain deadline. Returns ErrLeaseLost if workerId no longer holds it.
+func (r *Repository) Release(ctx context.Context, taskId string, workerId string) error {
+	if err := r.storer.Release(ctx, taskId, workerId, time.Now().UTC()); err != nil {
+		return fmt.Errorf("release task[%v]: %w", taskId, err)
+	}
+	return nil
+}
+
+// Fail records a failed run of a task held by workerId and appends its attempts to error_history.
+// The task goes back to pending while retry_count is below max_retries, otherwise (or straight
+// away when the failure is permanent) it is dead-lettered and the tasks depending on it are
+// cancelled or skipped.
+func (r *Repository) Fail(ctx context.Context, taskId string, workerId string, input FailTask) error {
+	now := time.Now().UTC()
+	if len(input.Attempts) == 0 {
+		input.Attempts = []Attempt{{Attempt: 1, FailedAt: now, Error: input.ErrorMessage}}
+	}
+	for i := range input.Attempts {
+		input.Attempts[i].WorkerId = workerId
+	}
+
+	if err := r.storer.Fail(ctx, taskId, workerId, input, now); err != nil {
+		return fmt.Errorf("fail task[%v]: %w", taskId, err)
+	}
+	return nil
+}
+
+// ========================================
+// CANCELLATION
+// ========================================
+
+// RequestCancel asks for a task to be cancelled. A pending task is cancelled right away and the
+// tasks depending on it are cancelled or skipped. A processing task can only be stopped by its
+// worker: it is flagged, and the worker cancels it when its next lease heartbeat sees the flag.
+// Returns the task's status afterwards (StatusCancelled or StatusProcessing), or
+// ErrTaskNotCancellable if the task doesn't exist or has already finished.
+func (r *Repository) RequestCancel(ctx context.Context, taskId string) (string, error) {
+	status, err := r.storer.RequestCancel(ctx, taskId, time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("request cancel task[%v]: %w", taskId, err)
+	}
+	return status, nil
+}
+
+// Cancel marks a task held by workerId as cancelled. It isn't retried, and the tasks depending
+// on it are cancelled or skipped. Returns ErrLeaseLost if workerId no longer holds it.
+func (r *Repository) Cancel(ctx context.Context, taskId string, workerId string) error {
+	if err := r.storer.Cancel(ctx, taskId, workerId, time.Now().UTC()); err != nil {
+		return fmt.Errorf("cancel task[%v]: %w", taskId, err)
+	}
+	return nil
+}
+
+// ========================================
+// DEAD LETTERS
+// ========================================
+
+// ListDead lists dead-lettered tasks. The filter's processing status is ignored.
+func (r *Repository) ListDead(ctx context.Context, filter TaskFilter, order fop.By, page fop.PageStringCursor) ([]Task, fop.Pagination, error) {
+	status := StatusDead
+	filter.ProcessingStatus = &status
+	return r.List(ctx, filter, order, page)
+}
+
+// GetDead retrieves a dead-lettered task. Returns ErrTaskNotDead if the task exists but isn't dead.
+func (r *Repository) GetDead(ctx context.Context, taskId string) (Task, error) {
+	task, err := r.Get(ctx, taskId)
+	if err != nil {
+		return Task{}, err
+	}
+	if task.ProcessingStatus != StatusDead {
+		return Task{}, fmt.Errorf("get dead task[%v]: %w", taskId, ErrTaskNotDead)
+	}
+	return task, nil
+}
+
+// Requeue moves a dead task back to pending and resets its retries. The error history is kept.
+// Tasks its failure cancelled or skipped stay that way. Returns ErrTaskNotDead if the task isn't dead-lettered.
+func (r *Repository) Requeue(ctx context.Context, taskId string) error {
+	if err := r.storer.Requeue(ctx, taskId, time.Now().UTC()); err != nil {
+		return fmt.Errorf("requeue task[%v]: %w", taskId, err)
+	}
+	return nil
+}
+
+// PurgeDead deletes tasks that were dead-lettered before the given time and reports how many
+// were removed. Pass time.Now() to purge every dead task.
+func (r *Repository) PurgeDead(ctx context.Context, before time.Time) (int, error) {
+	count, err := r.storer.PurgeDead(ctx, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("purge dead tasks: %w", err)
+	}
+	return count, nil
+}
@@ -34,19 +34,32 @@ type GeneratedStore struct {
 // Create inserts a new Task
 func (s *GeneratedStore) Create(ctx context.Context, input tasksrepo.CreateTask) (tasksrepo.Task, error) {
 	// PK is in Create struct - use value from input
-	query := `INSERT INTO public.tasks (task_id, processing_status, task_type, metadata, priority, max_retries, retry_count, error_message, processing_time_ms, last_run_at) VALUES (@task_id, @processing_status, @task_type, @metadata, @priority, @max_retries, @retry_count, @error_message, @processing_time_ms, @last_run_at) RETURNING task_id, processing_status, created_at, updated_at, task_type, metadata, priority, max_retries, retry_count, error_message, processing_time_ms, last_run_at`
+	query := `INSERT INTO public.tasks (task_id, processing_status, task_type, metadata, priority, max_retries, retry_count, error_message, processing_time_ms, last_run_at, locked_by, lease_expires_at, error_history, dead_at, run_at, idempotency_key, idempotency_expires_at, workflow_id, cancel_requested_at, progress_percent, progress_step, progress_updated_at, checkpoint) VALUES (@task_id, @processing_status, @task_type, @metadata, @priority, @max_retries, @retry_count, @error_message, @processing_time_ms, @last_run_at, @locked_by, @lease_expires_at, @error_history, @dead_at, @run_at, @idempotency_key, @idempotency_expires_at, @workflow_id, @cancel_requested_at, @progress_percent, @progress_step, @progress_updated_at, @checkpoint) RETURNING task_id, processing_status, created_at, updated_at, task_type, metadata, priority, max_retries, retry_count, error_message, processing_time_ms, last_run_at, locked_by, lease_expires_at, error_history, dead_at, run_at, idempotency_key, idempotency_expires_at, workflow_id, cancel_requested_at, progress_percent, progress_step, progress_updated_at, checkpoint`
 
 	args := pgx.NamedArgs{
-		"task_id":            input.TaskId,
-		"processing_status":  input.ProcessingStatus,
-		"task_type":          input.TaskType,
-		"metadata":           input.Metadata,
-		"priority":           input.Priority,
-		"max_retries":        input.MaxRetries,
-		"retry_count":        input.RetryCount,
-		"error_message":      input.ErrorMessage,
-		"processing_time_ms": input.ProcessingTimeMs,
-		"last_run_at":        input.LastRunAt,
+		"task_id":                input.TaskId,
+		"processing_status":      input.ProcessingStatus,
+		"task_type":              input.TaskType,
+		"metadata":               input.Metadata,
+		"priority":               input.Priority,
+		"max_retries":            input.MaxRetries,
+		"retry_count":            input.RetryCount,
+		"error_message":          input.ErrorMessage,
+		"processing_time_ms":     input.ProcessingTimeMs,
+		"last_run_at":            input.LastRunAt,
+		"locked_by":              input.LockedBy,
+		"lease_expires_at":       input.LeaseExpiresAt,
+		"error_history":          input.ErrorHistory,
+		"dead_at":                input.DeadAt,
+		"run_at":                 input.RunAt,
+		"idempotency_key":        input.IdempotencyKey,
+		"idempotency_expires_at": input.IdempotencyExpiresAt,
+		"workflow_id":            input.WorkflowId,
+		"cancel_requested_at":    input.CancelRequestedAt,
+		"progress_percent":       input.ProgressPercent,
+		"progress_step":          input.ProgressStep,
+		"progress_updated_at":    input.ProgressUpdatedAt,
+		"checkpoint":             input.Checkpoint,
 	}
 
 	rows, err := s.pool.Query(ctx, query, args)
@@ -65,7 +78,7 @@ func (s *GeneratedStore) Create(ctx context.Context, input tasksrepo.CreateTask)
 
 // Get retrieves a single Task by ID
 func (s *GeneratedStore) Get(ctx context.Context, taskId string) (tasksrepo.Task, error) {
-	query := `SELECT task_id, processing_status, created_at, updated_at, task_type, metadata, priority, max_retries, retry_count, error_message, processing_time_ms, last_run_at FROM public.tasks WHERE task_id = @taskId`
+	query := `SELECT task_id, processing_status, created_at, updated_at, task_type, metadata, priority, max_retries, retry_count, error_message, processing_time_ms, last_run_at, locked_by, lease_expires_at, error_history, dead_at, run_at, idempotency_key, idempotency_expires_at, workflow_id, cancel_requested_at, progress_percent, progress_step, progress_updated_at, checkpoint FROM public.tasks WHERE task_id = @taskId`
 
 	args := pgx.NamedArgs{
 		"taskId": taskId,
@@ -131,6 +144,58 @@ func (s *GeneratedStore) Update(ctx context.Context, taskId string, input tasksr
 		fields = append(fields, "last_run_at = @last_run_at")
 		args["last_run_at"] = *input.LastRunAt
 	}
+	if input.LockedBy != nil {
+		fields = append(fields, "locked_by = @locked_by")
+		args["locked_by"] = *input.LockedBy
+	}
+	if input.LeaseExpiresAt != nil {
+		fields = append(fields, "lease_expires_at = @lease_expires_at")
+		args["lease_expires_at"] = *input.LeaseExpiresAt
+	}
+	if input.ErrorHistory != nil {
+		fields = append(fields, "error_history = @error_history")
+		args["error_history"] = *input.ErrorHistory
+	}
+	if input.DeadAt != nil {
+		fields = append(fields, "dead_at = @dead_at")
+		args["dead_at"] = *input.DeadAt
+	}
+	if input.RunAt != nil {
+		fields = append(fields, "run_at = @run_at")
+		args["run_at"] = *input.RunAt
+	}
+	if input.IdempotencyKey != nil {
+		fields = append(fields, "idempotency_key = @idempotency_key")
+		args["idempotency_key"] = *input.IdempotencyKey
+	}
+	if input.IdempotencyExpiresAt != nil {
+		fields = append(fields, "idempotency_expires_at = @idempotency_expires_at")
+		args["idempotency_expires_at"] = *input.IdempotencyExpiresAt
+	}
+	if input.WorkflowId != nil {
+		fields = append(fields, "workflow_id = @workflow_id")
+		args["workflow_id"] = *input.WorkflowId
+	}
+	if input.CancelRequestedAt != nil {
+		fields = append(fields, "cancel_requested_at = @cancel_requested_at")
+		args["cancel_requested_at"] = *input.CancelRequestedAt
+	}
+	if input.ProgressPercent != nil {
+		fields = append(fields, "progress_percent = @progress_percent")
+		args["progress_percent"] = *input.ProgressPercent
+	}
+	if input.ProgressStep != nil {
+		fields = append(fields, "progress_step = @progress_step")
+		args["progress_step"] = *input.ProgressStep
+	}
+	if input.ProgressUpdatedAt != nil {
+		fields = append(fields, "progress_updated_at = @progress_updated_at")
+		args["progress_updated_at"] = *input.ProgressUpdatedAt
+	}
+	if input.Checkpoint != nil {
+		fields = append(fields, "checkpoint = @checkpoint")
+		args["checkpoint"] = input.Checkpoint
+	}
 
 	// Always update the updated_at field
 	now := time.Now().UTC()
@@ -203,7 +268,20 @@ func (s *GeneratedStore) List(ctx context.Context, filter tasksrepo.TaskFilter,
 			retry_count,
 			error_message,
 			processing_time_ms,
-			last_run_at
+			last_run_at,
+			locked_by,
+			lease_expires_at,
+			error_history,
+			dead_at,
+			run_at,
+			idempotency_key,
+			idempotency_expires_at,
+			workflow_id,
+			cancel_requested_at,
+			progress_percent,
+			progress_step,
+			progress_updated_at,
+			checkpoint
 		FROM
 			public.tasks`)
 
@@ -268,18 +346,31 @@ func (s *GeneratedStore) List(ctx context.Context, filter tasksrepo.TaskFilter,
 
 // orderByFields maps repository field names to database column names
 var orderByFields = map[string]string{
-	tasksrepo.OrderByPK:               "task_id",
-	tasksrepo.OrderByCreatedAt:        "created_at",
-	tasksrepo.OrderByUpdatedAt:        "updated_at",
-	tasksrepo.OrderByProcessingStatus: "processing_status",
-	tasksrepo.OrderByTaskType:         "task_type",
-	tasksrepo.OrderByMetadata:         "metadata",
-	tasksrepo.OrderByPriority:         "priority",
-	tasksrepo.OrderByMaxRetries:       "max_retries",
-	tasksrepo.OrderByRetryCount:       "retry_count",
-	tasksrepo.OrderByErrorMessage:     "error_message",
-	tasksrepo.OrderByProcessingTimeMs: "processing_time_ms",
-	tasksrepo.OrderByLastRunAt:        "last_run_at",
+	tasksrepo.OrderByPK:                   "task_id",
+	tasksrepo.OrderByCreatedAt:            "created_at",
+	tasksrepo.OrderByUpdatedAt:            "updated_at",
+	tasksrepo.OrderByProcessingStatus:     "processing_status",
+	tasksrepo.OrderByTaskType:             "task_type",
+	tasksrepo.OrderByMetadata:             "metadata",
+	tasksrepo.OrderByPriority:             "priority",
+	tasksrepo.OrderByMaxRetries:           "max_retries",
+	tasksrepo.OrderByRetryCount:           "retry_count",
+	tasksrepo.OrderByErrorMessage:         "error_message",
+	tasksrepo.OrderByProcessingTimeMs:     "processing_time_ms",
+	tasksrepo.OrderByLastRunAt:            "last_run_at",
+	tasksrepo.OrderByLockedBy:             "locked_by",
+	tasksrepo.OrderByLeaseExpiresAt:       "lease_expires_at",
+	tasksrepo.OrderByErrorHistory:         "error_history",
+	tasksrepo.OrderByDeadAt:               "dead_at",
+	tasksrepo.OrderByRunAt:                "run_at",
+	tasksrepo.OrderByIdempotencyKey:       "idempotency_key",
+	tasksrepo.OrderByIdempotencyExpiresAt: "idempotency_expires_at",
+	tasksrepo.OrderByWorkflowId:           "workflow_id",
+	tasksrepo.OrderByCancelRequestedAt:    "cancel_requested_at",
+	tasksrepo.OrderByProgressPercent:      "progress_percent",
+	tasksrepo.OrderByProgressStep:         "progress_step",
+	tasksrepo.OrderByProgressUpdatedAt:    "progress_updated_at",
+	tasksrepo.OrderByCheckpoint:           "checkpoint",
 }
 
 // applyFilter applies query filters to the SQL query
@@ -347,6 +438,61 @@ func (s *GeneratedStore) applyFilter(filter tasksrepo.TaskFilter, data pgx.Named
 		conditions = append(conditions, "last_run_at = @lastRunAt")
 		data["lastRunAt"] = *filter.LastRunAt
 	}
+	// Filter by locked_by
+	if filter.LockedBy != nil {
+		conditions = append(conditions, "locked_by = @lockedBy")
+		data["lockedBy"] = *filter.LockedBy
+	}
+	// Filter by lease_expires_at
+	if filter.LeaseExpiresAt != nil {
+		conditions = append(conditions, "lease_expires_at = @leaseExpiresAt")
+		data["leaseExpiresAt"] = *filter.LeaseExpiresAt
+	}
+	// Filter by dead_at
+	if filter.DeadAt != nil {
+		conditions = append(conditions, "dead_at = @deadAt")
+		data["deadAt"] = *filter.DeadAt
+	}
+	// Filter by run_at
+	if filter.RunAt != nil {
+		conditions = append(conditions, "run_at = @runAt")
+		data["runAt"] = *filter.RunAt
+	}
+	// Filter by idempotency_key
+	if filter.IdempotencyKey != nil {
+		conditions = append(conditions, "idempotency_key = @idempotencyKey")
+		data["idempotencyKey"] = *filter.IdempotencyKey
+	}
+	// Filter by idempotency_expires_at
+	if filter.IdempotencyExpiresAt != nil {
+		conditions = append(conditions, "idempotency_expires_at = @idempotencyExpiresAt")
+		data["idempotencyExpiresAt"] = *filter.IdempotencyExpiresAt
+	}
+	// Filter by workflow_id
+	if filter.WorkflowId != nil {
+		conditions = append(conditions, "workflow_id = @workflowId")
+		data["workflowId"] = *filter.WorkflowId
+	}
+	// Filter by cancel_requested_at
+	if filter.CancelRequestedAt != nil {
+		conditions = append(conditions, "cancel_requested_at = @cancelRequestedAt")
+		data["cancelRequestedAt"] = *filter.CancelRequestedAt
+	}
+	// Filter by progress_percent
+	if filter.ProgressPercent != nil {
+		conditions = append(conditions, "progress_percent = @progressPercent")
+		data["progressPercent"] = *filter.ProgressPercent
+	}
+	// Filter by progress_step
+	if filter.ProgressStep != nil {
+		conditions = append(conditions, "progress_step = @progressStep")
+		data["progressStep"] = *filter.ProgressStep
+	}
+	// Filter by progress_updated_at
+	if filter.ProgressUpdatedAt != nil {
+		conditions = append(conditions, "progress_updated_at = @progressUpdatedAt")
+		data["progressUpdatedAt"] = *filter.ProgressUpdatedAt
+	}
 
 	// Search term across text fields
 	if filter.SearchTerm != nil && *filter.SearchTerm != "" {
@@ -356,6 +502,10 @@ func (s *GeneratedStore) applyFilter(filter tasksrepo.TaskFilter, data pgx.Named
 		searchConditions = append(searchConditions, "processing_status ILIKE @search_term")
 		searchConditions = append(searchConditions, "task_type ILIKE @search_term")
 		searchConditions = append(searchConditions, "error_message ILIKE @search_term")
+		searchConditions = append(searchConditions, "locked_by ILIKE @search_term")
+		searchConditions = append(searchConditions, "idempotency_key ILIKE @search_term")
+		searchConditions = append(searchConditions, "workflow_id ILIKE @search_term")
+		searchConditions = append(searchConditions, "progress_step ILIKE @search_term")
 		if len(searchConditions) > 0 {
 			conditions = append(conditions, "("+strings.Join(searchConditions, " OR ")+")")
 			data["search_term"] = searchPattern
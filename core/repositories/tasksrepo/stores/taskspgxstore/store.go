@@ -12,6 +12,14 @@
 package taskspgxstore
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jrazmi/envoker/core/repositories/tasksrepo"
 	"github.com/jrazmi/envoker/infrastructure/postgresdb"
 	"github.com/jrazmi/envoker/sdk/logger"
 )
@@ -74,3 +82,1156 @@ func NewStore(log *logger.Logger, pool *postgresdb.Pool) *Store {
 //
 //     return entities, nil
 // }
+
+// ========================================
+// QUEUE QUERIES
+// ========================================
+
+// taskColumns is the column list returned by the queue queries, matching tasksrepo.Task.
+const taskColumns = `task_id, processing_status, created_at, updated_at, task_type, metadata, priority, max_retries, retry_count, error_message, processing_time_ms, last_run_at, locked_by, lease_expires_at, error_history, dead_at, run_at, idempotency_key, idempotency_expires_at, workflow_id, cancel_requested_at, progress_percent, progress_step, progress_updated_at, checkpoint`
+
+// readyTask is the condition for a task t to be checked out: pending, due (run_at unset or
+// passed) and with every parent completed or skipped.
+const readyTask = `
+	t.processing_status = @pending
+	AND (t.run_at IS NULL OR t.run_at <= @now)
+	AND NOT EXISTS (
+		SELECT 1
+		FROM public.task_dependencies d
+		JOIN public.tasks parent ON parent.task_id = d.depends_on_task_id
+		WHERE d.task_id = t.task_id AND parent.processing_status NOT IN (@completed, @skipped)
+	)`
+
+// taskPartition is the partition of a task t: its task_type, or the metadata value under
+// @partition_key when one is set.
+const taskPartition = `CASE WHEN @partition_key = '' THEN t.task_type ELSE COALESCE(t.metadata->>@partition_key, '') END`
+
+// checkoutUpdate leases the task @taskId to the worker
+const checkoutUpdate = `
+	UPDATE public.tasks
+	SET
+		processing_status = @processing,
+		locked_by = @worker_id,
+		lease_expires_at = @lease_expires_at,
+		last_run_at = @now,
+		updated_at = @now
+	WHERE task_id = @taskId
+	RETURNING ` + taskColumns
+
+// Checkout claims the next ready task (see readyTask) and leases it to workerId. The
+// SELECT ... FOR UPDATE SKIP LOCKED lets concurrent workers each lock a different row instead of
+// blocking on the same one.
+//
+// With opts.Partitions set the partitions are tried in order. Types at their concurrency limit
+// are skipped: the limit is checked under a transaction scoped advisory lock per task_type, so
+// concurrent checkouts in any process can't both take the last slot. The lock is only tried,
+// a type another checkout is counting is skipped for this pass rather than waited on.
+func (s *Store) Checkout(ctx context.Context, workerId string, opts tasksrepo.CheckoutOptions, leaseExpiresAt time.Time, now time.Time) (tasksrepo.Task, error) {
+	if len(opts.Partitions) == 0 && len(opts.ConcurrencyLimits) == 0 {
+		return s.checkoutNext(ctx, workerId, leaseExpiresAt, now)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	full, err := typesAtLimit(ctx, tx, opts.ConcurrencyLimits)
+	if err != nil {
+		return tasksrepo.Task{}, err
+	}
+
+	partitions := opts.Partitions
+	if len(partitions) == 0 {
+		partitions = []string{""} // Unused when partitioned is false
+	}
+
+	for _, partition := range partitions {
+		for {
+			candidate, found, err := checkoutCandidate(ctx, tx, opts, partition, full, now)
+			if err != nil {
+				return tasksrepo.Task{}, err
+			}
+			if !found {
+				break
+			}
+
+			if limit, capped := opts.ConcurrencyLimits[candidate.TaskType]; capped {
+				atLimit, err := typeAtLimit(ctx, tx, candidate.TaskType, limit)
+				if err != nil {
+					return tasksrepo.Task{}, err
+				}
+				if atLimit {
+					full = append(full, candidate.TaskType)
+					continue
+				}
+			}
+
+			args := pgx.NamedArgs{
+				"taskId":           candidate.TaskId,
+				"processing":       tasksrepo.StatusProcessing,
+				"worker_id":        workerId,
+				"lease_expires_at": leaseExpiresAt,
+				"now":              now,
+			}
+
+			rows, err := tx.Query(ctx, checkoutUpdate, args)
+			if err != nil {
+				return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+			}
+			record, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[tasksrepo.Task])
+			if err != nil {
+				return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+			}
+
+			if err := tx.Commit(ctx); err != nil {
+				return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+			}
+			return record, nil
+		}
+	}
+
+	return tasksrepo.Task{}, tasksrepo.ErrNoTaskAvailable
+}
+
+// checkoutNext claims the next ready task of any partition in a single statement
+func (s *Store) checkoutNext(ctx context.Context, workerId string, leaseExpiresAt time.Time, now time.Time) (tasksrepo.Task, error) {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = @processing,
+			locked_by = @worker_id,
+			lease_expires_at = @lease_expires_at,
+			last_run_at = @now,
+			updated_at = @now
+		WHERE task_id = (
+			SELECT t.task_id
+			FROM public.tasks t
+			WHERE ` + readyTask + `
+			ORDER BY t.priority DESC NULLS LAST, t.created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + taskColumns
+
+	args := pgx.NamedArgs{
+		"processing":       tasksrepo.StatusProcessing,
+		"pending":          tasksrepo.StatusPending,
+		"completed":        tasksrepo.StatusCompleted,
+		"skipped":          tasksrepo.StatusSkipped,
+		"worker_id":        workerId,
+		"lease_expires_at": leaseExpiresAt,
+		"now":              now,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+	}
+	defer rows.Close()
+
+	record, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[tasksrepo.Task])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tasksrepo.Task{}, tasksrepo.ErrNoTaskAvailable
+		}
+		return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+	}
+
+	return record, nil
+}
+
+// CheckoutBatch claims up to size ready tasks (see readyTask) in one statement and leases them all
+// to workerId, like Checkout without options. Returns ErrNoTaskAvailable when there are none.
+func (s *Store) CheckoutBatch(ctx context.Context, workerId string, size int, leaseExpiresAt time.Time, now time.Time) ([]tasksrepo.Task, error) {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = @processing,
+			locked_by = @worker_id,
+			lease_expires_at = @lease_expires_at,
+			last_run_at = @now,
+			updated_at = @now
+		WHERE task_id IN (
+			SELECT t.task_id
+			FROM public.tasks t
+			WHERE ` + readyTask + `
+			ORDER BY t.priority DESC NULLS LAST, t.created_at ASC
+			LIMIT @size
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + taskColumns
+
+	args := pgx.NamedArgs{
+		"processing":       tasksrepo.StatusProcessing,
+		"pending":          tasksrepo.StatusPending,
+		"completed":        tasksrepo.StatusCompleted,
+		"skipped":          tasksrepo.StatusSkipped,
+		"worker_id":        workerId,
+		"lease_expires_at": leaseExpiresAt,
+		"size":             size,
+		"now":              now,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByName[tasksrepo.Task])
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+	if len(records) == 0 {
+		return nil, tasksrepo.ErrNoTaskAvailable
+	}
+	return records, nil
+}
+
+// checkoutCandidate locks the next ready task in partition, by priority (highest first) and then
+// age, skipping the task types in full.
+func checkoutCandidate(ctx context.Context, tx pgx.Tx, opts tasksrepo.CheckoutOptions, partition string, full []string, now time.Time) (candidateTask, bool, error) {
+	query := `
+		SELECT t.task_id, t.task_type
+		FROM public.tasks t
+		WHERE ` + readyTask + `
+			AND (NOT @partitioned OR ` + taskPartition + ` = @partition)
+			AND t.task_type <> ALL(@full)
+		ORDER BY t.priority DESC NULLS LAST, t.created_at ASC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`
+
+	if full == nil {
+		full = []string{}
+	}
+	args := pgx.NamedArgs{
+		"pending":       tasksrepo.StatusPending,
+		"completed":     tasksrepo.StatusCompleted,
+		"skipped":       tasksrepo.StatusSkipped,
+		"partitioned":   len(opts.Partitions) > 0,
+		"partition_key": opts.PartitionKey,
+		"partition":     partition,
+		"full":          full,
+		"now":           now,
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return candidateTask{}, false, postgresdb.HandlePgError(err)
+	}
+	candidate, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[candidateTask])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return candidateTask{}, false, nil
+		}
+		return candidateTask{}, false, postgresdb.HandlePgError(err)
+	}
+	return candidate, true, nil
+}
+
+// typesAtLimit returns the limited task types that already have as many tasks processing as
+// their limit allows
+func typesAtLimit(ctx context.Context, tx pgx.Tx, limits map[string]int) ([]string, error) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+
+	types := make([]string, 0, len(limits))
+	for taskType := range limits {
+		types = append(types, taskType)
+	}
+
+	query := `
+		SELECT task_type, count(*)
+		FROM public.tasks
+		WHERE processing_status = @processing AND task_type = ANY(@types)
+		GROUP BY task_type`
+
+	args := pgx.NamedArgs{
+		"processing": tasksrepo.StatusProcessing,
+		"types":      types,
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+	counts, err := pgx.CollectRows(rows, pgx.RowToStructByPos[typeCount])
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	var full []string
+	for _, count := range counts {
+		if count.Count >= limits[count.TaskType] {
+			full = append(full, count.TaskType)
+		}
+	}
+	return full, nil
+}
+
+// typeAtLimit takes the advisory lock of taskType for the rest of the transaction and then
+// counts its processing tasks, so the count can't change before the checkout commits. A lock held
+// by another checkout counts as the type being at its limit for this pass: waiting on it could
+// deadlock with a checkout that locks the same types in another order.
+func typeAtLimit(ctx context.Context, tx pgx.Tx, taskType string, limit int) (bool, error) {
+	lockArgs := pgx.NamedArgs{
+		"lock_key": "tasks.concurrency/" + taskType,
+	}
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtextextended(@lock_key, 0))`, lockArgs).Scan(&locked); err != nil {
+		return false, postgresdb.HandlePgError(err)
+	}
+	if !locked {
+		return true, nil
+	}
+
+	query := `
+		SELECT count(*)
+		FROM public.tasks
+		WHERE processing_status = @processing AND task_type = @task_type`
+
+	args := pgx.NamedArgs{
+		"processing": tasksrepo.StatusProcessing,
+		"task_type":  taskType,
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, query, args).Scan(&count); err != nil {
+		return false, postgresdb.HandlePgError(err)
+	}
+	return count >= limit, nil
+}
+
+// ReadyPartitions lists the partitions with ready tasks, see taskPartition
+func (s *Store) ReadyPartitions(ctx context.Context, partitionKey string, now time.Time) ([]string, error) {
+	query := `
+		SELECT DISTINCT ` + taskPartition + `
+		FROM public.tasks t
+		WHERE ` + readyTask
+
+	args := pgx.NamedArgs{
+		"pending":       tasksrepo.StatusPending,
+		"completed":     tasksrepo.StatusCompleted,
+		"skipped":       tasksrepo.StatusSkipped,
+		"partition_key": partitionKey,
+		"now":           now,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	partitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	return partitions, nil
+}
+
+// ExtendLease moves the lease expiry of a processing task, provided workerId still holds it.
+// Returns ErrCancelRequested, with the lease extended, when the task was asked to cancel.
+func (s *Store) ExtendLease(ctx context.Context, taskId string, workerId string, leaseExpiresAt time.Time, now time.Time) error {
+	query := `
+		UPDATE public.tasks
+		SET
+			lease_expires_at = @lease_expires_at,
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status = @processing AND locked_by = @worker_id
+		RETURNING cancel_requested_at IS NOT NULL`
+
+	args := pgx.NamedArgs{
+		"taskId":           taskId,
+		"processing":       tasksrepo.StatusProcessing,
+		"worker_id":        workerId,
+		"lease_expires_at": leaseExpiresAt,
+		"now":              now,
+	}
+
+	rows, err := s.pool.Query(ctx, query, args)
+	if err != nil {
+		return postgresdb.HandlePgError(err)
+	}
+	cancelRequested, err := pgx.CollectOneRow(rows, pgx.RowTo[bool])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tasksrepo.ErrLeaseLost
+		}
+		return postgresdb.HandlePgError(err)
+	}
+
+	if cancelRequested {
+		return tasksrepo.ErrCancelRequested
+	}
+	return nil
+}
+
+// SaveProgress records the progress of a processing task, provided workerId still holds it.
+// An empty step or a nil checkpoint keeps the one saved before.
+func (s *Store) SaveProgress(ctx context.Context, taskId string, workerId string, input tasksrepo.TaskProgress, now time.Time) error {
+	query := `
+		UPDATE public.tasks
+		SET
+			progress_percent = @progress_percent,
+			progress_step = COALESCE(NULLIF(@progress_step, ''), progress_step),
+			progress_updated_at = @now,
+			checkpoint = COALESCE(@checkpoint, checkpoint),
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status = @processing AND locked_by = @worker_id`
+
+	args := pgx.NamedArgs{
+		"taskId":           taskId,
+		"processing":       tasksrepo.StatusProcessing,
+		"worker_id":        workerId,
+		"progress_percent": input.Percent,
+		"progress_step":    input.Step,
+		"checkpoint":       input.Checkpoint,
+		"now":              now,
+	}
+
+	result, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return postgresdb.HandlePgError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return tasksrepo.ErrLeaseLost
+	}
+
+	return nil
+}
+
+// ReapExpired returns processing tasks with a lapsed lease to pending (or dead, once their
+// retries are used up), bumps retry_count and records the lost run in error_history.
+// Tasks that were asked to cancel are cancelled instead.
+// The dependents of dead-lettered and cancelled tasks are settled in the same transaction.
+// It reports how many tasks were reclaimed.
+func (s *Store) ReapExpired(ctx context.Context, now time.Time) (int, error) {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = CASE
+				WHEN cancel_requested_at IS NOT NULL THEN @cancelled
+				WHEN COALESCE(retry_count, 0) < COALESCE(max_retries, 0) THEN @pending
+				ELSE @dead
+			END,
+			dead_at = CASE
+				WHEN cancel_requested_at IS NOT NULL OR COALESCE(retry_count, 0) < COALESCE(max_retries, 0) THEN NULL
+				ELSE @now
+			END,
+			retry_count = COALESCE(retry_count, 0) + 1,
+			error_message = @error_message,
+			error_history = COALESCE(error_history, '[]'::jsonb) || jsonb_build_array(jsonb_build_object(
+				'run', COALESCE(retry_count, 0) + 1,
+				'attempt', 1,
+				'worker_id', locked_by,
+				'started_at', ` + jsonTimestamp("last_run_at") + `,
+				'failed_at', ` + jsonTimestamp("@now::timestamp") + `,
+				'error', @error_message::text
+			)),
+			locked_by = NULL,
+			lease_expires_at = NULL,
+			updated_at = @now
+		WHERE processing_status = @processing AND lease_expires_at < @now
+		RETURNING task_id, processing_status`
+
+	args := pgx.NamedArgs{
+		"pending":       tasksrepo.StatusPending,
+		"dead":          tasksrepo.StatusDead,
+		"cancelled":     tasksrepo.StatusCancelled,
+		"processing":    tasksrepo.StatusProcessing,
+		"error_message": "lease expired",
+		"now":           now,
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, postgresdb.HandlePgError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return 0, postgresdb.HandlePgError(err)
+	}
+	reaped, err := pgx.CollectRows(rows, pgx.RowToStructByPos[taskState])
+	if err != nil {
+		return 0, postgresdb.HandlePgError(err)
+	}
+
+	var failed []string
+	for _, task := range reaped {
+		if task.ProcessingStatus == tasksrepo.StatusDead || task.ProcessingStatus == tasksrepo.StatusCancelled {
+			failed = append(failed, task.TaskId)
+		}
+	}
+	if _, err := settleDependents(ctx, tx, failed, now); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, postgresdb.HandlePgError(err)
+	}
+	return len(reaped), nil
+}
+
+// Complete marks a processing task held by workerId as completed and releases its lease.
+func (s *Store) Complete(ctx context.Context, taskId string, workerId string, processingTimeMs int, now time.Time) error {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = @completed,
+			error_message = NULL,
+			processing_time_ms = @processing_time_ms,
+			locked_by = NULL,
+			lease_expires_at = NULL,
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status = @processing AND locked_by = @worker_id`
+
+	args := pgx.NamedArgs{
+		"taskId":             taskId,
+		"completed":          tasksrepo.StatusCompleted,
+		"processing":         tasksrepo.StatusProcessing,
+		"worker_id":          workerId,
+		"processing_time_ms": processingTimeMs,
+		"now":                now,
+	}
+
+	result, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return postgresdb.HandlePgError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return tasksrepo.ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Release returns a processing task to pending and clears its lease, provided workerId still
+// holds it. retry_count is left alone: the run was interrupted, it didn't fail. A task that was
+// asked to cancel is cancelled instead, settling its dependents in the same transaction.
+func (s *Store) Release(ctx context.Context, taskId string, workerId string, now time.Time) error {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = CASE WHEN cancel_requested_at IS NOT NULL THEN @cancelled ELSE @pending END,
+			locked_by = NULL,
+			lease_expires_at = NULL,
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status = @processing AND locked_by = @worker_id
+		RETURNING task_id, processing_status`
+
+	args := pgx.NamedArgs{
+		"taskId":     taskId,
+		"pending":    tasksrepo.StatusPending,
+		"cancelled":  tasksrepo.StatusCancelled,
+		"processing": tasksrepo.StatusProcessing,
+		"worker_id":  workerId,
+		"now":        now,
+	}
+
+	return s.settle(ctx, query, args, now)
+}
+
+// Cancel marks a processing task held by workerId as cancelled and releases its lease. The
+// task's dependents are settled in the same transaction.
+// processing_time_ms is measured from last_run_at, as for Fail.
+func (s *Store) Cancel(ctx context.Context, taskId string, workerId string, now time.Time) error {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = @cancelled,
+			error_message = @error_message,
+			processing_time_ms = (EXTRACT(EPOCH FROM (@now - last_run_at)) * 1000)::int,
+			locked_by = NULL,
+			lease_expires_at = NULL,
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status = @processing AND locked_by = @worker_id
+		RETURNING task_id, processing_status`
+
+	args := pgx.NamedArgs{
+		"taskId":        taskId,
+		"cancelled":     tasksrepo.StatusCancelled,
+		"processing":    tasksrepo.StatusProcessing,
+		"worker_id":     workerId,
+		"error_message": "cancelled",
+		"now":           now,
+	}
+
+	return s.settle(ctx, query, args, now)
+}
+
+// settle runs query, an update of a single task held by a worker that returns its task_id and
+// processing_status, and settles the task's dependents if it ended cancelled or dead.
+// Returns ErrLeaseLost when the update matched no task.
+func (s *Store) settle(ctx context.Context, query string, args pgx.NamedArgs, now time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return postgresdb.HandlePgError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return postgresdb.HandlePgError(err)
+	}
+	task, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[taskState])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tasksrepo.ErrLeaseLost
+		}
+		return postgresdb.HandlePgError(err)
+	}
+
+	if task.ProcessingStatus == tasksrepo.StatusDead || task.ProcessingStatus == tasksrepo.StatusCancelled {
+		if _, err := settleDependents(ctx, tx, []string{task.TaskId}, now); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return postgresdb.HandlePgError(err)
+	}
+	return nil
+}
+
+// Fail records a failed run, appends its attempts to error_history and releases the lease.
+// The task returns to pending while retry_count is below max_retries and is dead-lettered once
+// they are used up, or straight away for a permanent failure. A task that was asked to cancel
+// is cancelled instead. Dead-lettering or cancelling settles the task's dependents in the same
+// transaction.
+// processing_time_ms is measured from last_run_at, which Checkout stamps when the run starts.
+func (s *Store) Fail(ctx context.Context, taskId string, workerId string, input tasksrepo.FailTask, now time.Time) error {
+	attempts, err := json.Marshal(input.Attempts)
+	if err != nil {
+		return fmt.Errorf("encode attempts: %w", err)
+	}
+
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = CASE
+				WHEN cancel_requested_at IS NOT NULL THEN @cancelled
+				WHEN NOT @permanent AND COALESCE(retry_count, 0) < COALESCE(max_retries, 0) THEN @pending
+				ELSE @dead
+			END,
+			dead_at = CASE
+				WHEN cancel_requested_at IS NOT NULL OR (NOT @permanent AND COALESCE(retry_count, 0) < COALESCE(max_retries, 0)) THEN NULL
+				ELSE @now
+			END,
+			retry_count = COALESCE(retry_count, 0) + 1,
+			error_message = @error_message,
+			error_history = COALESCE(error_history, '[]'::jsonb) || (
+				SELECT COALESCE(jsonb_agg(attempt || jsonb_build_object('run', COALESCE(retry_count, 0) + 1)), '[]'::jsonb)
+				FROM jsonb_array_elements(@attempts::jsonb) AS attempt
+			),
+			processing_time_ms = (EXTRACT(EPOCH FROM (@now - last_run_at)) * 1000)::int,
+			locked_by = NULL,
+			lease_expires_at = NULL,
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status = @processing AND locked_by = @worker_id
+		RETURNING task_id, processing_status`
+
+	args := pgx.NamedArgs{
+		"taskId":        taskId,
+		"pending":       tasksrepo.StatusPending,
+		"dead":          tasksrepo.StatusDead,
+		"cancelled":     tasksrepo.StatusCancelled,
+		"processing":    tasksrepo.StatusProcessing,
+		"worker_id":     workerId,
+		"permanent":     input.Permanent,
+		"error_message": input.ErrorMessage,
+		"attempts":      string(attempts),
+		"now":           now,
+	}
+
+	return s.settle(ctx, query, args, now)
+}
+
+// CreateOnce inserts a task unless one with the same task_id exists. Returns false, and no
+// task, when the task was already there.
+func (s *Store) CreateOnce(ctx context.Context, input tasksrepo.CreateTask) (tasksrepo.Task, bool, error) {
+	return createOnce(ctx, s.pool, input)
+}
+
+// CreateUnique inserts a task unless its idempotency key is taken within its task_type: by a
+// task that is pending or processing, or whose idempotency_expires_at is after now. Returns the
+// task holding the key, and false, for a duplicate. Concurrent calls for the same key are
+// serialized with a transaction scoped advisory lock.
+func (s *Store) CreateUnique(ctx context.Context, input tasksrepo.CreateTask, now time.Time) (tasksrepo.Task, bool, error) {
+	return s.CreateWithDependencies(ctx, input, nil, now)
+}
+
+// CreateWithDependencies inserts a task and the dependencies it waits on in one transaction.
+// The parents are locked FOR SHARE while the dependencies are written, so a parent that fails
+// concurrently settles the new task too; a parent that has already failed settles it before the
+// commit. With an idempotency key it de-duplicates like CreateUnique, a duplicate's dependencies
+// are left as they are. Without one, false means the task_id was taken.
+func (s *Store) CreateWithDependencies(ctx context.Context, input tasksrepo.CreateTask, dependencies []tasksrepo.Dependency, now time.Time) (tasksrepo.Task, bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return tasksrepo.Task{}, false, postgresdb.HandlePgError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if input.IdempotencyKey != nil {
+		existing, found, err := findUnique(ctx, tx, input, now)
+		if err != nil {
+			return tasksrepo.Task{}, false, err
+		}
+		if found {
+			return existing, false, tx.Commit(ctx)
+		}
+	}
+
+	record, created, err := createOnce(ctx, tx, input)
+	if err != nil {
+		return tasksrepo.Task{}, false, err
+	}
+	if !created {
+		if input.IdempotencyKey != nil {
+			return tasksrepo.Task{}, false, fmt.Errorf("task id %v already taken", input.TaskId)
+		}
+		return tasksrepo.Task{}, false, nil
+	}
+
+	if len(dependencies) > 0 {
+		failed, err := addDependencies(ctx, tx, input.TaskId, dependencies, now)
+		if err != nil {
+			return tasksrepo.Task{}, false, err
+		}
+		settled, err := settleDependents(ctx, tx, failed, now)
+		if err != nil {
+			return tasksrepo.Task{}, false, err
+		}
+		if settled > 0 {
+			if record, err = getTask(ctx, tx, input.TaskId); err != nil {
+				return tasksrepo.Task{}, false, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return tasksrepo.Task{}, false, postgresdb.HandlePgError(err)
+	}
+	return record, true, nil
+}
+
+// findUnique takes the advisory lock on the input's idempotency key and looks for the task
+// holding it
+func findUnique(ctx context.Context, tx pgx.Tx, input tasksrepo.CreateTask, now time.Time) (tasksrepo.Task, bool, error) {
+	lockArgs := pgx.NamedArgs{
+		"lock_key": input.TaskType + "/" + *input.IdempotencyKey,
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended(@lock_key, 0))`, lockArgs); err != nil {
+		return tasksrepo.Task{}, false, postgresdb.HandlePgError(err)
+	}
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM public.tasks
+		WHERE task_type = @task_type
+			AND idempotency_key = @idempotency_key
+			AND (processing_status IN (@pending, @processing) OR idempotency_expires_at > @now)
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	args := pgx.NamedArgs{
+		"task_type":       input.TaskType,
+		"idempotency_key": *input.IdempotencyKey,
+		"pending":         tasksrepo.StatusPending,
+		"processing":      tasksrepo.StatusProcessing,
+		"now":             now,
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return tasksrepo.Task{}, false, postgresdb.HandlePgError(err)
+	}
+	existing, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[tasksrepo.Task])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tasksrepo.Task{}, false, nil
+		}
+		return tasksrepo.Task{}, false, postgresdb.HandlePgError(err)
+	}
+	return existing, true, nil
+}
+
+// querier is what createOnce needs from a pool or a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// createOnce runs the CreateOnce insert on q. Unset priority, max_retries and retry_count get
+// the column defaults.
+func createOnce(ctx context.Context, q querier, input tasksrepo.CreateTask) (tasksrepo.Task, bool, error) {
+	query := `
+		INSERT INTO public.tasks (task_id, processing_status, task_type, metadata, priority, max_retries, retry_count, run_at, idempotency_key, idempotency_expires_at, workflow_id)
+		VALUES (@task_id, @processing_status, @task_type, @metadata, COALESCE(@priority, 0), COALESCE(@max_retries, 3), COALESCE(@retry_count, 0), @run_at, @idempotency_key, @idempotency_expires_at, @workflow_id)
+		ON CONFLICT (task_id) DO NOTHING
+		RETURNING ` + taskColumns
+
+	args := pgx.NamedArgs{
+		"task_id":                input.TaskId,
+		"processing_status":      input.ProcessingStatus,
+		"task_type":              input.TaskType,
+		"metadata":               input.Metadata,
+		"priority":               input.Priority,
+		"max_retries":            input.MaxRetries,
+		"retry_count":            input.RetryCount,
+		"run_at":                 input.RunAt,
+		"idempotency_key":        input.IdempotencyKey,
+		"idempotency_expires_at": input.IdempotencyExpiresAt,
+		"workflow_id":            input.WorkflowId,
+	}
+
+	rows, err := q.Query(ctx, query, args)
+	if err != nil {
+		return tasksrepo.Task{}, false, postgresdb.HandlePgError(err)
+	}
+	defer rows.Close()
+
+	record, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[tasksrepo.Task])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tasksrepo.Task{}, false, nil
+		}
+		return tasksrepo.Task{}, false, postgresdb.HandlePgError(err)
+	}
+
+	return record, true, nil
+}
+
+// getTask reads a task within tx
+func getTask(ctx context.Context, tx pgx.Tx, taskId string) (tasksrepo.Task, error) {
+	rows, err := tx.Query(ctx, `SELECT `+taskColumns+` FROM public.tasks WHERE task_id = @taskId`, pgx.NamedArgs{"taskId": taskId})
+	if err != nil {
+		return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+	}
+	record, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[tasksrepo.Task])
+	if err != nil {
+		return tasksrepo.Task{}, postgresdb.HandlePgError(err)
+	}
+	return record, nil
+}
+
+// ========================================
+// DEPENDENCY QUERIES
+// ========================================
+
+// taskState is the id and status of a task, as returned by the updates that settle dependents
+type taskState struct {
+	TaskId           string
+	ProcessingStatus string
+}
+
+// candidateTask is the id and type of a checkout candidate
+type candidateTask struct {
+	TaskId   string
+	TaskType string
+}
+
+// typeCount is the number of tasks of a type
+type typeCount struct {
+	TaskType string
+	Count    int
+}
+
+// addDependencies records what taskId waits on. The parents are locked FOR SHARE until the
+// transaction ends, which makes a concurrent Fail of a parent wait for the dependencies to be
+// visible. Returns the parents that have already failed.
+func addDependencies(ctx context.Context, tx pgx.Tx, taskId string, dependencies []tasksrepo.Dependency, now time.Time) ([]string, error) {
+	parents := make([]string, len(dependencies))
+	onFailure := make([]string, len(dependencies))
+	for i, dependency := range dependencies {
+		parents[i] = dependency.DependsOnTaskId
+		onFailure[i] = dependency.OnFailure
+	}
+
+	lockQuery := `
+		SELECT task_id, processing_status
+		FROM public.tasks
+		WHERE task_id = ANY(@parents)
+		FOR SHARE`
+
+	rows, err := tx.Query(ctx, lockQuery, pgx.NamedArgs{"parents": parents})
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+	found, err := pgx.CollectRows(rows, pgx.RowToStructByPos[taskState])
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	var failed []string
+	existing := make(map[string]bool, len(found))
+	for _, parent := range found {
+		existing[parent.TaskId] = true
+		if parent.ProcessingStatus == tasksrepo.StatusDead || parent.ProcessingStatus == tasksrepo.StatusCancelled {
+			failed = append(failed, parent.TaskId)
+		}
+	}
+	for _, parent := range parents {
+		if !existing[parent] {
+			return nil, fmt.Errorf("task[%v]: %w", parent, tasksrepo.ErrDependencyNotFound)
+		}
+	}
+
+	query := `
+		INSERT INTO public.task_dependencies (task_id, depends_on_task_id, on_failure, created_at)
+		SELECT @task_id, parent, on_failure, @now
+		FROM unnest(@parents::varchar[], @on_failure::varchar[]) AS d(parent, on_failure)`
+
+	args := pgx.NamedArgs{
+		"task_id":    taskId,
+		"parents":    parents,
+		"on_failure": onFailure,
+		"now":        now,
+	}
+
+	if _, err := tx.Exec(ctx, query, args); err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+	return failed, nil
+}
+
+// settleDependents settles the pending tasks that depend on the failed (dead or cancelled)
+// tasks: a task with an on_failure = 'cancel' dependency on any failed parent is cancelled,
+// otherwise it is skipped. Cancelled tasks fail their own dependents in turn, so this walks down
+// the graph one level per query. Returns how many tasks were settled.
+func settleDependents(ctx context.Context, tx pgx.Tx, failed []string, now time.Time) (int, error) {
+	query := `
+		UPDATE public.tasks t
+		SET
+			processing_status = CASE
+				WHEN EXISTS (
+					SELECT 1
+					FROM public.task_dependencies d
+					JOIN public.tasks parent ON parent.task_id = d.depends_on_task_id
+					WHERE d.task_id = t.task_id AND d.on_failure = @cancel AND parent.processing_status IN (@dead, @cancelled)
+				) THEN @cancelled
+				ELSE @skipped
+			END,
+			error_message = @error_message,
+			updated_at = @now
+		WHERE t.processing_status = @pending
+			AND t.task_id IN (
+				SELECT d.task_id
+				FROM public.task_dependencies d
+				JOIN public.tasks parent ON parent.task_id = d.depends_on_task_id
+				WHERE d.depends_on_task_id = ANY(@failed) AND parent.processing_status IN (@dead, @cancelled)
+			)
+		RETURNING t.task_id, t.processing_status`
+
+	settled := 0
+	for len(failed) > 0 {
+		args := pgx.NamedArgs{
+			"failed":        failed,
+			"pending":       tasksrepo.StatusPending,
+			"dead":          tasksrepo.StatusDead,
+			"cancelled":     tasksrepo.StatusCancelled,
+			"skipped":       tasksrepo.StatusSkipped,
+			"cancel":        tasksrepo.OnFailureCancel,
+			"error_message": "dependency failed",
+			"now":           now,
+		}
+
+		rows, err := tx.Query(ctx, query, args)
+		if err != nil {
+			return settled, postgresdb.HandlePgError(err)
+		}
+		tasks, err := pgx.CollectRows(rows, pgx.RowToStructByPos[taskState])
+		if err != nil {
+			return settled, postgresdb.HandlePgError(err)
+		}
+		settled += len(tasks)
+
+		var cancelled []string
+		for _, task := range tasks {
+			if task.ProcessingStatus == tasksrepo.StatusCancelled {
+				cancelled = append(cancelled, task.TaskId)
+			}
+		}
+		failed = cancelled
+	}
+
+	return settled, nil
+}
+
+// ListWorkflow lists the tasks with the given workflow_id, oldest first
+func (s *Store) ListWorkflow(ctx context.Context, workflowId string) ([]tasksrepo.Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM public.tasks
+		WHERE workflow_id = @workflow_id
+		ORDER BY created_at ASC, task_id ASC`
+
+	rows, err := s.pool.Query(ctx, query, pgx.NamedArgs{"workflow_id": workflowId})
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByName[tasksrepo.Task])
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	return records, nil
+}
+
+// ListDependencies lists the dependencies of the given tasks
+func (s *Store) ListDependencies(ctx context.Context, taskIds []string) ([]tasksrepo.Dependency, error) {
+	query := `
+		SELECT task_id, depends_on_task_id, on_failure
+		FROM public.task_dependencies
+		WHERE task_id = ANY(@task_ids)
+		ORDER BY task_id, created_at, depends_on_task_id`
+
+	rows, err := s.pool.Query(ctx, query, pgx.NamedArgs{"task_ids": taskIds})
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	dependencies, err := pgx.CollectRows(rows, pgx.RowToStructByPos[tasksrepo.Dependency])
+	if err != nil {
+		return nil, postgresdb.HandlePgError(err)
+	}
+
+	return dependencies, nil
+}
+
+// ========================================
+// CANCELLATION QUERIES
+// ========================================
+
+// RequestCancel cancels a pending task straight away, settling its dependents, and flags a
+// processing task with cancel_requested_at for its worker to cancel. Returns the task's status
+// afterwards, or ErrTaskNotCancellable when the task doesn't exist or has already finished.
+func (s *Store) RequestCancel(ctx context.Context, taskId string, now time.Time) (string, error) {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = CASE WHEN processing_status = @pending THEN @cancelled ELSE processing_status END,
+			error_message = CASE WHEN processing_status = @pending THEN @error_message ELSE error_message END,
+			cancel_requested_at = COALESCE(cancel_requested_at, @now),
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status IN (@pending, @processing)
+		RETURNING task_id, processing_status`
+
+	args := pgx.NamedArgs{
+		"taskId":        taskId,
+		"pending":       tasksrepo.StatusPending,
+		"processing":    tasksrepo.StatusProcessing,
+		"cancelled":     tasksrepo.StatusCancelled,
+		"error_message": "cancelled",
+		"now":           now,
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return "", postgresdb.HandlePgError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return "", postgresdb.HandlePgError(err)
+	}
+	task, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[taskState])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", tasksrepo.ErrTaskNotCancellable
+		}
+		return "", postgresdb.HandlePgError(err)
+	}
+
+	if task.ProcessingStatus == tasksrepo.StatusCancelled {
+		if _, err := settleDependents(ctx, tx, []string{taskId}, now); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", postgresdb.HandlePgError(err)
+	}
+	return task.ProcessingStatus, nil
+}
+
+// ========================================
+// DEAD LETTER QUERIES
+// ========================================
+
+// Requeue moves a dead task back to pending with its retry_count reset. error_history is kept
+// so the earlier failures remain visible.
+func (s *Store) Requeue(ctx context.Context, taskId string, now time.Time) error {
+	query := `
+		UPDATE public.tasks
+		SET
+			processing_status = @pending,
+			retry_count = 0,
+			dead_at = NULL,
+			updated_at = @now
+		WHERE task_id = @taskId AND processing_status = @dead`
+
+	args := pgx.NamedArgs{
+		"taskId":  taskId,
+		"pending": tasksrepo.StatusPending,
+		"dead":    tasksrepo.StatusDead,
+		"now":     now,
+	}
+
+	result, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return postgresdb.HandlePgError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return tasksrepo.ErrTaskNotDead
+	}
+
+	return nil
+}
+
+// PurgeDead deletes dead tasks with dead_at before the given time
+func (s *Store) PurgeDead(ctx context.Context, before time.Time) (int, error) {
+	query := `
+		DELETE FROM public.tasks
+		WHERE processing_status = @dead AND dead_at < @before`
+
+	args := pgx.NamedArgs{
+		"dead":   tasksrepo.StatusDead,
+		"before": before,
+	}
+
+	result, err := s.pool.Exec(ctx, query, args)
+	if err != nil {
+		return 0, postgresdb.HandlePgError(err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+// jsonTimestamp formats a timestamp expression the way encoding/json writes a UTC time.Time,
+// so error_history entries built in SQL decode like the ones written from Go.
+func jsonTimestamp(expr string) string {
+	return `to_char(` + expr + `, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')`
+}
+
+// ========================================
+// NOTIFICATIONS
+// ========================================
+
+// NotifyChannel is the channel the tasks_notify_new trigger notifies when a task becomes
+// pending and due
+const NotifyChannel = "tasks_new"
+
+// NewListener creates a listener for new tasks. Pass it to workers.WithNotifier so idle
+// workers pick up new tasks immediately.
+func NewListener(log *logger.Logger, pool *postgresdb.Pool) *postgresdb.Listener {
+	return postgresdb.NewListener(pool, NotifyChannel, log.Logger)
+}
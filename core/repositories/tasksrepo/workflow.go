@@ -0,0 +1,185 @@
+package tasksrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Workflow errors
+var (
+	// ErrWorkflowNotFound is returned by WorkflowStatus when no task carries the workflow id.
+	ErrWorkflowNotFound = errors.New("workflow not found")
+
+	// ErrDependencyNotFound is returned when a task is created with a dependency on a task that doesn't exist.
+	ErrDependencyNotFound = errors.New("dependency task not found")
+)
+
+// ========================================
+// DEPENDENCIES
+// ========================================
+
+// What happens to a task when a task it depends on is dead-lettered or cancelled.
+const (
+	// OnFailureCancel cancels the task. Tasks depending on it see a failed parent in turn.
+	OnFailureCancel = "cancel"
+
+	// OnFailureSkip skips the task. Tasks depending on it treat it as done and carry on.
+	OnFailureSkip = "skip"
+)
+
+// Dependency makes TaskId wait until DependsOnTaskId has completed (or was skipped).
+type Dependency struct {
+	TaskId          string `json:"task_id"`
+	DependsOnTaskId string `json:"depends_on_task_id"`
+	OnFailure       string `json:"on_failure"` // OnFailureCancel or OnFailureSkip
+}
+
+// WithDependsOn makes the task wait until every one of taskIds has completed. It is cancelled if
+// one of them fails.
+func WithDependsOn(taskIds ...string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		for _, taskId := range taskIds {
+			o.dependencies = append(o.dependencies, Dependency{DependsOnTaskId: taskId, OnFailure: OnFailureCancel})
+		}
+	}
+}
+
+// WithDependency makes the task wait until taskId has completed. onFailure, OnFailureCancel or
+// OnFailureSkip, decides what happens to the task if taskId fails.
+func WithDependency(taskId string, onFailure string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.dependencies = append(o.dependencies, Dependency{DependsOnTaskId: taskId, OnFailure: onFailure})
+	}
+}
+
+// WithWorkflow groups the task with the other tasks of workflowId, see WorkflowStatus
+func WithWorkflow(workflowId string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.workflowId = workflowId
+	}
+}
+
+// CreateWithDependencies creates a task that waits on the given dependencies, in one
+// transaction, and reports whether it was created. A dependency on a task that has already
+// failed settles the new task right away. With input.IdempotencyKey set it behaves like
+// CreateUnique. An empty ProcessingStatus defaults to pending, an empty OnFailure to cancel.
+func (r *Repository) CreateWithDependencies(ctx context.Context, input CreateTask, dependencies []Dependency) (Task, bool, error) {
+	if input.ProcessingStatus == "" {
+		input.ProcessingStatus = StatusPending
+	}
+
+	dependencies = slices.Clone(dependencies)
+	seen := make(map[string]bool, len(dependencies))
+	for i := range dependencies {
+		dependency := &dependencies[i]
+		dependency.TaskId = input.TaskId
+		if dependency.OnFailure == "" {
+			dependency.OnFailure = OnFailureCancel
+		}
+
+		switch {
+		case dependency.DependsOnTaskId == "":
+			return Task{}, false, fmt.Errorf("create task[%v]: dependency without a task id", input.TaskId)
+		case dependency.DependsOnTaskId == input.TaskId:
+			return Task{}, false, fmt.Errorf("create task[%v]: task can't depend on itself", input.TaskId)
+		case dependency.OnFailure != OnFailureCancel && dependency.OnFailure != OnFailureSkip:
+			return Task{}, false, fmt.Errorf("create task[%v]: unknown on_failure %q", input.TaskId, dependency.OnFailure)
+		case seen[dependency.DependsOnTaskId]:
+			return Task{}, false, fmt.Errorf("create task[%v]: duplicate dependency on task[%v]", input.TaskId, dependency.DependsOnTaskId)
+		}
+		seen[dependency.DependsOnTaskId] = true
+	}
+
+	task, created, err := r.storer.CreateWithDependencies(ctx, input, dependencies, time.Now().UTC())
+	if err != nil {
+		return Task{}, false, fmt.Errorf("create task[%v] with dependencies: %w", input.TaskId, err)
+	}
+	return task, created, nil
+}
+
+// ========================================
+// WORKFLOWS
+// ========================================
+
+// Workflow statuses, derived from the statuses of a workflow's tasks.
+const (
+	WorkflowPending   = "pending"   // No task has started
+	WorkflowRunning   = "running"   // Some tasks are done, others are still to run
+	WorkflowCompleted = "completed" // Every task completed or was skipped
+	WorkflowFailed    = "failed"    // Nothing is left to run and some tasks died or were cancelled
+)
+
+// WorkflowStatus aggregates the tasks of a workflow
+type WorkflowStatus struct {
+	WorkflowId string               `json:"workflow_id"`
+	Status     string               `json:"status"`
+	Total      int                  `json:"total"`
+	Counts     map[string]int       `json:"counts"` // Tasks per processing status
+	Tasks      []WorkflowTaskStatus `json:"tasks"`  // Oldest first
+}
+
+// WorkflowTaskStatus is a task of a workflow and the tasks it waits on
+type WorkflowTaskStatus struct {
+	TaskStatus
+	DependsOn []Dependency `json:"depends_on,omitempty"`
+}
+
+// WorkflowStatus returns the status of every task of workflowId and of the workflow as a whole.
+// Returns ErrWorkflowNotFound when it has no tasks.
+func (r *Repository) WorkflowStatus(ctx context.Context, workflowId string) (WorkflowStatus, error) {
+	tasks, err := r.storer.ListWorkflow(ctx, workflowId)
+	if err != nil {
+		return WorkflowStatus{}, fmt.Errorf("workflow status[%v]: %w", workflowId, err)
+	}
+	if len(tasks) == 0 {
+		return WorkflowStatus{}, fmt.Errorf("workflow status[%v]: %w", workflowId, ErrWorkflowNotFound)
+	}
+
+	taskIds := make([]string, len(tasks))
+	for i, task := range tasks {
+		taskIds[i] = task.TaskId
+	}
+	dependencies, err := r.storer.ListDependencies(ctx, taskIds)
+	if err != nil {
+		return WorkflowStatus{}, fmt.Errorf("workflow status[%v]: %w", workflowId, err)
+	}
+	dependsOn := make(map[string][]Dependency)
+	for _, dependency := range dependencies {
+		dependsOn[dependency.TaskId] = append(dependsOn[dependency.TaskId], dependency)
+	}
+
+	status := WorkflowStatus{
+		WorkflowId: workflowId,
+		Total:      len(tasks),
+		Counts:     make(map[string]int),
+		Tasks:      make([]WorkflowTaskStatus, 0, len(tasks)),
+	}
+	for _, task := range tasks {
+		status.Counts[task.ProcessingStatus]++
+		status.Tasks = append(status.Tasks, WorkflowTaskStatus{
+			TaskStatus: taskStatus(task),
+			DependsOn:  dependsOn[task.TaskId],
+		})
+	}
+	status.Status = workflowState(status.Counts, status.Total)
+
+	return status, nil
+}
+
+// workflowState derives a workflow's status from its task counts
+func workflowState(counts map[string]int, total int) string {
+	active := counts[StatusPending] + counts[StatusProcessing]
+	switch {
+	case counts[StatusCompleted]+counts[StatusSkipped] == total:
+		return WorkflowCompleted
+	case active == 0:
+		return WorkflowFailed
+	case counts[StatusPending] == total:
+		return WorkflowPending
+	default:
+		return WorkflowRunning
+	}
+}
@@ -0,0 +1,242 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// limiterPoll is how often a ConcurrencyLimiter retries while every slot of a key is taken
+const limiterPoll = 100 * time.Millisecond
+
+// dbNow is the database clock in UTC. Limiters use it instead of the caller's clock so
+// processes with skewed clocks agree on the state of a key.
+const dbNow = "(now() AT TIME ZONE 'utc')"
+
+// limitKey scopes key to the limiter name
+func limitKey(name, key string) string {
+	if key == "" {
+		return name
+	}
+	return name + "/" + key
+}
+
+// ================================================================================
+// RateLimiter
+// ================================================================================
+
+// RateLimiter is a token bucket per key kept in the rate_limits table, so every process using
+// the same name shares one quota. RateLimiter satisfies workers.Limiter.
+type RateLimiter struct {
+	pool  *Pool
+	name  string
+	rate  float64
+	burst float64
+}
+
+// NewRateLimiter creates a limiter named name with rps tokens per second and room for burst
+// per key. A burst below 1 is raised to 1.
+func NewRateLimiter(pool *Pool, name string, rps float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		pool:  pool,
+		name:  name,
+		rate:  rps,
+		burst: float64(max(burst, 1)),
+	}
+}
+
+// Acquire takes a token for key, waiting until one is available. The token is handed back if
+// ctx is done first. The returned release does nothing, tokens are spent once taken.
+func (l *RateLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	limitKey := limitKey(l.name, key)
+
+	// Refill from the time elapsed since the last take and take a token, possibly one that
+	// hasn't been added yet
+	const q = `
+	INSERT INTO rate_limits (limit_key, tokens, updated_at)
+	VALUES (@limit_key, @burst::float8 - 1, ` + dbNow + `)
+	ON CONFLICT (limit_key) DO UPDATE SET
+		tokens = LEAST(@burst::float8, rate_limits.tokens + EXTRACT(EPOCH FROM (EXCLUDED.updated_at - rate_limits.updated_at))::float8 * @rate) - 1,
+		updated_at = EXCLUDED.updated_at
+	RETURNING tokens`
+
+	var tokens float64
+	err := l.pool.QueryRow(ctx, q, pgx.NamedArgs{
+		"limit_key": limitKey,
+		"burst":     l.burst,
+		"rate":      l.rate,
+	}).Scan(&tokens)
+	if err != nil {
+		return nil, fmt.Errorf("take token[%v]: %w", limitKey, HandlePgError(err))
+	}
+	if tokens >= 0 {
+		return func() {}, nil
+	}
+
+	wait := time.Duration(math.MaxInt64)
+	if l.rate > 0 {
+		wait = time.Duration(-tokens / l.rate * float64(time.Second))
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return func() {}, nil
+	case <-ctx.Done():
+		l.refund(limitKey)
+		return nil, ctx.Err()
+	}
+}
+
+// refund hands back a token reserved by a caller that gave up waiting. It is best effort, a
+// token that can't be handed back only delays later callers.
+func (l *RateLimiter) refund(limitKey string) {
+	const q = `UPDATE rate_limits SET tokens = LEAST(@burst::float8, tokens + 1) WHERE limit_key = @limit_key`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = l.pool.Exec(ctx, q, pgx.NamedArgs{"limit_key": limitKey, "burst": l.burst})
+}
+
+// ================================================================================
+// ConcurrencyLimiter
+// ================================================================================
+
+// ConcurrencyLimiter allows at most limit holders per key across every process using the same
+// name. Each holder has a row in concurrency_leases that it extends while it works; a lease
+// that isn't extended, because its process died, expires and frees the slot.
+// ConcurrencyLimiter satisfies workers.Limiter.
+type ConcurrencyLimiter struct {
+	pool  *Pool
+	name  string
+	limit int
+	lease time.Duration
+	log   *slog.Logger
+}
+
+// NewConcurrencyLimiter creates a limiter named name with limit slots per key. Holders extend
+// their lease every third of lease. A limit below 1 is raised to 1, a lease below a second to
+// a second.
+func NewConcurrencyLimiter(pool *Pool, name string, limit int, lease time.Duration, log *slog.Logger) *ConcurrencyLimiter {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &ConcurrencyLimiter{
+		pool:  pool,
+		name:  name,
+		limit: max(limit, 1),
+		lease: max(lease, time.Second),
+		log:   log,
+	}
+}
+
+// Acquire takes a slot of key, polling until one is free. The lease is extended until release
+// is called.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	limitKey := limitKey(l.name, key)
+
+	ticker := time.NewTicker(limiterPoll)
+	defer ticker.Stop()
+	for {
+		leaseId, acquired, err := l.tryAcquire(ctx, limitKey)
+		if err != nil {
+			return nil, fmt.Errorf("acquire lease[%v]: %w", limitKey, err)
+		}
+		if acquired {
+			return l.hold(limitKey, leaseId), nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// tryAcquire inserts a lease for limitKey if it has a free slot. Expired leases are cleared
+// first. The advisory lock serializes acquirers of a key so the count can't go stale.
+func (l *ConcurrencyLimiter) tryAcquire(ctx context.Context, limitKey string) (string, bool, error) {
+	tx, err := l.pool.Begin(ctx)
+	if err != nil {
+		return "", false, HandlePgError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	args := pgx.NamedArgs{
+		"limit_key":     limitKey,
+		"limit":         l.limit,
+		"lease_seconds": l.lease.Seconds(),
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended(@limit_key, 0))`, args); err != nil {
+		return "", false, HandlePgError(err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM concurrency_leases WHERE limit_key = @limit_key AND expires_at <= `+dbNow, args); err != nil {
+		return "", false, HandlePgError(err)
+	}
+
+	const q = `
+	INSERT INTO concurrency_leases (limit_key, expires_at)
+	SELECT @limit_key, ` + dbNow + ` + make_interval(secs => @lease_seconds)
+	WHERE (SELECT count(*) FROM concurrency_leases WHERE limit_key = @limit_key) < @limit
+	RETURNING lease_id`
+
+	var leaseId string
+	err = tx.QueryRow(ctx, q, args).Scan(&leaseId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, HandlePgError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", false, HandlePgError(err)
+	}
+	return leaseId, true, nil
+}
+
+// hold extends leaseId in the background and returns the func that releases it
+func (l *ConcurrencyLimiter) hold(limitKey, leaseId string) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(l.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				const q = `UPDATE concurrency_leases SET expires_at = ` + dbNow + ` + make_interval(secs => @lease_seconds) WHERE lease_id = @lease_id`
+				if _, err := l.pool.Exec(ctx, q, pgx.NamedArgs{"lease_id": leaseId, "lease_seconds": l.lease.Seconds()}); err != nil && ctx.Err() == nil {
+					l.log.Error("extending concurrency lease", "limit_key", limitKey, "lease_id", leaseId, "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := l.pool.Exec(ctx, `DELETE FROM concurrency_leases WHERE lease_id = @lease_id`, pgx.NamedArgs{"lease_id": leaseId}); err != nil {
+				// The slot frees up once the lease expires
+				l.log.Error("releasing concurrency lease", "limit_key", limitKey, "lease_id", leaseId, "error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package postgresdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Listener receives Postgres notifications (LISTEN/NOTIFY) on a channel. It holds a dedicated
+// connection outside the pool while listening, so it never ties up a pooled connection.
+// Listener satisfies workers.Notifier.
+type Listener struct {
+	pool    *Pool
+	channel string
+	log     *slog.Logger
+}
+
+// NewListener creates a listener for channel that connects with the pool's configuration
+func NewListener(pool *Pool, channel string, log *slog.Logger) *Listener {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Listener{
+		pool:    pool,
+		channel: channel,
+		log:     log,
+	}
+}
+
+// Listen opens a dedicated connection, LISTENs on the channel and calls notify for every
+// notification until ctx is cancelled. It returns nil on cancellation and an error if the
+// connection fails; callers are expected to call Listen again to reconnect.
+func (l *Listener) Listen(ctx context.Context, notify func()) error {
+	conn, err := pgx.ConnectConfig(ctx, l.pool.Config().ConnConfig.Copy())
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("connecting listener: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("listen %s: %w", l.channel, err)
+	}
+
+	l.log.InfoContext(ctx, "listening for notifications", "channel", l.channel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("waiting for notification on %s: %w", l.channel, err)
+		}
+
+		l.log.DebugContext(ctx, "notification received", "channel", notification.Channel, "payload", notification.Payload)
+		notify()
+	}
+}
@@ -0,0 +1,89 @@
+package workers
+
+import (
+	"context"
+	"time"
+)
+
+// autoscaler holds the bounds and pacing of a pool's automatic resizing, see WithAutoscale
+type autoscaler struct {
+	min, max    int
+	interval    time.Duration
+	idlePeriods int // Idle intervals in a row before a worker is removed
+}
+
+// autoscaleSample is what the autoscaler saw over one interval
+type autoscaleSample struct {
+	checkedOut     int64 // Checkouts that returned a task
+	checkoutErrors int64 // Checkouts that found no work (or failed)
+}
+
+// decide returns the worker count for the next interval. idle counts the idle intervals in a row
+// and is updated in place.
+//
+// Every checkout finding work means the workers can't keep up, so the pool doubles to catch up
+// with a burst quickly. Only checkouts coming back empty, interval after interval, shrink it, one
+// worker at a time. Intervals where workers are busy with long tasks and don't check anything
+// out are neutral.
+func (a *autoscaler) decide(current int, sample autoscaleSample, idle *int) int {
+	target := current
+	switch {
+	case sample.checkedOut > 0 && sample.checkoutErrors == 0:
+		*idle = 0
+		target = current * 2
+	case sample.checkedOut == 0 && sample.checkoutErrors > 0:
+		*idle++
+		if *idle >= a.idlePeriods {
+			*idle = 0
+			target = current - 1
+		}
+	case sample.checkedOut > 0:
+		// Some workers found work, some didn't: about the right size
+		*idle = 0
+	}
+	return min(max(target, a.min), a.max)
+}
+
+// autoscale resizes the pool every interval based on the checkout counters in its metrics
+func (wp *WorkerPool[T]) autoscale() {
+	defer wp.background.Done()
+
+	ticker := time.NewTicker(wp.autoscaler.interval)
+	defer ticker.Stop()
+
+	last := wp.metrics.GetSnapshot()
+	idle := 0
+
+	for {
+		select {
+		case <-wp.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		snapshot := wp.metrics.GetSnapshot()
+		sample := autoscaleSample{
+			checkedOut:     snapshot.TasksCheckedOut - last.TasksCheckedOut,
+			checkoutErrors: snapshot.CheckoutErrors - last.CheckoutErrors,
+		}
+		last = snapshot
+
+		current := wp.WorkerCount()
+		target := wp.autoscaler.decide(current, sample, &idle)
+		if target == current {
+			continue
+		}
+
+		wp.log.InfoContext(context.Background(), "autoscaling worker pool",
+			"pool", wp.name,
+			"from", current,
+			"to", target,
+			"checked_out", sample.checkedOut,
+			"checkout_errors", sample.checkoutErrors)
+		if err := wp.Resize(target); err != nil {
+			wp.log.ErrorContext(context.Background(), "failed to autoscale worker pool",
+				"pool", wp.name,
+				"error", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package workers_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// eventually polls cond until it holds or the timeout passes
+func eventually(t *testing.T, timeout time.Duration, cond func() bool, msg string, args ...any) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf(msg, args...)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	metrics := workers.NewInMemoryMetrics()
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		return task, nil
+	})
+
+	pool, err := workers.NewWorkerPool("resize-pool", 1, workers.NewQueueProcessor[TestTask](memqueue.New[TestTask](), handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	if err := pool.Resize(0); err == nil {
+		t.Error("expected an error resizing to 0 workers")
+	}
+	// A stopped pool just remembers the size for the next Start
+	if err := pool.Resize(3); err != nil {
+		t.Fatalf("resize failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	defer func() {
+		pool.Stop()
+		<-done
+	}()
+
+	active := func() int64 { return metrics.GetSnapshot().WorkersActive }
+	eventually(t, time.Second, func() bool { return active() == 3 }, "expected 3 workers after Start, got %d", active())
+
+	if err := pool.Resize(6); err != nil {
+		t.Fatalf("resize failed: %v", err)
+	}
+	eventually(t, time.Second, func() bool { return active() == 6 }, "expected 6 workers after growing, got %d", active())
+
+	if err := pool.Resize(2); err != nil {
+		t.Fatalf("resize failed: %v", err)
+	}
+	eventually(t, time.Second, func() bool { return active() == 2 }, "expected 2 workers after shrinking, got %d", active())
+
+	if got := pool.WorkerCount(); got != 2 {
+		t.Errorf("expected WorkerCount 2, got %d", got)
+	}
+}
+
+func TestWorkerPool_AutoscaleFollowsQueueDepth(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	for i := 0; i < 300; i++ {
+		enqueue(t, queue, TestTask{ID: fmt.Sprintf("task-%d", i)})
+	}
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		time.Sleep(10 * time.Millisecond)
+		return task, nil
+	})
+
+	pool, err := workers.NewWorkerPool("autoscale-pool", 1, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(5*time.Millisecond),
+		workers.WithIdleInterval(10*time.Millisecond),
+		workers.WithAutoscale(1, 8),
+		workers.WithAutoscaleInterval(50*time.Millisecond),
+		workers.WithAutoscaleIdlePeriods(2),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	defer func() {
+		pool.Stop()
+		<-done
+	}()
+
+	// A deep queue grows the pool to its max
+	eventually(t, 2*time.Second, func() bool { return pool.WorkerCount() == 8 }, "expected the pool to grow to 8 workers, got %d", pool.WorkerCount())
+
+	pending := func() int { return queue.Stats().Pending }
+	eventually(t, 5*time.Second, func() bool { return pending() == 0 }, "queue not drained, %d tasks left", pending())
+
+	// An empty queue shrinks it back to its min
+	eventually(t, 5*time.Second, func() bool { return pool.WorkerCount() == 1 }, "expected the pool to shrink to 1 worker, got %d", pool.WorkerCount())
+}
@@ -0,0 +1,445 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchResult is the outcome of one task of a batch
+type BatchResult[T Task] struct {
+	Task T     // Handed to Complete, return the task given when processing doesn't change it
+	Err  error // Nil when the task succeeded
+}
+
+// BatchHandler is implemented by handlers that are more efficient working several tasks at once,
+// e.g. bulk upserts. When the processor (or the handler behind a QueueProcessor) implements it,
+// the pool runs in batch mode: each worker checks out up to the batch size (see WithBatchSize),
+// processes the tasks in one ProcessBatch call and completes or fails them one by one.
+type BatchHandler[T Task] interface {
+	// ProcessBatch processes the tasks together and returns one result per task, in the same
+	// order. A returned error fails every task of the batch.
+	ProcessBatch(ctx context.Context, tasks []T) ([]BatchResult[T], error)
+}
+
+// BatchQueue is implemented by queues that can claim several tasks in one go. Without it, batches
+// are checked out one Checkout (or CheckoutLeased) at a time.
+type BatchQueue[T Task] interface {
+	// CheckoutBatch claims up to size tasks, atomically like Checkout, and leases them to workerID
+	// for lease like CheckoutLeased. Queues that don't lease tasks ignore lease. Returns
+	// ErrNoWorkAvailable when there are none.
+	CheckoutBatch(ctx context.Context, workerID string, size int, lease time.Duration) ([]T, error)
+}
+
+// BatchProcessor is a Processor that checks out and processes tasks in batches
+type BatchProcessor[T Task] interface {
+	Processor[T]
+	BatchQueue[T]
+	BatchHandler[T]
+}
+
+// BatchHandlerFunc adapts a plain function to a BatchHandler. It is also a Handler, processing
+// a single task as a batch of one.
+type BatchHandlerFunc[T Task] func(ctx context.Context, tasks []T) ([]BatchResult[T], error)
+
+// ProcessBatch calls f(ctx, tasks)
+func (f BatchHandlerFunc[T]) ProcessBatch(ctx context.Context, tasks []T) ([]BatchResult[T], error) {
+	return f(ctx, tasks)
+}
+
+// Process runs task as a batch of one
+func (f BatchHandlerFunc[T]) Process(ctx context.Context, task T) (T, error) {
+	results, err := f(ctx, []T{task})
+	if err != nil {
+		return task, err
+	}
+	if len(results) == 0 {
+		return task, fmt.Errorf("no result for task %s", task.GetID())
+	}
+	return results[0].Task, results[0].Err
+}
+
+// WithBatchSize sets how many tasks a worker checks out at once in batch mode (default 10)
+func WithBatchSize(size int) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithBatchMaxWait lets a worker that checked out fewer tasks than the batch size keep topping
+// the batch up, every poll interval, for up to wait before processing it. 0 processes whatever
+// the first checkout found. Keep it well below the lease duration, leases aren't extended while
+// the batch fills up.
+func WithBatchMaxWait(wait time.Duration) Option {
+	return func(o *options) {
+		o.batchMaxWait = wait
+	}
+}
+
+// ================================================================================
+// Batch mode
+// ================================================================================
+
+// workBatch is work for batch mode: Checkout up to batchSize -> ProcessBatch -> Complete/Fail
+// each task. Failed tasks are retried together per the pool's retry settings; a PolicyProvider
+// or TimeoutTask doesn't apply to batches.
+func (wp *WorkerPool[T]) workBatch(ctx context.Context, workerID string) error {
+	checkoutStart := time.Now()
+	tasks, err := wp.checkoutBatch(ctx, workerID)
+	wp.metrics.RecordCheckoutLatency(time.Since(checkoutStart))
+	if err != nil {
+		wp.metrics.RecordCheckoutError()
+		if errors.Is(err, ErrNoWorkAvailable) {
+			return err
+		}
+		return fmt.Errorf("checkout failed: %w", err)
+	}
+
+	labels := make([]TaskLabels, len(tasks))
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		wp.metrics.RecordTaskCheckedOut()
+		labels[i] = taskLabels(task, workerID)
+		ids[i] = task.GetID()
+	}
+	if handle := wp.handle(workerID); handle != nil {
+		handle.startTask(strings.Join(ids, ","), nil)
+		defer handle.finishTask()
+	}
+
+	// The batch gets its own context so the drain deadline can abort it, see work
+	processCtx, cancelProcess := context.WithCancelCause(ctx)
+	defer cancelProcess(nil)
+	finishCtx := context.WithoutCancel(ctx)
+
+	for _, task := range tasks {
+		for _, hook := range wp.preProcessHooks {
+			if err := hook(ctx, task); err != nil {
+				wp.log.ErrorContext(ctx, "pre-process hook failed",
+					"task_id", task.GetID(),
+					"error", err)
+			}
+		}
+	}
+
+	wp.log.InfoContext(ctx, "processing batch",
+		"worker_id", workerID,
+		"batch_size", len(tasks))
+
+	startTime := time.Now()
+	stopHeartbeat := wp.startBatchHeartbeat(processCtx, workerID, tasks)
+	results := wp.processBatchWithRetry(processCtx, tasks, labels)
+	leases := stopHeartbeat()
+	duration := time.Since(startTime)
+	cause := context.Cause(processCtx)
+
+	failed := 0
+	for i, task := range tasks {
+		result := results[i]
+		hookTask := result.Task
+		if result.Err != nil {
+			hookTask = task
+			failed++
+		}
+		for _, hook := range wp.postProcessHooks {
+			if err := hook(ctx, hookTask, result.Err); err != nil {
+				wp.log.ErrorContext(ctx, "post-process hook failed",
+					"task_id", task.GetID(),
+					"error", err)
+			}
+		}
+
+		taskCause := cause
+		if leases[i] != nil {
+			taskCause = leases[i]
+		}
+		wp.finish(ctx, finishCtx, workerID, task, result.Task, result.Err, taskCause, labels[i], duration)
+	}
+
+	if failed > 0 {
+		wp.log.ErrorContext(ctx, "batch processed with failures",
+			"worker_id", workerID,
+			"batch_size", len(tasks),
+			"failed", failed,
+			"duration_ms", int(duration.Milliseconds()))
+		return fmt.Errorf("batch processing error: %d of %d tasks failed", failed, len(tasks))
+	}
+
+	wp.log.InfoContext(ctx, "batch completed",
+		"worker_id", workerID,
+		"batch_size", len(tasks),
+		"duration_ms", int(duration.Milliseconds()))
+	return nil
+}
+
+// checkoutBatch claims up to batchSize tasks. Once it has some, it keeps topping the batch up
+// every poll interval until the batch is full or batchMaxWait has passed.
+func (wp *WorkerPool[T]) checkoutBatch(ctx context.Context, workerID string) ([]T, error) {
+	tasks, err := wp.checkoutUpTo(ctx, workerID, wp.batchSize)
+	if err != nil || wp.batchMaxWait <= 0 {
+		return tasks, err
+	}
+
+	deadline := time.Now().Add(wp.batchMaxWait)
+	for len(tasks) < wp.batchSize {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		select {
+		case <-wp.ctx.Done():
+			// Stopping, process what we have
+			return tasks, nil
+		case <-time.After(min(wp.pollInterval, remaining)):
+		}
+
+		more, err := wp.checkoutUpTo(ctx, workerID, wp.batchSize-len(tasks))
+		if err != nil {
+			if !errors.Is(err, ErrNoWorkAvailable) {
+				wp.log.ErrorContext(ctx, "failed to top up batch",
+					"worker_id", workerID,
+					"error", err)
+			}
+			continue
+		}
+		tasks = append(tasks, more...)
+	}
+	return tasks, nil
+}
+
+// checkoutUpTo claims up to n tasks, through the BatchQueue when there is one. Tasks already
+// claimed are returned even if a later checkout fails, they have to be processed.
+func (wp *WorkerPool[T]) checkoutUpTo(ctx context.Context, workerID string, n int) ([]T, error) {
+	if wp.batchQueue != nil {
+		tasks, err := wp.batchQueue.CheckoutBatch(ctx, workerID, n, wp.leaseDuration)
+		if err == nil && len(tasks) == 0 {
+			return nil, ErrNoWorkAvailable
+		}
+		return tasks, err
+	}
+
+	var tasks []T
+	for len(tasks) < n {
+		task, err := wp.checkout(ctx, workerID)
+		if err != nil {
+			if len(tasks) == 0 {
+				return nil, err
+			}
+			if !errors.Is(err, ErrNoWorkAvailable) {
+				wp.log.ErrorContext(ctx, "checkout failed, processing a partial batch",
+					"worker_id", workerID,
+					"batch_size", len(tasks),
+					"error", err)
+			}
+			break
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// processBatchWithRetry runs the tasks through ProcessBatch. The tasks that fail with a
+// retryable error are retried together in a smaller batch until they succeed or maxRetries is
+// used up. Failures are returned as a *ProcessError holding every attempt of the task.
+func (wp *WorkerPool[T]) processBatchWithRetry(ctx context.Context, tasks []T, labels []TaskLabels) []BatchResult[T] {
+	maxAttempts := max(wp.maxRetries, 1)
+	results := make([]BatchResult[T], len(tasks))
+	attempts := make([][]Attempt, len(tasks))
+	pending := make([]int, len(tasks)) // Indexes of the tasks still to process
+	for i, task := range tasks {
+		results[i].Task = task
+		pending[i] = i
+	}
+
+	for attempt := 1; attempt <= maxAttempts && len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			// The longest RetryAfter hint wins over the backoff policy
+			delay, hinted := time.Duration(0), false
+			for _, i := range pending {
+				if hint, ok := retryAfterHint(results[i].Err); ok {
+					delay, hinted = max(delay, hint), true
+				}
+				wp.metrics.RecordRetryAttempt()
+				if wp.labelled != nil {
+					wp.labelled.RecordLabelledRetry(labels[i])
+				}
+			}
+			if !hinted {
+				delay = wp.backoff.Delay(attempt - 1)
+			}
+			wp.log.InfoContext(ctx, "retrying batch tasks",
+				"tasks", len(pending),
+				"attempt", attempt,
+				"max_attempts", maxAttempts,
+				"delay", delay)
+
+			select {
+			case <-ctx.Done():
+				for _, i := range pending {
+					results[i].Err = ctx.Err()
+				}
+				return results
+			case <-time.After(delay):
+			}
+		}
+
+		batch := make([]T, len(pending))
+		for j, i := range pending {
+			batch[j] = tasks[i]
+		}
+		startedAt := time.Now()
+		batchResults, batchErr := wp.processBatchAttempt(ctx, batch)
+
+		var retry []int
+		for j, i := range pending {
+			err := batchErr
+			if err == nil {
+				if j < len(batchResults) {
+					results[i].Task, err = batchResults[j].Task, batchResults[j].Err
+				} else {
+					err = fmt.Errorf("no result for task %s", tasks[i].GetID())
+				}
+			}
+			if err == nil {
+				results[i].Err = nil
+				if attempt > 1 {
+					wp.metrics.RecordRetrySuccess()
+				}
+				continue
+			}
+			results[i].Task = tasks[i]
+			if ctx.Err() != nil {
+				results[i].Err = ctx.Err()
+				continue
+			}
+
+			attempts[i] = append(attempts[i], Attempt{
+				Number:    attempt,
+				StartedAt: startedAt,
+				FailedAt:  time.Now(),
+				Error:     err.Error(),
+			})
+			wp.log.ErrorContext(ctx, "task processing attempt failed",
+				"task_id", tasks[i].GetID(),
+				"attempt", attempt,
+				"error", err)
+
+			if !IsRetryable(err) {
+				results[i].Err = &ProcessError{
+					Attempts: attempts[i],
+					Err:      fmt.Errorf("failed permanently on attempt %d: %w", attempt, err),
+				}
+				continue
+			}
+			results[i].Err = err
+			retry = append(retry, i)
+		}
+		if ctx.Err() != nil {
+			return results
+		}
+		pending = retry
+	}
+
+	for _, i := range pending {
+		if maxAttempts > 1 {
+			wp.metrics.RecordRetryExhausted()
+		}
+		results[i].Err = &ProcessError{
+			Attempts: attempts[i],
+			Err:      fmt.Errorf("failed after %d attempts: %w", maxAttempts, results[i].Err),
+		}
+	}
+	return results
+}
+
+// processBatchAttempt runs one ProcessBatch call, bounded by the task timeout. A panic fails the
+// whole batch for good, as it does a single task.
+func (wp *WorkerPool[T]) processBatchAttempt(ctx context.Context, tasks []T) (results []BatchResult[T], err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			wp.log.ErrorContext(ctx, "panic recovered in batch",
+				"batch_size", len(tasks),
+				"panic", r,
+				"stack_trace", string(debug.Stack()))
+			wp.metrics.RecordWorkerPanic()
+			results, err = nil, Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if wp.taskTimeout <= 0 {
+		return wp.batchHandler.ProcessBatch(ctx, tasks)
+	}
+
+	attemptCtx, cancel := context.WithTimeoutCause(ctx, wp.taskTimeout, ErrTaskTimeout)
+	defer cancel()
+	results, err = wp.batchHandler.ProcessBatch(attemptCtx, tasks)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(attemptCtx), ErrTaskTimeout) {
+		wp.metrics.RecordTaskTimeout()
+		err = fmt.Errorf("%w after %s: %w", ErrTaskTimeout, wp.taskTimeout, err)
+	}
+	return results, err
+}
+
+// startBatchHeartbeat extends the leases of a batch's tasks every heartbeatInterval until the
+// returned stop function is called. A task can't be pulled out of a batch being processed, so a
+// lost lease or a requested cancellation is only noted: stop returns them by task index, for the
+// task to be left to the queue or cancelled once the batch is done.
+func (wp *WorkerPool[T]) startBatchHeartbeat(ctx context.Context, workerID string, tasks []T) func() []error {
+	leases := make([]error, len(tasks))
+	if wp.leaser == nil {
+		return func() []error { return leases }
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(wp.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for i, task := range tasks {
+					if leases[i] != nil {
+						continue
+					}
+					err := wp.leaser.ExtendLease(ctx, task, workerID, wp.leaseDuration)
+					switch {
+					case err == nil:
+					case errors.Is(err, ErrLeaseLost):
+						leases[i] = ErrLeaseLost
+					case errors.Is(err, ErrTaskCancelled):
+						wp.log.InfoContext(ctx, "task cancellation requested",
+							"worker_id", workerID,
+							"task_id", task.GetID())
+						leases[i] = ErrTaskCancelled
+					default:
+						wp.log.ErrorContext(ctx, "failed to extend task lease",
+							"worker_id", workerID,
+							"task_id", task.GetID(),
+							"error", err)
+					}
+				}
+			}
+		}
+	}()
+
+	return func() []error {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+		return leases
+	}
+}
@@ -0,0 +1,169 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// batchRecorder is a batch handler that records the tasks of every batch it processes
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	failing map[string]int // Task id -> attempts left to fail
+}
+
+func (b *batchRecorder) ProcessBatch(ctx context.Context, tasks []TestTask) ([]workers.BatchResult[TestTask], error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]string, len(tasks))
+	results := make([]workers.BatchResult[TestTask], len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+		results[i].Task = task
+		if task.ShouldErr {
+			results[i].Err = workers.Permanent(errors.New("bad task"))
+		} else if b.failing[task.ID] > 0 {
+			b.failing[task.ID]--
+			results[i].Err = errors.New("try again")
+		}
+	}
+	b.batches = append(b.batches, ids)
+	return results, nil
+}
+
+func (b *batchRecorder) Batches() [][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batches
+}
+
+func newBatchPool(t *testing.T, queue workers.Queue[TestTask], handler *batchRecorder, opts ...workers.Option) *workers.WorkerPool[TestTask] {
+	t.Helper()
+	opts = append([]workers.Option{
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10 * time.Millisecond),
+		workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+	}, opts...)
+	pool, err := workers.NewWorkerPool("batch-pool", 1,
+		workers.NewQueueProcessor[TestTask](queue, workers.BatchHandlerFunc[TestTask](handler.ProcessBatch)), opts...)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	return pool
+}
+
+func runPool(pool *workers.WorkerPool[TestTask], d time.Duration) {
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	time.Sleep(d)
+	pool.Stop()
+	<-done
+}
+
+func TestBatch_CompletesAndFailsTasksIndividually(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	for i := 0; i < 7; i++ {
+		enqueue(t, queue, TestTask{ID: fmt.Sprintf("task-%d", i), ShouldErr: i == 4})
+	}
+	handler := &batchRecorder{}
+
+	metrics := workers.NewInMemoryMetrics()
+	pool := newBatchPool(t, queue, handler, workers.WithBatchSize(3), workers.WithMetrics(metrics))
+	runPool(pool, 150*time.Millisecond)
+
+	batches := handler.Batches()
+	if len(batches) != 3 || len(batches[0]) != 3 || len(batches[2]) != 1 {
+		t.Errorf("expected batches of 3, 3 and 1 tasks, got %v", batches)
+	}
+	if dead := queue.Dead(); queue.Stats().Completed != 6 || len(dead) != 1 || dead[0].Task.ID != "task-4" {
+		t.Errorf("expected 6 completed and task-4 failed, got %+v with %d dead", queue.Stats(), len(dead))
+	}
+	if snapshot := metrics.GetSnapshot(); snapshot.TasksCheckedOut != 7 || snapshot.TasksCompleted != 6 || snapshot.TasksFailed != 1 {
+		t.Errorf("expected 7 checked out, 6 completed and 1 failed, got %d, %d and %d",
+			snapshot.TasksCheckedOut, snapshot.TasksCompleted, snapshot.TasksFailed)
+	}
+}
+
+func TestBatch_RetriesOnlyFailedTasks(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	enqueue(t, queue, TestTask{ID: "a"}, TestTask{ID: "b"}, TestTask{ID: "c"})
+	handler := &batchRecorder{failing: map[string]int{"b": 1}}
+
+	pool := newBatchPool(t, queue, handler, workers.WithMaxRetries(2))
+	runPool(pool, 100*time.Millisecond)
+
+	batches := handler.Batches()
+	if len(batches) != 2 || len(batches[1]) != 1 || batches[1][0] != "b" {
+		t.Errorf("expected the retry to run b alone, got %v", batches)
+	}
+	if stats := queue.Stats(); stats.Completed != 3 || stats.Dead != 0 || stats.Pending != 0 {
+		t.Errorf("expected every task to complete, got %+v", stats)
+	}
+}
+
+func TestBatch_MaxWaitFillsTheBatch(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	enqueue(t, queue, TestTask{ID: "early"})
+	handler := &batchRecorder{}
+
+	pool := newBatchPool(t, queue, handler, workers.WithBatchSize(3), workers.WithBatchMaxWait(time.Second))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		queue.Enqueue(TestTask{ID: "late-1"})
+		queue.Enqueue(TestTask{ID: "late-2"})
+	}()
+	runPool(pool, 200*time.Millisecond)
+
+	batches := handler.Batches()
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Errorf("expected a single batch topped up to 3 tasks, got %v", batches)
+	}
+}
+
+func TestBatch_LeasesForThePoolsLeaseDuration(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	queue := memqueue.New[TestTask](memqueue.WithClock(func() time.Time { return now }))
+	queue.Enqueue(TestTask{ID: "a"})
+	queue.Enqueue(TestTask{ID: "b"})
+
+	var mu sync.Mutex
+	leases := map[string]time.Time{}
+	handler := workers.BatchHandlerFunc[TestTask](func(ctx context.Context, tasks []TestTask) ([]workers.BatchResult[TestTask], error) {
+		mu.Lock()
+		defer mu.Unlock()
+		results := make([]workers.BatchResult[TestTask], len(tasks))
+		for i, task := range tasks {
+			entry, _ := queue.Get(task.ID)
+			leases[task.ID] = entry.LeaseExpiresAt
+			results[i].Task = task
+		}
+		return results, nil
+	})
+
+	pool, err := workers.NewWorkerPool("batch-pool", 1, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithLeaseDuration(2*time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	runPool(pool, 100*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(leases) != 2 || !leases["a"].Equal(now.Add(2*time.Minute)) || !leases["b"].Equal(now.Add(2*time.Minute)) {
+		t.Errorf("expected both tasks leased for the pool's 2m, got %v", leases)
+	}
+}
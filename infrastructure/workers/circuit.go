@@ -0,0 +1,152 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of checking out while a CircuitBreaker is open. It wraps
+// ErrNoWorkAvailable so workers fall back to idle polling until the breaker lets work through.
+var ErrCircuitOpen = fmt.Errorf("circuit open: %w", ErrNoWorkAvailable)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Work runs normally
+	CircuitOpen     CircuitState = "open"      // Checkouts are paused until the cooldown is over
+	CircuitHalfOpen CircuitState = "half_open" // A single probe runs to see if the dependency is back
+)
+
+// CircuitBreaker is a middleware that pauses checkouts once work has failed failures times in a
+// row, e.g. while a downstream dependency is down, instead of having every worker burn through
+// its retries. After cooldown it lets one worker probe: a success closes the breaker, a failure
+// opens it for another cooldown. Unlike ConsecutiveErrorShutdown no worker is stopped, the pool
+// recovers by itself once the dependency is back.
+//
+// Only retryable failures count, a permanent error says nothing about the dependency. State
+// changes are logged with the pool logger and recorded in the pool metrics. The breaker is
+// shared by every pool the middleware is added to.
+func CircuitBreaker(failures int, cooldown time.Duration) Middleware {
+	breaker := &circuitBreaker{
+		failures: max(failures, 1),
+		cooldown: cooldown,
+		state:    CircuitClosed,
+	}
+
+	return func(next WorkFunc) WorkFunc {
+		return func(ctx context.Context, workerID string) error {
+			allowed, probe := breaker.allow(ctx)
+			if !allowed {
+				return ErrCircuitOpen
+			}
+			err := next(ctx, workerID)
+			breaker.record(ctx, err, probe)
+			return err
+		}
+	}
+}
+
+// circuitBreaker is the state shared by the work funcs of a CircuitBreaker
+type circuitBreaker struct {
+	failures int
+	cooldown time.Duration
+
+	mu          sync.Mutex
+	state       CircuitState
+	consecutive int       // Failures in a row while closed
+	openedAt    time.Time // When the breaker last opened
+	probing     bool      // A probe is running while half-open
+}
+
+// allow reports whether work may run, and whether it is the probe of a half-open breaker
+func (b *circuitBreaker) allow(ctx context.Context) (allowed bool, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false, false
+		}
+		b.transition(ctx, CircuitHalfOpen)
+	case CircuitHalfOpen:
+		if b.probing {
+			return false, false
+		}
+	default:
+		return true, false
+	}
+
+	b.probing = true
+	return true, true
+}
+
+// record updates the breaker with the outcome of work
+func (b *circuitBreaker) record(ctx context.Context, err error, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil && IsRetryable(err) &&
+		!errors.Is(err, ErrNoWorkAvailable) &&
+		!errors.Is(err, ErrWorkerShutdown) &&
+		!errors.Is(err, ErrPoolShutdown)
+
+	if probe {
+		b.probing = false
+		switch {
+		case err == nil:
+			b.consecutive = 0
+			b.transition(ctx, CircuitClosed)
+		case failed:
+			b.openedAt = time.Now()
+			b.transition(ctx, CircuitOpen)
+		}
+		// Anything else, e.g. no work to probe with, leaves the breaker half-open for the next probe
+		return
+	}
+
+	// Work let through before the breaker opened says nothing about the probe
+	if b.state != CircuitClosed {
+		return
+	}
+	switch {
+	case err == nil:
+		b.consecutive = 0
+	case failed:
+		b.consecutive++
+		if b.consecutive >= b.failures {
+			b.consecutive = 0
+			b.openedAt = time.Now()
+			b.transition(ctx, CircuitOpen)
+		}
+	}
+}
+
+// transition moves the breaker to state and reports the change to the pool running the work.
+// Callers hold b.mu.
+func (b *circuitBreaker) transition(ctx context.Context, state CircuitState) {
+	from := b.state
+	b.state = state
+
+	pool, ok := ctx.Value(poolKey{}).(poolValues)
+	if !ok {
+		return
+	}
+	pool.metrics.RecordCircuitState(state)
+	switch state {
+	case CircuitOpen:
+		pool.log.WarnContext(ctx, "circuit breaker opened, pausing checkouts",
+			"pool", pool.name,
+			"from", from,
+			"cooldown", b.cooldown)
+	default:
+		pool.log.InfoContext(ctx, "circuit breaker state changed",
+			"pool", pool.name,
+			"from", from,
+			"to", state)
+	}
+}
@@ -0,0 +1,72 @@
+package workers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"every minute", "* * * * *", utc(2025, 1, 1, 10, 0, 30), utc(2025, 1, 1, 10, 1, 0)},
+		{"strictly after", "30 10 * * *", utc(2025, 1, 1, 10, 30, 0), utc(2025, 1, 2, 10, 30, 0)},
+		{"step", "*/15 * * * *", utc(2025, 1, 1, 10, 16, 0), utc(2025, 1, 1, 10, 30, 0)},
+		{"offset step", "5/20 * * * *", utc(2025, 1, 1, 10, 30, 0), utc(2025, 1, 1, 10, 45, 0)},
+		{"range and list", "0 9-17/4 * * mon,WED", utc(2025, 1, 6, 17, 0, 0), utc(2025, 1, 8, 9, 0, 0)},
+		{"sunday as 7", "0 0 * * 7", utc(2025, 1, 1, 0, 0, 0), utc(2025, 1, 5, 0, 0, 0)},
+		{"day of month or week", "0 0 13 * fri", utc(2025, 6, 1, 0, 0, 0), utc(2025, 6, 6, 0, 0, 0)},
+		{"month names", "0 0 1 jan,jul *", utc(2025, 2, 1, 0, 0, 0), utc(2025, 7, 1, 0, 0, 0)},
+		{"leap day", "0 0 29 2 *", utc(2025, 1, 1, 0, 0, 0), utc(2028, 2, 29, 0, 0, 0)},
+		{"descriptor", "@daily", utc(2025, 12, 31, 23, 59, 0), utc(2026, 1, 1, 0, 0, 0)},
+		{"timezone prefix", "CRON_TZ=America/New_York 0 9 * * *", utc(2025, 1, 1, 0, 0, 0), time.Date(2025, 1, 1, 9, 0, 0, 0, newYork)},
+		{"across DST", "CRON_TZ=America/New_York 30 2 * * *", utc(2025, 3, 8, 12, 0, 0), time.Date(2025, 3, 10, 2, 30, 0, 0, newYork)},
+		{"never", "0 0 30 2 *", utc(2025, 1, 1, 0, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := workers.ParseCron(tt.spec, nil)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"@fortnightly",
+		"CRON_TZ=Nowhere/Special * * * * *",
+	} {
+		if _, err := workers.ParseCron(spec, nil); err == nil {
+			t.Errorf("ParseCron(%q) should fail", spec)
+		}
+	}
+}
+
+func utc(year int, month time.Month, day, hour, minute, second int) time.Time {
+	return time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+}
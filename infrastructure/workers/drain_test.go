@@ -0,0 +1,123 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// startDrainPool starts a pool over queue whose handler signals started and then runs for delay,
+// or until its context is cancelled
+func startDrainPool(t *testing.T, queue workers.Queue[TestTask], delay time.Duration, drainTimeout time.Duration) (*workers.WorkerPool[TestTask], <-chan struct{}, <-chan error) {
+	t.Helper()
+
+	started := make(chan struct{}, 10)
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		started <- struct{}{}
+		select {
+		case <-time.After(delay):
+			return task, nil
+		case <-ctx.Done():
+			return task, ctx.Err()
+		}
+	})
+
+	pool, err := workers.NewWorkerPool("drain-pool", 2, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(1),
+		workers.WithDrainTimeout(drainTimeout),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	return pool, started, done
+}
+
+func TestWorkerPool_StopDrainsInFlightTasks(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	enqueue(t, queue, TestTask{ID: "a"}, TestTask{ID: "b"}, TestTask{ID: "c"}, TestTask{ID: "d"})
+	pool, started, done := startDrainPool(t, queue, 200*time.Millisecond, 5*time.Second)
+
+	// Both workers are busy, two tasks are still queued
+	<-started
+	<-started
+
+	report := pool.Stop()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error from Start: %v", err)
+	}
+
+	if report.Drained != 2 || report.Abandoned != 0 {
+		t.Errorf("expected 2 drained and 0 abandoned, got %+v", report)
+	}
+
+	stats := queue.Stats()
+	if stats.Completed != 2 {
+		t.Errorf("expected the 2 in-flight tasks to complete, got %+v", stats)
+	}
+	if stats.Pending != 2 {
+		t.Errorf("expected no new checkouts while draining, %d tasks left", stats.Pending)
+	}
+}
+
+func TestWorkerPool_StopReleasesTasksAtDrainDeadline(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	enqueue(t, queue, TestTask{ID: "a"}, TestTask{ID: "b"})
+	pool, started, done := startDrainPool(t, queue, time.Minute, 100*time.Millisecond)
+
+	<-started
+	<-started
+
+	begin := time.Now()
+	report := pool.Stop()
+	<-done
+
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Stop took %v, expected it to give up at the drain deadline", elapsed)
+	}
+	if report.Drained != 0 || report.Abandoned != 2 {
+		t.Errorf("expected 0 drained and 2 abandoned, got %+v", report)
+	}
+
+	if stats := queue.Stats(); stats.Pending != 2 || stats.Processing != 0 || stats.Dead != 0 {
+		t.Errorf("expected both tasks to be released, got %+v", stats)
+	}
+	for _, id := range []string{"a", "b"} {
+		if entry, _ := queue.Get(id); entry.RetryCount != 0 || entry.LastError != "" {
+			t.Errorf("expected released task %s not to be failed, got %d failures: %q", id, entry.RetryCount, entry.LastError)
+		}
+	}
+}
+
+func TestWorkerPool_StopFailsTasksAtDrainDeadlineWithoutReleaser(t *testing.T) {
+	queue := &sliceQueue{pending: []TestTask{{ID: "a"}}}
+	pool, started, done := startDrainPool(t, queue, time.Minute, 0)
+
+	<-started
+
+	report := pool.Stop()
+	<-done
+
+	if report.Abandoned != 1 {
+		t.Errorf("expected 1 abandoned task, got %+v", report)
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.failErrs) != 1 || !errors.Is(queue.failErrs[0], workers.ErrDrainTimeout) {
+		t.Errorf("expected the task to fail with ErrDrainTimeout, got %v", queue.failErrs)
+	}
+}
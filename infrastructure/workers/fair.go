@@ -0,0 +1,98 @@
+package workers
+
+import (
+	"sort"
+	"sync"
+)
+
+// FairScheduler shares work between partitions of a queue (task types, tenants, ...) in
+// proportion to their weights, so a flood in one partition can't starve the others. It is
+// weighted fair queuing by stride scheduling: every partition has a pass that advances by
+// 1/weight each time it is served, and the partition with the lowest pass goes next. A
+// partition that was idle rejoins at the lowest pass of the busy ones, it doesn't get to
+// claim the turns it missed.
+//
+// Queues ask Order which partitions to take work from, then report the one they served.
+// A FairScheduler is safe for concurrent use.
+type FairScheduler struct {
+	mu      sync.Mutex
+	weights map[string]int
+	pass    map[string]float64
+	floor   float64 // Lowest pass among the busy partitions when last ordered
+}
+
+// NewFairScheduler creates a scheduler with the given weights per partition. Partitions
+// without a weight (or with a weight below 1) get a weight of 1.
+func NewFairScheduler(weights map[string]int) *FairScheduler {
+	copied := make(map[string]int, len(weights))
+	for partition, weight := range weights {
+		copied[partition] = weight
+	}
+	return &FairScheduler{
+		weights: copied,
+		pass:    make(map[string]float64),
+	}
+}
+
+// Weight returns the weight of a partition
+func (f *FairScheduler) Weight(partition string) int {
+	if weight := f.weights[partition]; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// Order returns the partitions that have work, in the order they should be served. Passes of
+// partitions that are not in the list and have fallen behind are forgotten.
+func (f *FairScheduler) Order(partitions []string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ordered := make([]string, 0, len(partitions))
+	ready := make(map[string]bool, len(partitions))
+	floor, known := 0.0, false
+	for _, partition := range partitions {
+		if ready[partition] {
+			continue
+		}
+		ready[partition] = true
+		ordered = append(ordered, partition)
+		if pass, ok := f.pass[partition]; ok && (!known || pass < floor) {
+			floor, known = pass, true
+		}
+	}
+	if known && floor > f.floor {
+		f.floor = floor
+	}
+
+	// Idle partitions rejoin at the floor. Forget the ones that won't be ahead of it either way.
+	for partition, pass := range f.pass {
+		if !ready[partition] && pass <= f.floor {
+			delete(f.pass, partition)
+		}
+	}
+	for _, partition := range ordered {
+		if pass, ok := f.pass[partition]; !ok || pass < f.floor {
+			f.pass[partition] = f.floor
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if f.pass[a] != f.pass[b] {
+			return f.pass[a] < f.pass[b]
+		}
+		if f.Weight(a) != f.Weight(b) {
+			return f.Weight(a) > f.Weight(b)
+		}
+		return a < b
+	})
+	return ordered
+}
+
+// Served records that a unit of work was taken from partition
+func (f *FairScheduler) Served(partition string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pass[partition] += 1 / float64(f.Weight(partition))
+}
@@ -0,0 +1,53 @@
+package workers_test
+
+import (
+	"testing"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+// serve takes n units of work from the partitions, always from the first one in order
+func serve(f *workers.FairScheduler, partitions []string, n int, served map[string]int) {
+	for range n {
+		next := f.Order(partitions)[0]
+		f.Served(next)
+		served[next]++
+	}
+}
+
+func TestFairScheduler_SharesByWeight(t *testing.T) {
+	f := workers.NewFairScheduler(map[string]int{"email": 3})
+
+	served := make(map[string]int)
+	serve(f, []string{"email", "report"}, 400, served)
+
+	if served["email"] != 300 || served["report"] != 100 {
+		t.Errorf("expected a 3:1 split, got %v", served)
+	}
+}
+
+func TestFairScheduler_FloodDoesNotStarve(t *testing.T) {
+	f := workers.NewFairScheduler(nil)
+
+	// A flood of one partition has been served for a while
+	served := make(map[string]int)
+	serve(f, []string{"bulk"}, 1000, served)
+
+	// A newcomer gets every other turn, not 1000 turns in a row to catch up
+	served = make(map[string]int)
+	serve(f, []string{"bulk", "tenant-a"}, 10, served)
+	if served["bulk"] != 5 || served["tenant-a"] != 5 {
+		t.Errorf("expected an even split after rejoining, got %v", served)
+	}
+
+	// Duplicates are dropped, a newcomer is served within one round
+	partitions := []string{"tenant-a", "bulk", "tenant-a", "tenant-b"}
+	if order := f.Order(partitions); len(order) != 3 {
+		t.Fatalf("expected 3 partitions, got %v", order)
+	}
+	served = make(map[string]int)
+	serve(f, partitions, 3, served)
+	if served["bulk"] != 1 || served["tenant-a"] != 1 || served["tenant-b"] != 1 {
+		t.Errorf("expected one turn each, got %v", served)
+	}
+}
@@ -0,0 +1,197 @@
+package workers
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// histogramBuckets is the number of finite buckets of a Histogram. Bounds grow by 2^(1/4) from
+// 100µs, which reaches about 28 minutes and keeps estimated percentiles within ~20% of the
+// true value.
+const histogramBuckets = 97
+
+// histogramBounds are the upper bounds of the buckets of every Histogram
+var histogramBounds = func() [histogramBuckets]time.Duration {
+	var bounds [histogramBuckets]time.Duration
+	for i := range bounds {
+		bounds[i] = time.Duration(float64(100*time.Microsecond) * math.Pow(2, float64(i)/4))
+	}
+	return bounds
+}()
+
+// Histogram counts durations in exponential buckets so percentiles can be estimated without
+// keeping every observation. The zero value is ready to use and it is safe for concurrent use.
+type Histogram struct {
+	counts   [histogramBuckets + 1]atomic.Int64 // The last bucket holds everything past the bounds
+	count    atomic.Int64
+	sumNanos atomic.Int64
+}
+
+// Observe adds d to the histogram
+func (h *Histogram) Observe(d time.Duration) {
+	i, j := 0, histogramBuckets
+	for i < j {
+		mid := (i + j) / 2
+		if d <= histogramBounds[mid] {
+			j = mid
+		} else {
+			i = mid + 1
+		}
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sumNanos.Add(int64(d))
+}
+
+// Snapshot returns the current counts
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snapshot := HistogramSnapshot{
+		Count:  h.count.Load(),
+		Sum:    time.Duration(h.sumNanos.Load()),
+		Counts: make([]int64, len(h.counts)),
+	}
+	for i := range h.counts {
+		snapshot.Counts[i] = h.counts[i].Load()
+	}
+	return snapshot
+}
+
+// HistogramSnapshot is a point-in-time copy of a Histogram
+type HistogramSnapshot struct {
+	Count  int64         `json:"count"`
+	Sum    time.Duration `json:"sum_ns"`
+	Counts []int64       `json:"counts"` // Per bucket, see HistogramBounds; the last one is past every bound
+}
+
+// HistogramBounds returns the upper bounds of the buckets of a HistogramSnapshot
+func HistogramBounds() []time.Duration {
+	return histogramBounds[:]
+}
+
+// Merge adds the counts of other to s
+func (s *HistogramSnapshot) Merge(other HistogramSnapshot) {
+	if s.Counts == nil {
+		s.Counts = make([]int64, len(other.Counts))
+	}
+	s.Count += other.Count
+	s.Sum += other.Sum
+	for i := range min(len(s.Counts), len(other.Counts)) {
+		s.Counts[i] += other.Counts[i]
+	}
+}
+
+// Quantile estimates the duration below which a fraction q (0..1) of the observations fall,
+// interpolating within the bucket it lands in. Returns 0 for an empty histogram.
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := q * float64(s.Count)
+	var seen int64
+	for i, n := range s.Counts {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		// Past the last bound there is nothing to interpolate towards
+		if i >= histogramBuckets {
+			return histogramBounds[histogramBuckets-1]
+		}
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = histogramBounds[i-1]
+		}
+		within := (rank - float64(seen)) / float64(n)
+		return lower + time.Duration(within*float64(histogramBounds[i]-lower))
+	}
+	return histogramBounds[histogramBuckets-1]
+}
+
+// Percentiles summarizes a latency distribution. It is serialized in milliseconds, fractional
+// since checkout latencies are often below one.
+type Percentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// MarshalJSON encodes the percentiles in milliseconds
+func (p Percentiles) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		P50 float64 `json:"p50_ms"`
+		P90 float64 `json:"p90_ms"`
+		P99 float64 `json:"p99_ms"`
+	}{
+		P50: milliseconds(p.P50),
+		P90: milliseconds(p.P90),
+		P99: milliseconds(p.P99),
+	})
+}
+
+// milliseconds returns d in fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Percentiles estimates the p50, p90 and p99 of s
+func (s HistogramSnapshot) Percentiles() Percentiles {
+	return Percentiles{
+		P50: s.Quantile(0.50),
+		P90: s.Quantile(0.90),
+		P99: s.Quantile(0.99),
+	}
+}
+
+// ================================================================================
+// Sliding window
+// ================================================================================
+
+// rateWindowSeconds is how far back a rateWindow counts
+const rateWindowSeconds = 300
+
+// rateWindow counts events per second over the last five minutes. The zero value is ready to use.
+type rateWindow struct {
+	mu    sync.Mutex
+	slots [rateWindowSeconds]struct {
+		second int64 // Unix second the slot counts, a slot is reused once it falls out of the window
+		count  int64
+	}
+}
+
+// Add counts an event at now
+func (w *rateWindow) Add(now time.Time) {
+	second := now.Unix()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	slot := &w.slots[second%rateWindowSeconds]
+	if slot.second != second {
+		slot.second, slot.count = second, 0
+	}
+	slot.count++
+}
+
+// Rate returns the events per second over the window up to now. A window longer than uptime is
+// cut down to uptime so a pool that just started isn't reported as slow.
+func (w *rateWindow) Rate(now time.Time, window, uptime time.Duration) float64 {
+	window = min(window, uptime, rateWindowSeconds*time.Second)
+	if window <= 0 {
+		return 0
+	}
+
+	second := now.Unix()
+	oldest := second - int64(math.Ceil(window.Seconds())) + 1
+	var total int64
+	w.mu.Lock()
+	for _, slot := range w.slots {
+		if slot.second >= oldest && slot.second <= second {
+			total += slot.count
+		}
+	}
+	w.mu.Unlock()
+
+	return float64(total) / window.Seconds()
+}
@@ -0,0 +1,79 @@
+package workers_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+// within reports whether got is within 20% of want, the precision of the histogram buckets
+func within(got, want time.Duration) bool {
+	return got >= want*8/10 && got <= want*12/10
+}
+
+func TestHistogram_Quantiles(t *testing.T) {
+	var h workers.Histogram
+	for i := 1; i <= 1000; i++ {
+		h.Observe(time.Duration(i) * time.Millisecond)
+	}
+
+	snapshot := h.Snapshot()
+	if snapshot.Count != 1000 {
+		t.Fatalf("expected 1000 observations, got %d", snapshot.Count)
+	}
+	percentiles := snapshot.Percentiles()
+	if !within(percentiles.P50, 500*time.Millisecond) || !within(percentiles.P90, 900*time.Millisecond) || !within(percentiles.P99, 990*time.Millisecond) {
+		t.Errorf("percentiles off: %+v", percentiles)
+	}
+
+	// Past the last bucket the estimate is capped, not lost
+	h.Observe(time.Hour)
+	if got := h.Snapshot().Quantile(1); got < 20*time.Minute {
+		t.Errorf("expected the max to land in the overflow bucket, got %v", got)
+	}
+
+	if got := (workers.HistogramSnapshot{}).Quantile(0.5); got != 0 {
+		t.Errorf("expected 0 for an empty histogram, got %v", got)
+	}
+
+	// Serialized in milliseconds
+	encoded, _ := json.Marshal(workers.Percentiles{P50: 1500 * time.Microsecond, P90: 2 * time.Millisecond, P99: time.Second})
+	if string(encoded) != `{"p50_ms":1.5,"p90_ms":2,"p99_ms":1000}` {
+		t.Errorf("expected percentiles in milliseconds, got %s", encoded)
+	}
+}
+
+func TestInMemoryMetrics_TailLatencyAndWindowedThroughput(t *testing.T) {
+	fast := workers.NewInMemoryMetrics()
+	slow := workers.NewInMemoryMetrics()
+	fast.Start(context.Background(), "fast")
+	slow.Start(context.Background(), "slow")
+
+	for range 95 {
+		fast.RecordTaskCompleted(10 * time.Millisecond)
+		fast.RecordCheckoutLatency(time.Millisecond)
+	}
+	for range 5 {
+		slow.RecordTaskFailed(2 * time.Second)
+	}
+
+	snapshot := fast.GetSnapshot()
+	if !within(snapshot.TaskDuration.P99, 10*time.Millisecond) || !within(snapshot.CheckoutLatency.P50, time.Millisecond) {
+		t.Errorf("unexpected percentiles: task %+v, checkout %+v", snapshot.TaskDuration, snapshot.CheckoutLatency)
+	}
+	if snapshot.Throughput1m <= 0 || snapshot.Throughput1m != snapshot.Throughput5m {
+		t.Errorf("expected both windows cut down to the uptime, got %v and %v", snapshot.Throughput1m, snapshot.Throughput5m)
+	}
+
+	// The average hides the slow tail, the aggregated p99 doesn't
+	total := workers.AggregateSnapshots(snapshot, slow.GetSnapshot())
+	if total.AverageDuration > 200*time.Millisecond {
+		t.Fatalf("expected a low average, got %v", total.AverageDuration)
+	}
+	if !within(total.TaskDuration.P50, 10*time.Millisecond) || !within(total.TaskDuration.P99, 2*time.Second) {
+		t.Errorf("unexpected aggregated percentiles: %+v", total.TaskDuration)
+	}
+}
@@ -0,0 +1,188 @@
+package workers
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TaskTyper is implemented by tasks that name their type. Metrics are broken down by it.
+type TaskTyper interface {
+	GetTaskType() string
+}
+
+// TaskLabels identify the tasks a labelled metric counts
+type TaskLabels struct {
+	TaskType string // Empty when the task doesn't implement TaskTyper
+	WorkerID string
+}
+
+// LabelledMetrics is implemented by metrics that break task outcomes down by task type and
+// worker, next to the pool-wide counters of WorkerPoolMetrics. InMemoryMetrics (and the metrics
+// built on it) implement it; the pool skips the breakdown for metrics that don't.
+type LabelledMetrics interface {
+	RecordLabelledCompleted(labels TaskLabels, duration time.Duration)
+	RecordLabelledFailed(labels TaskLabels, duration time.Duration)
+	RecordLabelledRetry(labels TaskLabels)
+}
+
+// taskLabels returns the labels of task run by workerID
+func taskLabels[T Task](task T, workerID string) TaskLabels {
+	labels := TaskLabels{WorkerID: workerID}
+	if typer, ok := any(task).(TaskTyper); ok {
+		labels.TaskType = typer.GetTaskType()
+	}
+	return labels
+}
+
+// LabelSnapshot is the breakdown of MetricsSnapshot for a task type or a worker
+type LabelSnapshot struct {
+	Completed       int64             `json:"completed"`
+	Failed          int64             `json:"failed"`
+	Retries         int64             `json:"retries"`
+	ErrorRate       float64           `json:"error_rate"`
+	TotalDuration   time.Duration     `json:"-"`
+	AverageDuration time.Duration     `json:"-"`
+	Duration        Percentiles       `json:"duration"`
+	Durations       HistogramSnapshot `json:"-"`
+
+	// The durations above in milliseconds, as they are serialized
+	TotalDurationMs   int64 `json:"total_duration_ms"`
+	AverageDurationMs int64 `json:"average_duration_ms"`
+}
+
+// merge adds other to s and recomputes the derived fields
+func (s *LabelSnapshot) merge(other LabelSnapshot) {
+	s.Completed += other.Completed
+	s.Failed += other.Failed
+	s.Retries += other.Retries
+	s.TotalDuration += other.TotalDuration
+	s.Durations.Merge(other.Durations)
+	s.derive()
+}
+
+// derive computes the rates and averages from the counters
+func (s *LabelSnapshot) derive() {
+	s.ErrorRate, s.AverageDuration = 0, 0
+	if total := s.Completed + s.Failed; total > 0 {
+		s.ErrorRate = float64(s.Failed) / float64(total) * 100
+		s.AverageDuration = s.TotalDuration / time.Duration(total)
+	}
+	s.Duration = s.Durations.Percentiles()
+	s.TotalDurationMs = s.TotalDuration.Milliseconds()
+	s.AverageDurationMs = s.AverageDuration.Milliseconds()
+}
+
+// mergeLabelSnapshots adds the snapshots of src to dst, which is created if nil
+func mergeLabelSnapshots(dst, src map[string]LabelSnapshot) map[string]LabelSnapshot {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]LabelSnapshot, len(src))
+	}
+	for label, snapshot := range src {
+		merged := dst[label]
+		merged.merge(snapshot)
+		dst[label] = merged
+	}
+	return dst
+}
+
+// ================================================================================
+// Counters
+// ================================================================================
+
+// labelCounters counts the outcomes of the tasks of a label
+type labelCounters struct {
+	completed atomic.Int64
+	failed    atomic.Int64
+	retries   atomic.Int64
+	totalNs   atomic.Int64
+	durations Histogram
+}
+
+func (c *labelCounters) snapshot() LabelSnapshot {
+	snapshot := LabelSnapshot{
+		Completed:     c.completed.Load(),
+		Failed:        c.failed.Load(),
+		Retries:       c.retries.Load(),
+		TotalDuration: time.Duration(c.totalNs.Load()),
+		Durations:     c.durations.Snapshot(),
+	}
+	snapshot.derive()
+	return snapshot
+}
+
+// labelSet holds the counters of every label seen so far. The zero value is ready to use.
+type labelSet struct {
+	mu       sync.RWMutex
+	counters map[string]*labelCounters
+}
+
+// get returns the counters of label, creating them on first use
+func (s *labelSet) get(label string) *labelCounters {
+	s.mu.RLock()
+	counters, ok := s.counters[label]
+	s.mu.RUnlock()
+	if ok {
+		return counters
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if counters, ok = s.counters[label]; !ok {
+		if s.counters == nil {
+			s.counters = make(map[string]*labelCounters)
+		}
+		counters = &labelCounters{}
+		s.counters[label] = counters
+	}
+	return counters
+}
+
+// snapshot returns the snapshots of every label, nil when there are none
+func (s *labelSet) snapshot() map[string]LabelSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.counters) == 0 {
+		return nil
+	}
+	snapshots := make(map[string]LabelSnapshot, len(s.counters))
+	for label, counters := range s.counters {
+		snapshots[label] = counters.snapshot()
+	}
+	return snapshots
+}
+
+// each calls f with the counters of the task type (when there is one) and the worker of labels
+func (m *InMemoryMetrics) each(labels TaskLabels, f func(c *labelCounters)) {
+	if labels.TaskType != "" {
+		f(m.byTaskType.get(labels.TaskType))
+	}
+	if labels.WorkerID != "" {
+		f(m.byWorker.get(labels.WorkerID))
+	}
+}
+
+func (m *InMemoryMetrics) RecordLabelledCompleted(labels TaskLabels, duration time.Duration) {
+	m.each(labels, func(c *labelCounters) {
+		c.completed.Add(1)
+		c.totalNs.Add(int64(duration))
+		c.durations.Observe(duration)
+	})
+}
+
+func (m *InMemoryMetrics) RecordLabelledFailed(labels TaskLabels, duration time.Duration) {
+	m.each(labels, func(c *labelCounters) {
+		c.failed.Add(1)
+		c.totalNs.Add(int64(duration))
+		c.durations.Observe(duration)
+	})
+}
+
+func (m *InMemoryMetrics) RecordLabelledRetry(labels TaskLabels) {
+	m.each(labels, func(c *labelCounters) {
+		c.retries.Add(1)
+	})
+}
@@ -0,0 +1,101 @@
+package workers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+func TestMetrics_BreakdownByTaskTypeAndWorker(t *testing.T) {
+	mux := workers.NewTaskMux[muxTask]()
+	workers.Register(mux, "email", func(ctx context.Context, task muxTask, payload struct{}) (muxTask, error) {
+		return task, nil
+	})
+	workers.Register(mux, "report", func(ctx context.Context, task muxTask, payload struct{}) (muxTask, error) {
+		return task, errors.New("report backend down")
+	})
+
+	queue := &muxQueue{failed: make(map[string]error)}
+	for _, id := range []string{"1", "2", "3", "4"} {
+		queue.pending = append(queue.pending, muxTask{ID: "email-" + id, Type: "email"})
+	}
+	queue.pending = append(queue.pending, muxTask{ID: "report-1", Type: "report"}, muxTask{ID: "report-2", Type: "report"})
+	metrics := workers.NewInMemoryMetrics()
+
+	pool, err := workers.NewWorkerPool("labelled-pool", 2, workers.NewQueueProcessor(queue, mux),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(time.Millisecond),
+		workers.WithMaxRetries(2),
+		workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+		workers.WithMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	defer func() {
+		pool.Stop()
+		<-done
+	}()
+
+	finished := func() int64 {
+		snapshot := metrics.GetSnapshot()
+		return snapshot.TasksCompleted + snapshot.TasksFailed
+	}
+	eventually(t, time.Second, func() bool { return finished() == 6 }, "expected 6 finished tasks, got %d", finished())
+
+	snapshot := metrics.GetSnapshot()
+	email, report := snapshot.ByTaskType["email"], snapshot.ByTaskType["report"]
+	if email.Completed != 4 || email.Failed != 0 || email.Retries != 0 {
+		t.Errorf("unexpected email breakdown %+v", email)
+	}
+	if report.Completed != 0 || report.Failed != 2 || report.Retries != 2 || report.ErrorRate != 100 {
+		t.Errorf("unexpected report breakdown %+v", report)
+	}
+
+	var byWorker int64
+	for workerID, labelled := range snapshot.ByWorker {
+		if workerID != "labelled-pool-worker-1" && workerID != "labelled-pool-worker-2" {
+			t.Errorf("unexpected worker %q", workerID)
+		}
+		byWorker += labelled.Completed + labelled.Failed
+	}
+	if byWorker != 6 {
+		t.Errorf("expected the workers to account for 6 tasks, got %d", byWorker)
+	}
+
+	// Aggregating pools merges the breakdowns
+	total := workers.AggregateSnapshots(snapshot, snapshot)
+	if got := total.ByTaskType["report"]; got.Failed != 4 || got.ErrorRate != 100 {
+		t.Errorf("unexpected aggregated report breakdown %+v", got)
+	}
+}
+
+func TestMetrics_BreakdownDurationsInMilliseconds(t *testing.T) {
+	metrics := workers.NewInMemoryMetrics()
+	labelled := metrics.(workers.LabelledMetrics)
+	labels := workers.TaskLabels{TaskType: "email", WorkerID: "worker-1"}
+	labelled.RecordLabelledCompleted(labels, 1500*time.Millisecond)
+	labelled.RecordLabelledCompleted(labels, 500*time.Millisecond)
+
+	encoded, err := json.Marshal(metrics.GetSnapshot().ByTaskType["email"])
+	if err != nil {
+		t.Fatalf("failed to encode the breakdown: %v", err)
+	}
+	for _, want := range []string{`"total_duration_ms":2000`, `"average_duration_ms":1000`} {
+		if !strings.Contains(string(encoded), want) {
+			t.Errorf("expected %s in %s", want, encoded)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// leaseOnlyQueue hides every optional capability of a memqueue but its leases, and records the
+// errors tasks are failed with
+type leaseOnlyQueue struct {
+	workers.Queue[TestTask]
+	workers.Leaser[TestTask]
+
+	mu       sync.Mutex
+	failErrs []error
+}
+
+func (q *leaseOnlyQueue) Fail(ctx context.Context, task TestTask, err error) error {
+	q.mu.Lock()
+	q.failErrs = append(q.failErrs, err)
+	q.mu.Unlock()
+	return q.Queue.Fail(ctx, task, err)
+}
+
+func newLeasingPool(t *testing.T, queue workers.Queue[TestTask], handler workers.HandlerFunc[TestTask]) *workers.WorkerPool[TestTask] {
+	t.Helper()
+	pool, err := workers.NewWorkerPool("lease-pool", 1, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(1),
+		workers.WithLeaseDuration(100*time.Millisecond),
+		workers.WithHeartbeatInterval(20*time.Millisecond),
+		workers.WithReapInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	return pool
+}
+
+func TestLeases_HeartbeatAndReap(t *testing.T) {
+	ctx := context.Background()
+	queue := memqueue.New[TestTask]()
+
+	// A worker that crashed left its task leased
+	enqueue(t, queue, TestTask{ID: "orphaned-task"})
+	if _, err := queue.CheckoutLeased(ctx, "crashed-worker", 10*time.Millisecond); err != nil {
+		t.Fatalf("failed to check out: %v", err)
+	}
+	enqueue(t, queue, TestTask{ID: "slow-task"})
+
+	var lease atomic.Value
+	pool := newLeasingPool(t, queue, func(ctx context.Context, task TestTask) (TestTask, error) {
+		if task.ID == "slow-task" {
+			entry, _ := queue.Get(task.ID)
+			lease.Store(time.Until(entry.LeaseExpiresAt))
+		}
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-time.After(150 * time.Millisecond):
+		}
+		return task, nil
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	if leased, _ := lease.Load().(time.Duration); leased <= 0 || leased > 100*time.Millisecond {
+		t.Errorf("expected the task to be checked out for the pool's 100ms lease, got %v", leased)
+	}
+	// slow-task outlives its lease, it only completes if the heartbeat kept extending it
+	if stats := queue.Stats(); stats.Completed != 2 || stats.Pending != 0 || stats.Processing != 0 {
+		t.Errorf("expected the reaper to reclaim the orphaned task and both to complete, got %+v", stats)
+	}
+}
+
+func TestLeases_LostLeaseCancelsProcessing(t *testing.T) {
+	// The queue's clock is pushed past the lease to have the task reaped under the worker
+	var skew atomic.Int64
+	queue := memqueue.New[TestTask](memqueue.WithMaxRetries(0), memqueue.WithClock(func() time.Time {
+		return time.Now().Add(time.Duration(skew.Load()))
+	}))
+	enqueue(t, queue, TestTask{ID: "stolen-task"})
+
+	var processErr atomic.Value
+	pool := newLeasingPool(t, queue, func(ctx context.Context, task TestTask) (TestTask, error) {
+		select {
+		case <-ctx.Done():
+			processErr.Store(ctx.Err())
+			return task, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		return task, nil
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	state := func() memqueue.State {
+		entry, _ := queue.Get("stolen-task")
+		return entry.State
+	}
+	eventually(t, time.Second, func() bool { return state() == memqueue.StateProcessing }, "task never checked out")
+	eventually(t, time.Second, func() bool {
+		skew.Add(int64(time.Hour))
+		return state() == memqueue.StateDead
+	}, "task never reaped")
+	eventually(t, time.Second, func() bool { return processErr.Load() != nil }, "processing never cancelled")
+
+	pool.Stop()
+	<-done
+
+	if err, _ := processErr.Load().(error); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected processing to be cancelled after the lease was lost, got %v", err)
+	}
+	entry, _ := queue.Get("stolen-task")
+	if stats := queue.Stats(); stats.Completed != 0 || entry.RetryCount != 1 || entry.LastError != "lease expired" {
+		t.Errorf("a task with a lost lease must be left to the queue, got %+v and %+v", stats, entry)
+	}
+}
+
+// waitForCancel is a handler that runs until its context is cancelled and records why
+func waitForCancel(cause *atomic.Value) workers.HandlerFunc[TestTask] {
+	return func(ctx context.Context, task TestTask) (TestTask, error) {
+		select {
+		case <-ctx.Done():
+			cause.Store(context.Cause(ctx))
+			return task, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		return task, nil
+	}
+}
+
+// requestCancel waits for the task to be processing and asks the queue to cancel it
+func requestCancel(t *testing.T, queue *memqueue.Queue[TestTask], taskID string) {
+	t.Helper()
+	eventually(t, time.Second, func() bool {
+		entry, _ := queue.Get(taskID)
+		return entry.State == memqueue.StateProcessing
+	}, "task never checked out")
+	if _, err := queue.RequestCancel(taskID); err != nil {
+		t.Fatalf("failed to request cancel: %v", err)
+	}
+}
+
+func TestLeases_CancelRequestedCancelsProcessing(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	enqueue(t, queue, TestTask{ID: "cancelled-task"})
+
+	var cause atomic.Value
+	metrics := workers.NewInMemoryMetrics()
+	pool, err := workers.NewWorkerPool("lease-pool", 1, workers.NewQueueProcessor[TestTask](queue, waitForCancel(&cause)),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(3),
+		workers.WithHeartbeatInterval(20*time.Millisecond),
+		workers.WithMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	requestCancel(t, queue, "cancelled-task")
+	time.Sleep(100 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	if err, _ := cause.Load().(error); !errors.Is(err, workers.ErrTaskCancelled) {
+		t.Errorf("expected processing to be cancelled with ErrTaskCancelled, got %v", err)
+	}
+	if stats := queue.Stats(); stats.Cancelled != 1 || stats.Dead != 0 || stats.Completed != 0 || stats.Pending != 0 {
+		t.Errorf("expected the task to end cancelled only, got %+v", stats)
+	}
+	if snapshot := metrics.GetSnapshot(); snapshot.TasksCancelled != 1 || snapshot.RetryAttempts != 0 {
+		t.Errorf("expected 1 cancelled task and no retries, got %d and %d", snapshot.TasksCancelled, snapshot.RetryAttempts)
+	}
+}
+
+func TestLeases_CancelRequestedWithoutCanceller(t *testing.T) {
+	mq := memqueue.New[TestTask]()
+	enqueue(t, mq, TestTask{ID: "cancelled-task"})
+	queue := &leaseOnlyQueue{Queue: mq, Leaser: mq}
+
+	var cause atomic.Value
+	pool := newLeasingPool(t, queue, waitForCancel(&cause))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	requestCancel(t, mq, "cancelled-task")
+	time.Sleep(100 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.failErrs) != 1 {
+		t.Fatalf("expected the cancelled task to be failed, got %d failures", len(queue.failErrs))
+	}
+	if err := queue.failErrs[0]; !errors.Is(err, workers.ErrTaskCancelled) || workers.IsRetryable(err) {
+		t.Errorf("expected a permanent ErrTaskCancelled, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter gates work by key, e.g. a rate limit per third-party API or a concurrency limit per
+// customer. RateLimiter and ConcurrencyLimiter keep their state in the process; the postgresdb
+// package has limiters that share it across processes.
+type Limiter interface {
+	// Acquire blocks until work for key may start, or ctx is done. release must be called once
+	// the work has finished.
+	Acquire(ctx context.Context, key string) (release func(), err error)
+}
+
+// RateLimit is a middleware that lets the pool start at most rps tasks per second, with bursts
+// of up to burst tasks. Workers wait for a token before checking out, so idle polls use up
+// tokens too. The wait shows up as throttled time in the pool's metrics.
+func RateLimit(rps float64, burst int) Middleware {
+	return Throttle(NewRateLimiter(rps, burst), "")
+}
+
+// Throttle is a middleware that acquires key from limiter before each checkout and releases it
+// once the task has finished. Pass a limiter shared by several pools (or processes) to have them
+// respect one quota together. The wait shows up as throttled time in the pool's metrics.
+func Throttle(limiter Limiter, key string) Middleware {
+	return func(next WorkFunc) WorkFunc {
+		return func(ctx context.Context, workerID string) error {
+			release, err := acquire(ctx, limiter, key)
+			if err != nil {
+				return err
+			}
+			defer release()
+			return next(ctx, workerID)
+		}
+	}
+}
+
+// LimitHandler wraps handler so tasks only run once limiter lets their key through, e.g. at
+// most 2 tasks per customer at once with a ConcurrencyLimiter and a key read from the payload.
+// The task stays checked out (and its lease extended) while it waits, and the wait counts
+// towards the task's timeout.
+func LimitHandler[T Task](handler Handler[T], limiter Limiter, key func(task T) string) Handler[T] {
+	return HandlerFunc[T](func(ctx context.Context, task T) (T, error) {
+		release, err := acquire(ctx, limiter, key(task))
+		if err != nil {
+			return task, err
+		}
+		defer release()
+		return handler.Process(ctx, task)
+	})
+}
+
+// acquire acquires key from limiter and records the wait as throttled time
+func acquire(ctx context.Context, limiter Limiter, key string) (func(), error) {
+	start := time.Now()
+	release, err := limiter.Acquire(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("acquire limiter %q: %w", key, err)
+	}
+	// Anything under a millisecond is bookkeeping, not throttling
+	if wait := time.Since(start); wait >= time.Millisecond {
+		if pool, ok := ctx.Value(poolKey{}).(poolValues); ok {
+			pool.metrics.RecordThrottled(wait)
+		}
+	}
+	return release, nil
+}
+
+// ================================================================================
+// RateLimiter
+// ================================================================================
+
+// RateLimiter is a token bucket per key: each key gets rate tokens per second, up to burst
+// saved up. Acquire takes a token, waiting for one if the bucket is empty.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket is the state of a key of a RateLimiter
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a token bucket limiter with rps tokens per second and room for burst.
+// A burst below 1 is raised to 1.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:    rps,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Acquire takes a token for key, waiting until one is available. The token is handed back if
+// ctx is done first. The returned release does nothing, tokens are spent once taken.
+func (l *RateLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	wait := l.reserve(key)
+	if wait <= 0 {
+		return func() {}, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return func() {}, nil
+	case <-ctx.Done():
+		l.refund(key)
+		return nil, ctx.Err()
+	}
+}
+
+// reserve takes a token for key, possibly one that hasn't been added yet, and returns how long
+// until it is
+func (l *RateLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	bucket.tokens--
+
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	if l.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(-bucket.tokens / l.rate * float64(time.Second))
+}
+
+// refund hands back a token reserved by a caller that gave up waiting
+func (l *RateLimiter) refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bucket, ok := l.buckets[key]; ok {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+1)
+	}
+}
+
+// ================================================================================
+// ConcurrencyLimiter
+// ================================================================================
+
+// ConcurrencyLimiter allows at most limit holders per key at once
+type ConcurrencyLimiter struct {
+	limit int
+
+	mu   sync.Mutex
+	keys map[string]*keySlots
+}
+
+// keySlots tracks the holders of a key. freed is closed, and replaced, whenever one releases.
+type keySlots struct {
+	held  int
+	freed chan struct{}
+}
+
+// NewConcurrencyLimiter creates a limiter with limit slots per key. A limit below 1 is raised to 1.
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		limit: max(limit, 1),
+		keys:  make(map[string]*keySlots),
+	}
+}
+
+// Acquire takes a slot of key, waiting until one is free
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	for {
+		l.mu.Lock()
+		slots, ok := l.keys[key]
+		if !ok {
+			slots = &keySlots{freed: make(chan struct{})}
+			l.keys[key] = slots
+		}
+		if slots.held < l.limit {
+			slots.held++
+			l.mu.Unlock()
+
+			var once sync.Once
+			return func() { once.Do(func() { l.release(key) }) }, nil
+		}
+		freed := slots.freed
+		l.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release frees a slot of key and wakes its waiters
+func (l *ConcurrencyLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots := l.keys[key]
+	slots.held--
+	close(slots.freed)
+	if slots.held == 0 {
+		delete(l.keys, key)
+		return
+	}
+	slots.freed = make(chan struct{})
+}
@@ -0,0 +1,142 @@
+package workers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+func TestWorkerPool_RateLimit(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	for i := range 6 {
+		enqueue(t, queue, TestTask{ID: fmt.Sprintf("task-%d", i)})
+	}
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		return task, nil
+	})
+	metrics := workers.NewInMemoryMetrics()
+
+	pool, err := workers.NewWorkerPool("rate-limited", 4, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(time.Millisecond),
+		workers.WithIdleInterval(time.Millisecond),
+		workers.WithMiddleware(workers.RateLimit(20, 1)),
+		workers.WithMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	start := time.Now()
+	go pool.Start(context.Background())
+	defer pool.Stop()
+
+	completed := func() int { return queue.Stats().Completed }
+	eventually(t, 2*time.Second, func() bool { return completed() == 6 }, "tasks not completed")
+
+	// One task right away, then one every 50ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the rate limit to spread 6 tasks over about 250ms, took %v", elapsed)
+	}
+	snapshot := metrics.GetSnapshot()
+	if snapshot.TimesThrottled == 0 || snapshot.ThrottledDuration == 0 {
+		t.Errorf("expected throttled time in metrics, got %d waits for %v", snapshot.TimesThrottled, snapshot.ThrottledDuration)
+	}
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("failed to encode the snapshot: %v", err)
+	}
+	if want := fmt.Sprintf(`"throttled_duration_ms":%d,`, snapshot.ThrottledDuration.Milliseconds()); !strings.Contains(string(encoded), want) {
+		t.Errorf("expected %s in %s", want, encoded)
+	}
+}
+
+func TestLimitHandler_ConcurrencyPerKey(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	for i := range 12 {
+		enqueue(t, queue, TestTask{ID: fmt.Sprintf("task-%d", i), Payload: fmt.Sprintf("customer-%d", i%2)})
+	}
+
+	var mu sync.Mutex
+	running := make(map[string]int)
+	peak := make(map[string]int)
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		mu.Lock()
+		running[task.Payload]++
+		peak[task.Payload] = max(peak[task.Payload], running[task.Payload])
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running[task.Payload]--
+		mu.Unlock()
+		return task, nil
+	})
+	limited := workers.LimitHandler(handler, workers.NewConcurrencyLimiter(2), func(task TestTask) string {
+		return task.Payload
+	})
+
+	pool, err := workers.NewWorkerPool("per-customer", 8, workers.NewQueueProcessor[TestTask](queue, limited),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(time.Millisecond),
+		workers.WithIdleInterval(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	go pool.Start(context.Background())
+	defer pool.Stop()
+
+	eventually(t, 2*time.Second, func() bool { return queue.Stats().Completed == 12 }, "tasks not completed")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for customer, n := range peak {
+		if n > 2 {
+			t.Errorf("%s ran %d tasks at once, limit is 2", customer, n)
+		}
+	}
+	if peak["customer-0"] != 2 {
+		t.Errorf("expected customer-0 to use both slots, peak %d", peak["customer-0"])
+	}
+}
+
+func TestRateLimiter_GivesBackTokenOnCancel(t *testing.T) {
+	limiter := workers.NewRateLimiter(1, 1)
+
+	if _, err := limiter.Acquire(context.Background(), "api"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The bucket is empty, a caller that gives up must not push later callers further back
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "api"); err == nil {
+		t.Fatal("expected the wait to be cut short")
+	}
+
+	start := time.Now()
+	if _, err := limiter.Acquire(context.Background(), "api"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 1200*time.Millisecond {
+		t.Errorf("expected the next token within a second, waited %v", elapsed)
+	}
+
+	// Keys have separate buckets
+	start = time.Now()
+	if _, err := limiter.Acquire(context.Background(), "other"); err != nil || time.Since(start) > 10*time.Millisecond {
+		t.Errorf("expected another key to go through right away, err %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package memqueue
+
+import (
+	"context"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+// Outcome is how a task taken from a Channel ended
+type Outcome[T workers.Task] struct {
+	Task           T
+	Err            error // Nil when the task completed
+	ProcessingTime time.Duration
+}
+
+// Channel is a workers.Queue fed by a Go channel: tasks sent on it are checked out in the order
+// they were sent, and how each one ended is sent on outcomes. There are no retries beyond the
+// pool's own, no leases and nothing to reap; use Queue for those.
+type Channel[T workers.Task] struct {
+	tasks    <-chan T
+	outcomes chan<- Outcome[T]
+}
+
+// NewChannel creates a queue that checks tasks out of tasks. Outcomes are sent on outcomes,
+// which blocks the worker until they are received, so buffer it or keep reading; a nil outcomes
+// discards them. Closing tasks stops the flow of work, the pool keeps polling until stopped.
+func NewChannel[T workers.Task](tasks <-chan T, outcomes chan<- Outcome[T]) *Channel[T] {
+	return &Channel[T]{
+		tasks:    tasks,
+		outcomes: outcomes,
+	}
+}
+
+// Checkout takes the next task from the channel without waiting for one
+func (c *Channel[T]) Checkout(ctx context.Context, workerID string) (T, error) {
+	select {
+	case task, ok := <-c.tasks:
+		if ok {
+			return task, nil
+		}
+	default:
+	}
+	var zero T
+	return zero, workers.ErrNoWorkAvailable
+}
+
+// Complete reports the task as completed on the outcomes channel
+func (c *Channel[T]) Complete(ctx context.Context, task T, processingTimeMS int) error {
+	return c.report(ctx, Outcome[T]{Task: task, ProcessingTime: time.Duration(processingTimeMS) * time.Millisecond})
+}
+
+// Fail reports the task as failed on the outcomes channel
+func (c *Channel[T]) Fail(ctx context.Context, task T, err error) error {
+	return c.report(ctx, Outcome[T]{Task: task, Err: err})
+}
+
+// report sends outcome, giving up when ctx is cancelled
+func (c *Channel[T]) report(ctx context.Context, outcome Outcome[T]) error {
+	if c.outcomes == nil {
+		return nil
+	}
+	select {
+	case c.outcomes <- outcome:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,58 @@
+package memqueue_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+func TestMemQueue_Channel(t *testing.T) {
+	tasks := make(chan testTask, 3)
+	outcomes := make(chan memqueue.Outcome[testTask], 3)
+	tasks <- testTask{ID: "task-1"}
+	tasks <- testTask{ID: "task-2", ShouldErr: true}
+	tasks <- testTask{ID: "task-3"}
+	close(tasks)
+
+	pool, err := workers.NewWorkerPool("channel-pool", 1,
+		workers.NewQueueProcessor[testTask](memqueue.NewChannel(tasks, outcomes), workers.HandlerFunc[testTask](func(ctx context.Context, task testTask) (testTask, error) {
+			if task.ShouldErr {
+				return task, errors.New("boom")
+			}
+			return task, nil
+		})),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	time.Sleep(100 * time.Millisecond)
+	pool.Stop()
+	<-done
+	close(outcomes)
+
+	var order []string
+	failed := 0
+	for outcome := range outcomes {
+		order = append(order, outcome.Task.ID)
+		if outcome.Err != nil {
+			failed++
+		}
+	}
+	if len(order) != 3 || order[0] != "task-1" || order[2] != "task-3" || failed != 1 {
+		t.Errorf("expected the 3 tasks in order with 1 failure, got %v with %d failed", order, failed)
+	}
+}
@@ -0,0 +1,606 @@
+// Package memqueue provides in-memory queues for worker pools, so apps can run without a
+// database in development and tests get the semantics of the Postgres queue: priorities,
+// delayed tasks, leases, retry counts, dead letters and cancellation.
+//
+// Queue is the full featured one. Channel feeds a pool from a Go channel when none of that is
+// needed. Nothing is persisted, tasks are lost when the process exits.
+package memqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+var (
+	ErrDuplicateTask      = errors.New("task already queued")
+	ErrTaskNotCancellable = errors.New("task is not pending or processing")
+	ErrTaskNotDead        = errors.New("task is not dead-lettered")
+)
+
+// DefaultLeaseDuration is the lease used by Checkout when the pool doesn't pick one
+const DefaultLeaseDuration = 5 * time.Minute
+
+// State is where a task stands in the queue
+type State string
+
+const (
+	StatePending    State = "pending"
+	StateProcessing State = "processing"
+	StateCompleted  State = "completed"
+	StateDead       State = "dead"
+	StateCancelled  State = "cancelled"
+)
+
+// Entry is a point-in-time view of a task in the queue
+type Entry[T workers.Task] struct {
+	Task            T
+	State           State
+	Priority        int
+	RetryCount      int // Failed runs so far
+	MaxRetries      int // Failed runs that are retried before the task is dead-lettered
+	RunAt           time.Time
+	LockedBy        string
+	LeaseExpiresAt  time.Time
+	CancelRequested bool
+	LastError       string
+	Progress        *workers.ProgressReport // Last progress saved by the pool, nil before any
+	Checkpoint      []byte                  // Last checkpoint saved by the pool
+	EnqueuedAt      time.Time
+}
+
+// Stats counts the tasks in each state. Completed and cancelled tasks are counted, not kept.
+type Stats struct {
+	Pending    int `json:"pending"`
+	Processing int `json:"processing"`
+	Completed  int `json:"completed"`
+	Dead       int `json:"dead"`
+	Cancelled  int `json:"cancelled"`
+}
+
+// ================================================================================
+// Options
+// ================================================================================
+
+// Option configures a Queue
+type Option func(*options)
+
+type options struct {
+	maxRetries int
+	backoff    workers.BackoffPolicy
+	now        func() time.Time
+}
+
+// WithMaxRetries sets how many times a failed task is retried before it is dead-lettered,
+// unless the task was enqueued with its own (default 3)
+func WithMaxRetries(maxRetries int) Option {
+	return func(o *options) {
+		o.maxRetries = maxRetries
+	}
+}
+
+// WithRetryBackoff delays failed tasks before they can be checked out again. By default they are
+// ready right away, as in the Postgres queue.
+func WithRetryBackoff(policy workers.BackoffPolicy) Option {
+	return func(o *options) {
+		o.backoff = policy
+	}
+}
+
+// WithClock replaces time.Now, so tests can move time along for delays and leases
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// EnqueueOption configures a task being enqueued
+type EnqueueOption func(*enqueueOptions)
+
+type enqueueOptions struct {
+	priority   int
+	delay      time.Duration
+	runAt      time.Time
+	maxRetries *int
+}
+
+// WithPriority sets the task's priority, higher runs first
+func WithPriority(priority int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.priority = priority
+	}
+}
+
+// WithDelay holds the task back for delay before it can be checked out
+func WithDelay(delay time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.delay = delay
+	}
+}
+
+// WithRunAt holds the task back until runAt
+func WithRunAt(runAt time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = runAt
+	}
+}
+
+// WithTaskMaxRetries overrides the queue's max retries for the task
+func WithTaskMaxRetries(maxRetries int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxRetries = &maxRetries
+	}
+}
+
+// ================================================================================
+// Queue
+// ================================================================================
+
+// entry is a task and its queue state
+type entry[T workers.Task] struct {
+	seq             uint64 // Enqueue order, breaks priority ties
+	task            T
+	state           State
+	priority        int
+	retryCount      int
+	maxRetries      int
+	runAt           time.Time
+	lockedBy        string
+	leaseExpiresAt  time.Time
+	cancelRequested bool
+	lastError       string
+	progress        *workers.ProgressReport
+	checkpoint      []byte
+	enqueuedAt      time.Time
+}
+
+// Queue is an in-memory task queue. It implements workers.Queue along with workers.Leaser,
+// workers.Releaser, workers.Canceller, workers.BatchQueue and workers.ProgressSaver, so a pool
+// run with workers.NewQueueProcessor gets leases, reaping, drain release, cancellation and saved
+// progress as it would on the tasks table. It also implements workers.Notifier: pass it to workers.WithNotifier and idle
+// workers wake up as soon as a task is enqueued.
+//
+// Tasks are ordered by priority, then enqueue order, and checked out once their run time has
+// passed. Failed runs are retried until the task's max retries are used up, errors that are not
+// retryable (see workers.IsRetryable) dead-letter the task straight away. Queue is a
+// workers.RetryingQueue, so the pool makes a single attempt per checkout unless it is given
+// workers.WithMaxRetries.
+//
+// Tasks are identified by GetID: a task id can't be enqueued again while it is pending or
+// processing. Complete, Fail, Release and Cancel can't tell workers apart, they apply to the
+// task as long as it is processing.
+type Queue[T workers.Task] struct {
+	maxRetries int
+	backoff    workers.BackoffPolicy
+	now        func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry[T]
+	seq       uint64
+	completed int
+	cancelled int
+	listeners map[chan struct{}]struct{}
+}
+
+// New creates an empty queue
+func New[T workers.Task](opts ...Option) *Queue[T] {
+	o := options{
+		maxRetries: 3,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxRetries < 0 {
+		o.maxRetries = 0
+	}
+
+	return &Queue[T]{
+		maxRetries: o.maxRetries,
+		backoff:    o.backoff,
+		now:        o.now,
+		entries:    make(map[string]*entry[T]),
+		listeners:  make(map[chan struct{}]struct{}),
+	}
+}
+
+// Enqueue adds a pending task. Returns ErrDuplicateTask if a task with the same id is pending or
+// processing; a finished one is replaced.
+func (q *Queue[T]) Enqueue(task T, opts ...EnqueueOption) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if e, ok := q.entries[task.GetID()]; ok && (e.state == StatePending || e.state == StateProcessing) {
+		return fmt.Errorf("enqueue task[%v]: %w", task.GetID(), ErrDuplicateTask)
+	}
+
+	var o enqueueOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	q.seq++
+	now := q.now()
+	e := &entry[T]{
+		seq:        q.seq,
+		task:       task,
+		state:      StatePending,
+		priority:   o.priority,
+		maxRetries: q.maxRetries,
+		runAt:      o.runAt,
+		enqueuedAt: now,
+	}
+	if o.delay > 0 {
+		e.runAt = now.Add(o.delay)
+	}
+	if o.maxRetries != nil {
+		e.maxRetries = max(*o.maxRetries, 0)
+	}
+	q.entries[task.GetID()] = e
+	q.notify()
+	return nil
+}
+
+// Checkout claims the next ready task with the default lease
+func (q *Queue[T]) Checkout(ctx context.Context, workerID string) (T, error) {
+	return q.CheckoutLeased(ctx, workerID, DefaultLeaseDuration)
+}
+
+// CheckoutLeased claims the next ready task and leases it to workerID
+func (q *Queue[T]) CheckoutLeased(ctx context.Context, workerID string, lease time.Duration) (T, error) {
+	tasks, err := q.checkout(workerID, lease, 1)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return tasks[0], nil
+}
+
+// CheckoutBatch claims up to size ready tasks and leases them to workerID
+func (q *Queue[T]) CheckoutBatch(ctx context.Context, workerID string, size int, lease time.Duration) ([]T, error) {
+	return q.checkout(workerID, lease, size)
+}
+
+// checkout leases up to n ready tasks to workerID, best first
+func (q *Queue[T]) checkout(workerID string, lease time.Duration, n int) ([]T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	var ready []*entry[T]
+	for _, e := range q.entries {
+		if e.state == StatePending && !e.runAt.After(now) {
+			ready = append(ready, e)
+		}
+	}
+	if len(ready) == 0 {
+		return nil, workers.ErrNoWorkAvailable
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].priority != ready[j].priority {
+			return ready[i].priority > ready[j].priority
+		}
+		return ready[i].seq < ready[j].seq
+	})
+
+	tasks := make([]T, 0, min(n, len(ready)))
+	for _, e := range ready[:min(n, len(ready))] {
+		e.state = StateProcessing
+		e.lockedBy = workerID
+		e.leaseExpiresAt = now.Add(lease)
+		tasks = append(tasks, e.task)
+	}
+	return tasks, nil
+}
+
+// ExtendLease keeps the task leased to workerID. Returns workers.ErrLeaseLost if workerID no
+// longer holds it, and workers.ErrTaskCancelled, with the lease extended, when the task was
+// asked to cancel.
+func (q *Queue[T]) ExtendLease(ctx context.Context, task T, workerID string, lease time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[task.GetID()]
+	if !ok || e.state != StateProcessing || e.lockedBy != workerID {
+		return workers.ErrLeaseLost
+	}
+	e.leaseExpiresAt = q.now().Add(lease)
+	if e.cancelRequested {
+		return workers.ErrTaskCancelled
+	}
+	return nil
+}
+
+// ReapExpired returns processing tasks with a lapsed lease to pending, counting the lost run
+// against their retries, and reports how many were reclaimed. Tasks that were asked to cancel
+// are cancelled instead.
+func (q *Queue[T]) ReapExpired(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	reaped := 0
+	for id, e := range q.entries {
+		if e.state != StateProcessing || !e.leaseExpiresAt.Before(now) {
+			continue
+		}
+		reaped++
+		if e.cancelRequested {
+			q.cancel(id)
+			continue
+		}
+		q.retry(e, "lease expired", true)
+	}
+	return reaped, nil
+}
+
+// Complete marks a processing task as completed
+func (q *Queue[T]) Complete(ctx context.Context, task T, processingTimeMS int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, err := q.processing(task); err != nil {
+		return err
+	}
+	delete(q.entries, task.GetID())
+	q.completed++
+	return nil
+}
+
+// Fail records a failed run. The task goes back to pending while its retries last, and is
+// dead-lettered once they are used up or straight away when err isn't retryable. A task that
+// was asked to cancel is cancelled instead.
+func (q *Queue[T]) Fail(ctx context.Context, task T, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, lookupErr := q.processing(task)
+	if lookupErr != nil {
+		return lookupErr
+	}
+	if e.cancelRequested {
+		q.cancel(task.GetID())
+		return nil
+	}
+	q.retry(e, err.Error(), workers.IsRetryable(err))
+	return nil
+}
+
+// SaveProgress records the progress of a processing task, with its checkpoint when the report
+// carries one; a report without a checkpoint keeps the one saved before. Unlike the tasks table,
+// the checkpoint is only kept for inspection through Get, it isn't handed back to later runs.
+// Queue implements workers.ProgressSaver.
+func (q *Queue[T]) SaveProgress(ctx context.Context, task T, report workers.ProgressReport) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, err := q.processing(task)
+	if err != nil {
+		return err
+	}
+	if report.Checkpoint != nil {
+		e.checkpoint = report.Checkpoint
+		report.Checkpoint = nil
+	}
+	e.progress = &report
+	return nil
+}
+
+// RetriesFailedTasks reports that failed tasks are retried by the queue. Queue implements
+// workers.RetryingQueue.
+func (q *Queue[T]) RetriesFailedTasks() bool {
+	return true
+}
+
+// Release hands a processing task back to pending without counting a failed run. Queue
+// implements workers.Releaser.
+func (q *Queue[T]) Release(ctx context.Context, task T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, err := q.processing(task)
+	if err != nil {
+		return err
+	}
+	if e.cancelRequested {
+		q.cancel(task.GetID())
+		return nil
+	}
+	e.state = StatePending
+	e.lockedBy = ""
+	e.leaseExpiresAt = time.Time{}
+	q.notify()
+	return nil
+}
+
+// Cancel marks a processing task whose cancellation was requested as cancelled. Queue
+// implements workers.Canceller.
+func (q *Queue[T]) Cancel(ctx context.Context, task T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, err := q.processing(task); err != nil {
+		return err
+	}
+	q.cancel(task.GetID())
+	return nil
+}
+
+// RequestCancel asks for a task to be cancelled. A pending task is cancelled right away; a
+// processing one is flagged and cancelled by its worker on the next lease heartbeat. Returns
+// the task's state afterwards, or ErrTaskNotCancellable if it isn't pending or processing.
+func (q *Queue[T]) RequestCancel(taskID string) (State, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[taskID]
+	switch {
+	case ok && e.state == StatePending:
+		q.cancel(taskID)
+		return StateCancelled, nil
+	case ok && e.state == StateProcessing:
+		e.cancelRequested = true
+		return StateProcessing, nil
+	}
+	return "", fmt.Errorf("request cancel task[%v]: %w", taskID, ErrTaskNotCancellable)
+}
+
+// Requeue moves a dead task back to pending with a fresh retry budget
+func (q *Queue[T]) Requeue(taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[taskID]
+	if !ok || e.state != StateDead {
+		return fmt.Errorf("requeue task[%v]: %w", taskID, ErrTaskNotDead)
+	}
+	e.state = StatePending
+	e.retryCount = 0
+	e.runAt = time.Time{}
+	q.notify()
+	return nil
+}
+
+// Get returns the task's entry. Completed and cancelled tasks are no longer kept.
+func (q *Queue[T]) Get(taskID string) (Entry[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[taskID]
+	if !ok {
+		return Entry[T]{}, false
+	}
+	return e.view(), true
+}
+
+// Dead lists the dead-lettered tasks, oldest first
+func (q *Queue[T]) Dead() []Entry[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var dead []*entry[T]
+	for _, e := range q.entries {
+		if e.state == StateDead {
+			dead = append(dead, e)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool { return dead[i].seq < dead[j].seq })
+
+	entries := make([]Entry[T], len(dead))
+	for i, e := range dead {
+		entries[i] = e.view()
+	}
+	return entries
+}
+
+// Stats counts the tasks in each state
+func (q *Queue[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := Stats{Completed: q.completed, Cancelled: q.cancelled}
+	for _, e := range q.entries {
+		switch e.state {
+		case StatePending:
+			stats.Pending++
+		case StateProcessing:
+			stats.Processing++
+		case StateDead:
+			stats.Dead++
+		}
+	}
+	return stats
+}
+
+// Listen calls notify whenever a task becomes ready through Enqueue, Release, a retry or Requeue,
+// until ctx is cancelled. Delayed tasks are picked up by polling. Queue implements
+// workers.Notifier.
+func (q *Queue[T]) Listen(ctx context.Context, notify func()) error {
+	wake := make(chan struct{}, 1)
+	q.mu.Lock()
+	q.listeners[wake] = struct{}{}
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.listeners, wake)
+		q.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+			notify()
+		}
+	}
+}
+
+// notify wakes every listener without blocking, the caller must hold mu
+func (q *Queue[T]) notify() {
+	for wake := range q.listeners {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// processing returns the entry of a processing task, the caller must hold mu
+func (q *Queue[T]) processing(task T) (*entry[T], error) {
+	e, ok := q.entries[task.GetID()]
+	if !ok || e.state != StateProcessing {
+		return nil, workers.ErrLeaseLost
+	}
+	return e, nil
+}
+
+// retry counts a failed run and puts the task back to pending, or dead-letters it when its
+// retries are used up or the failure isn't retryable. The caller must hold mu.
+func (q *Queue[T]) retry(e *entry[T], lastError string, retryable bool) {
+	e.retryCount++
+	e.lastError = lastError
+	e.lockedBy = ""
+	e.leaseExpiresAt = time.Time{}
+
+	if !retryable || e.retryCount > e.maxRetries {
+		e.state = StateDead
+		return
+	}
+	e.state = StatePending
+	if q.backoff != nil {
+		e.runAt = q.now().Add(q.backoff.Delay(e.retryCount))
+		return
+	}
+	q.notify()
+}
+
+// cancel drops a task as cancelled, the caller must hold mu
+func (q *Queue[T]) cancel(taskID string) {
+	delete(q.entries, taskID)
+	q.cancelled++
+}
+
+// view copies the entry for callers
+func (e *entry[T]) view() Entry[T] {
+	return Entry[T]{
+		Task:            e.task,
+		State:           e.state,
+		Priority:        e.priority,
+		RetryCount:      e.retryCount,
+		MaxRetries:      e.maxRetries,
+		RunAt:           e.runAt,
+		LockedBy:        e.lockedBy,
+		LeaseExpiresAt:  e.leaseExpiresAt,
+		CancelRequested: e.cancelRequested,
+		LastError:       e.lastError,
+		Progress:        e.progress,
+		Checkpoint:      e.checkpoint,
+		EnqueuedAt:      e.enqueuedAt,
+	}
+}
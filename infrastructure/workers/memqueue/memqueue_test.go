@@ -0,0 +1,210 @@
+package memqueue_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// testTask is the task type the tests queue
+type testTask struct {
+	ID        string
+	ShouldErr bool
+}
+
+func (t testTask) GetID() string {
+	return t.ID
+}
+
+// testClock is a clock the test moves along by hand
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestMemQueue_PriorityDelayAndLeases(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	queue := memqueue.New[testTask](memqueue.WithClock(clock.Now))
+
+	queue.Enqueue(testTask{ID: "low"})
+	queue.Enqueue(testTask{ID: "high"}, memqueue.WithPriority(10))
+	queue.Enqueue(testTask{ID: "later"}, memqueue.WithPriority(20), memqueue.WithDelay(time.Minute))
+	if err := queue.Enqueue(testTask{ID: "low"}); !errors.Is(err, memqueue.ErrDuplicateTask) {
+		t.Errorf("expected a queued task id to be refused, got %v", err)
+	}
+
+	var order []string
+	for {
+		task, err := queue.CheckoutLeased(ctx, "worker-1", 30*time.Second)
+		if errors.Is(err, workers.ErrNoWorkAvailable) {
+			break
+		}
+		order = append(order, task.ID)
+	}
+	if len(order) != 2 || order[0] != "high" || order[1] != "low" {
+		t.Errorf("expected high then low with later held back, got %v", order)
+	}
+
+	// The lease of low lapses, the reaper counts the lost run and puts it back
+	queue.ExtendLease(ctx, testTask{ID: "high"}, "worker-1", 2*time.Minute)
+	clock.Advance(time.Minute)
+	if reaped, _ := queue.ReapExpired(ctx); reaped != 1 {
+		t.Errorf("expected 1 reaped task, got %d", reaped)
+	}
+	if entry, _ := queue.Get("low"); entry.State != memqueue.StatePending || entry.RetryCount != 1 {
+		t.Errorf("expected low pending after 1 lost run, got %s after %d", entry.State, entry.RetryCount)
+	}
+	if err := queue.Complete(ctx, testTask{ID: "low"}, 0); !errors.Is(err, workers.ErrLeaseLost) {
+		t.Errorf("expected completing a reaped task to report a lost lease, got %v", err)
+	}
+
+	// The delay has passed, later outranks low
+	if task, _ := queue.Checkout(ctx, "worker-2"); task.ID != "later" {
+		t.Errorf("expected later once its delay passed, got %q", task.ID)
+	}
+}
+
+func TestMemQueue_RetriesThenDeadLetters(t *testing.T) {
+	queue := memqueue.New[testTask](memqueue.WithMaxRetries(2))
+	queue.Enqueue(testTask{ID: "flaky", ShouldErr: true})
+	queue.Enqueue(testTask{ID: "broken"})
+	queue.Enqueue(testTask{ID: "fine"})
+
+	var mu sync.Mutex
+	runs := map[string]int{}
+	handler := workers.HandlerFunc[testTask](func(ctx context.Context, task testTask) (testTask, error) {
+		mu.Lock()
+		runs[task.ID]++
+		mu.Unlock()
+		switch {
+		case task.ShouldErr:
+			return task, errors.New("flaked")
+		case task.ID == "broken":
+			return task, workers.Permanent(errors.New("bad input"))
+		}
+		return task, nil
+	})
+
+	pool, err := workers.NewWorkerPool("memqueue-pool", 2, workers.NewQueueProcessor[testTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(1),
+		workers.WithNotifier(queue),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	time.Sleep(200 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	if runs["flaky"] != 3 || runs["broken"] != 1 || runs["fine"] != 1 {
+		t.Errorf("expected flaky to run 3 times and the others once, got %v", runs)
+	}
+	if stats := queue.Stats(); stats.Completed != 1 || stats.Dead != 2 || stats.Pending != 0 {
+		t.Errorf("expected 1 completed and 2 dead, got %+v", stats)
+	}
+	dead := queue.Dead()
+	if len(dead) != 2 || dead[0].Task.ID != "flaky" || dead[0].RetryCount != 3 || dead[1].RetryCount != 1 {
+		t.Errorf("expected flaky dead after 3 runs and broken after 1, got %+v", dead)
+	}
+
+	if err := queue.Requeue("broken"); err != nil {
+		t.Errorf("expected a dead task to be requeued, got %v", err)
+	}
+	if entry, _ := queue.Get("broken"); entry.State != memqueue.StatePending || entry.RetryCount != 0 {
+		t.Errorf("expected broken pending with a fresh retry budget, got %s after %d", entry.State, entry.RetryCount)
+	}
+}
+
+func TestMemQueue_CancelProcessingTask(t *testing.T) {
+	queue := memqueue.New[testTask]()
+	queue.Enqueue(testTask{ID: "long"})
+	queue.Enqueue(testTask{ID: "waiting"}, memqueue.WithDelay(time.Hour))
+
+	if state, err := queue.RequestCancel("waiting"); err != nil || state != memqueue.StateCancelled {
+		t.Errorf("expected a pending task to be cancelled right away, got %s, %v", state, err)
+	}
+
+	var cause sync.Map
+	pool, err := workers.NewWorkerPool("memqueue-pool", 1, workers.NewQueueProcessor[testTask](queue,
+		workers.HandlerFunc[testTask](func(ctx context.Context, task testTask) (testTask, error) {
+			<-ctx.Done()
+			cause.Store(task.ID, context.Cause(ctx))
+			return task, ctx.Err()
+		})),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithHeartbeatInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if state, err := queue.RequestCancel("long"); err != nil || state != memqueue.StateProcessing {
+		t.Errorf("expected a processing task to be flagged, got %s, %v", state, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	stored, _ := cause.Load("long")
+	if err, _ := stored.(error); !errors.Is(err, workers.ErrTaskCancelled) {
+		t.Errorf("expected processing to be cancelled with ErrTaskCancelled, got %v", err)
+	}
+	if stats := queue.Stats(); stats.Cancelled != 2 || stats.Processing != 0 {
+		t.Errorf("expected both tasks cancelled, got %+v", stats)
+	}
+}
+
+func TestMemQueue_SaveProgress(t *testing.T) {
+	ctx := context.Background()
+	queue := memqueue.New[testTask]()
+	queue.Enqueue(testTask{ID: "resumable"})
+
+	if err := queue.SaveProgress(ctx, testTask{ID: "resumable"}, workers.ProgressReport{Percent: 10}); !errors.Is(err, workers.ErrLeaseLost) {
+		t.Errorf("expected saving progress of a pending task to report a lost lease, got %v", err)
+	}
+
+	task, _ := queue.Checkout(ctx, "worker-1")
+	queue.SaveProgress(ctx, task, workers.ProgressReport{Percent: 50, Step: "halfway", Checkpoint: []byte("page-5")})
+	queue.SaveProgress(ctx, task, workers.ProgressReport{Percent: 80, Step: "almost"})
+
+	entry, _ := queue.Get("resumable")
+	if entry.Progress == nil || entry.Progress.Percent != 80 || entry.Progress.Step != "almost" {
+		t.Errorf("expected the last report, got %+v", entry.Progress)
+	}
+	if string(entry.Checkpoint) != "page-5" || entry.Progress.Checkpoint != nil {
+		t.Errorf("expected the checkpoint kept apart from the report, got %q", entry.Checkpoint)
+	}
+}
@@ -21,7 +21,14 @@ type WorkerPoolMetrics interface {
 	RecordTaskCheckedOut()
 	RecordTaskCompleted(duration time.Duration)
 	RecordTaskFailed(duration time.Duration)
-	RecordCheckoutError() // No work available or checkout failed
+	RecordCheckoutError()                         // No work available or checkout failed
+	RecordCheckoutLatency(duration time.Duration) // How long a checkout took, whether it found work or not
+	RecordTaskTimeout()                           // A Process attempt ran past its timeout
+	RecordTaskCancelled()                         // A task was cancelled on request, it is recorded as failed too
+
+	// Throttling
+	RecordThrottled(wait time.Duration)    // Time spent waiting on a rate or concurrency limiter
+	RecordCircuitState(state CircuitState) // A CircuitBreaker changed state
 
 	// Retry metrics
 	RecordRetryAttempt()
@@ -50,6 +57,27 @@ type MetricsSnapshot struct {
 	TasksFailed     int64 `json:"tasks_failed"`
 	TasksInProgress int64 `json:"tasks_in_progress"`
 	CheckoutErrors  int64 `json:"checkout_errors"`
+	TasksTimedOut   int64 `json:"tasks_timed_out"` // Attempts, a task that times out on every retry counts once per attempt
+	TasksCancelled  int64 `json:"tasks_cancelled"` // Cancelled on request, included in TasksFailed
+
+	// Latency distributions. The histograms are kept for aggregating and exporting.
+	TaskDuration      Percentiles       `json:"task_duration"`
+	CheckoutLatency   Percentiles       `json:"checkout_latency"`
+	TaskDurations     HistogramSnapshot `json:"-"`
+	CheckoutLatencies HistogramSnapshot `json:"-"`
+
+	// Breakdown by task type (for tasks that implement TaskTyper) and by worker ID
+	ByTaskType map[string]LabelSnapshot `json:"by_task_type,omitempty"`
+	ByWorker   map[string]LabelSnapshot `json:"by_worker,omitempty"`
+
+	// Throttling
+	TimesThrottled      int64         `json:"times_throttled"` // Waits on a limiter that had to block
+	ThrottledDuration   time.Duration `json:"-"`
+	ThrottledDurationMs int64         `json:"throttled_duration_ms"`
+
+	// Circuit breaker
+	CircuitState  CircuitState `json:"circuit_state,omitempty"` // Empty when the pool has no breaker
+	CircuitOpened int64        `json:"circuit_opened"`          // Times the breaker opened
 
 	// Retry info
 	RetryAttempts    int64   `json:"retry_attempts"`
@@ -64,8 +92,10 @@ type MetricsSnapshot struct {
 	MaxDuration     time.Duration `json:"max_duration_ms"`
 
 	// Throughput
-	Throughput float64 `json:"throughput_per_sec"`
-	ErrorRate  float64 `json:"error_rate"`
+	Throughput   float64 `json:"throughput_per_sec"`    // Over the pool's lifetime
+	Throughput1m float64 `json:"throughput_1m_per_sec"` // Over the last minute
+	Throughput5m float64 `json:"throughput_5m_per_sec"` // Over the last five minutes
+	ErrorRate    float64 `json:"error_rate"`
 
 	// Timing
 	CollectedAt    time.Time     `json:"collected_at"`
@@ -82,19 +112,24 @@ func NewNoOpMetrics() WorkerPoolMetrics {
 	return &NoOpMetrics{}
 }
 
-func (n *NoOpMetrics) RecordWorkerStarted()                       {}
-func (n *NoOpMetrics) RecordWorkerStopped()                       {}
-func (n *NoOpMetrics) RecordWorkerPanic()                         {}
-func (n *NoOpMetrics) RecordTaskCheckedOut()                      {}
-func (n *NoOpMetrics) RecordTaskCompleted(duration time.Duration) {}
-func (n *NoOpMetrics) RecordTaskFailed(duration time.Duration)    {}
-func (n *NoOpMetrics) RecordCheckoutError()                       {}
-func (n *NoOpMetrics) RecordRetryAttempt()                        {}
-func (n *NoOpMetrics) RecordRetrySuccess()                        {}
-func (n *NoOpMetrics) RecordRetryExhausted()                      {}
-func (n *NoOpMetrics) GetSnapshot() MetricsSnapshot               { return MetricsSnapshot{} }
-func (n *NoOpMetrics) Start(ctx context.Context, poolName string) {}
-func (n *NoOpMetrics) Stop(ctx context.Context)                   {}
+func (n *NoOpMetrics) RecordWorkerStarted()                         {}
+func (n *NoOpMetrics) RecordWorkerStopped()                         {}
+func (n *NoOpMetrics) RecordWorkerPanic()                           {}
+func (n *NoOpMetrics) RecordTaskCheckedOut()                        {}
+func (n *NoOpMetrics) RecordTaskCompleted(duration time.Duration)   {}
+func (n *NoOpMetrics) RecordTaskFailed(duration time.Duration)      {}
+func (n *NoOpMetrics) RecordCheckoutError()                         {}
+func (n *NoOpMetrics) RecordCheckoutLatency(duration time.Duration) {}
+func (n *NoOpMetrics) RecordTaskTimeout()                           {}
+func (n *NoOpMetrics) RecordTaskCancelled()                         {}
+func (n *NoOpMetrics) RecordThrottled(wait time.Duration)           {}
+func (n *NoOpMetrics) RecordCircuitState(state CircuitState)        {}
+func (n *NoOpMetrics) RecordRetryAttempt()                          {}
+func (n *NoOpMetrics) RecordRetrySuccess()                          {}
+func (n *NoOpMetrics) RecordRetryExhausted()                        {}
+func (n *NoOpMetrics) GetSnapshot() MetricsSnapshot                 { return MetricsSnapshot{} }
+func (n *NoOpMetrics) Start(ctx context.Context, poolName string)   {}
+func (n *NoOpMetrics) Stop(ctx context.Context)                     {}
 
 // ================================================================================
 // InMemoryMetrics - Tracks metrics in memory
@@ -113,6 +148,14 @@ type InMemoryMetrics struct {
 	tasksCompleted  atomic.Int64
 	tasksFailed     atomic.Int64
 	checkoutErrors  atomic.Int64
+	tasksTimedOut   atomic.Int64
+	tasksCancelled  atomic.Int64
+
+	timesThrottled atomic.Int64
+	throttledNs    atomic.Int64
+
+	circuitState  atomic.Value // CircuitState
+	circuitOpened atomic.Int64
 
 	retryAttempts    atomic.Int64
 	retrySuccesses   atomic.Int64
@@ -120,6 +163,13 @@ type InMemoryMetrics struct {
 
 	totalDurationNs atomic.Int64
 
+	taskDurations   Histogram
+	checkoutLatency Histogram
+	finished        rateWindow // Completed and failed tasks, for the windowed throughput
+
+	byTaskType labelSet
+	byWorker   labelSet
+
 	// Protected by mutex for min/max tracking
 	mu          sync.RWMutex
 	minDuration time.Duration
@@ -133,6 +183,8 @@ func NewInMemoryMetrics() WorkerPoolMetrics {
 }
 
 func (m *InMemoryMetrics) Start(ctx context.Context, poolName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.poolName = poolName
 	m.startTime = time.Now()
 }
@@ -160,6 +212,8 @@ func (m *InMemoryMetrics) RecordTaskCheckedOut() {
 func (m *InMemoryMetrics) RecordTaskCompleted(duration time.Duration) {
 	m.tasksCompleted.Add(1)
 	m.totalDurationNs.Add(int64(duration))
+	m.taskDurations.Observe(duration)
+	m.finished.Add(time.Now())
 
 	m.mu.Lock()
 	if duration < m.minDuration {
@@ -174,6 +228,8 @@ func (m *InMemoryMetrics) RecordTaskCompleted(duration time.Duration) {
 func (m *InMemoryMetrics) RecordTaskFailed(duration time.Duration) {
 	m.tasksFailed.Add(1)
 	m.totalDurationNs.Add(int64(duration))
+	m.taskDurations.Observe(duration)
+	m.finished.Add(time.Now())
 
 	m.mu.Lock()
 	if duration < m.minDuration {
@@ -189,6 +245,30 @@ func (m *InMemoryMetrics) RecordCheckoutError() {
 	m.checkoutErrors.Add(1)
 }
 
+func (m *InMemoryMetrics) RecordCheckoutLatency(duration time.Duration) {
+	m.checkoutLatency.Observe(duration)
+}
+
+func (m *InMemoryMetrics) RecordTaskTimeout() {
+	m.tasksTimedOut.Add(1)
+}
+
+func (m *InMemoryMetrics) RecordTaskCancelled() {
+	m.tasksCancelled.Add(1)
+}
+
+func (m *InMemoryMetrics) RecordThrottled(wait time.Duration) {
+	m.timesThrottled.Add(1)
+	m.throttledNs.Add(int64(wait))
+}
+
+func (m *InMemoryMetrics) RecordCircuitState(state CircuitState) {
+	m.circuitState.Store(state)
+	if state == CircuitOpen {
+		m.circuitOpened.Add(1)
+	}
+}
+
 func (m *InMemoryMetrics) RecordRetryAttempt() {
 	m.retryAttempts.Add(1)
 }
@@ -203,7 +283,9 @@ func (m *InMemoryMetrics) RecordRetryExhausted() {
 
 func (m *InMemoryMetrics) GetSnapshot() MetricsSnapshot {
 	now := time.Now()
+	m.mu.RLock()
 	uptime := now.Sub(m.startTime)
+	m.mu.RUnlock()
 
 	workersStarted := m.workersStarted.Load()
 	workersStopped := m.workersStopped.Load()
@@ -241,6 +323,11 @@ func (m *InMemoryMetrics) GetSnapshot() MetricsSnapshot {
 		retryRate = float64(m.retryAttempts.Load()) / float64(totalTasks) * 100
 	}
 
+	circuitState, _ := m.circuitState.Load().(CircuitState)
+	taskDurations := m.taskDurations.Snapshot()
+	checkoutLatencies := m.checkoutLatency.Snapshot()
+	throttled := time.Duration(m.throttledNs.Load())
+
 	// Handle case where no tasks have been processed yet
 	if minDur == time.Duration(1<<63-1) {
 		minDur = 0
@@ -257,6 +344,23 @@ func (m *InMemoryMetrics) GetSnapshot() MetricsSnapshot {
 		TasksFailed:     tasksFailed,
 		TasksInProgress: tasksCheckedOut - totalTasks,
 		CheckoutErrors:  m.checkoutErrors.Load(),
+		TasksTimedOut:   m.tasksTimedOut.Load(),
+		TasksCancelled:  m.tasksCancelled.Load(),
+
+		TaskDuration:      taskDurations.Percentiles(),
+		CheckoutLatency:   checkoutLatencies.Percentiles(),
+		TaskDurations:     taskDurations,
+		CheckoutLatencies: checkoutLatencies,
+
+		ByTaskType: m.byTaskType.snapshot(),
+		ByWorker:   m.byWorker.snapshot(),
+
+		TimesThrottled:      m.timesThrottled.Load(),
+		ThrottledDuration:   throttled,
+		ThrottledDurationMs: throttled.Milliseconds(),
+
+		CircuitState:  circuitState,
+		CircuitOpened: m.circuitOpened.Load(),
 
 		RetryAttempts:    m.retryAttempts.Load(),
 		RetrySuccesses:   m.retrySuccesses.Load(),
@@ -268,14 +372,83 @@ func (m *InMemoryMetrics) GetSnapshot() MetricsSnapshot {
 		MinDuration:     minDur,
 		MaxDuration:     maxDur,
 
-		Throughput: throughput,
-		ErrorRate:  errorRate,
+		Throughput:   throughput,
+		Throughput1m: m.finished.Rate(now, time.Minute, uptime),
+		Throughput5m: m.finished.Rate(now, 5*time.Minute, uptime),
+		ErrorRate:    errorRate,
 
 		CollectedAt:    now,
 		UptimeDuration: uptime,
 	}
 }
 
+// circuitSeverity ranks circuit states for AggregateSnapshots
+var circuitSeverity = map[CircuitState]int{CircuitClosed: 1, CircuitHalfOpen: 2, CircuitOpen: 3}
+
+// AggregateSnapshots combines snapshots from several pools into one. Counters and throughput are
+// summed, rates, percentiles and the average duration are recomputed from the totals, and the
+// uptime is the longest of the pools. The circuit state is the worst of the pools, open over half-open over closed.
+func AggregateSnapshots(snapshots ...MetricsSnapshot) MetricsSnapshot {
+	var total MetricsSnapshot
+	for _, snapshot := range snapshots {
+		total.WorkersStarted += snapshot.WorkersStarted
+		total.WorkersStopped += snapshot.WorkersStopped
+		total.WorkersActive += snapshot.WorkersActive
+		total.WorkerPanics += snapshot.WorkerPanics
+
+		total.TasksCheckedOut += snapshot.TasksCheckedOut
+		total.TasksCompleted += snapshot.TasksCompleted
+		total.TasksFailed += snapshot.TasksFailed
+		total.TasksInProgress += snapshot.TasksInProgress
+		total.CheckoutErrors += snapshot.CheckoutErrors
+		total.TasksTimedOut += snapshot.TasksTimedOut
+		total.TasksCancelled += snapshot.TasksCancelled
+
+		total.TaskDurations.Merge(snapshot.TaskDurations)
+		total.CheckoutLatencies.Merge(snapshot.CheckoutLatencies)
+		total.ByTaskType = mergeLabelSnapshots(total.ByTaskType, snapshot.ByTaskType)
+		total.ByWorker = mergeLabelSnapshots(total.ByWorker, snapshot.ByWorker)
+
+		total.TimesThrottled += snapshot.TimesThrottled
+		total.ThrottledDuration += snapshot.ThrottledDuration
+		total.ThrottledDurationMs += snapshot.ThrottledDurationMs
+
+		total.CircuitOpened += snapshot.CircuitOpened
+		if circuitSeverity[snapshot.CircuitState] > circuitSeverity[total.CircuitState] {
+			total.CircuitState = snapshot.CircuitState
+		}
+
+		total.RetryAttempts += snapshot.RetryAttempts
+		total.RetrySuccesses += snapshot.RetrySuccesses
+		total.RetriesExhausted += snapshot.RetriesExhausted
+
+		total.TotalDuration += snapshot.TotalDuration
+		if snapshot.MinDuration > 0 && (total.MinDuration == 0 || snapshot.MinDuration < total.MinDuration) {
+			total.MinDuration = snapshot.MinDuration
+		}
+		total.MaxDuration = max(total.MaxDuration, snapshot.MaxDuration)
+
+		total.Throughput += snapshot.Throughput
+		total.Throughput1m += snapshot.Throughput1m
+		total.Throughput5m += snapshot.Throughput5m
+		if snapshot.CollectedAt.After(total.CollectedAt) {
+			total.CollectedAt = snapshot.CollectedAt
+		}
+		total.UptimeDuration = max(total.UptimeDuration, snapshot.UptimeDuration)
+	}
+
+	total.TaskDuration = total.TaskDurations.Percentiles()
+	total.CheckoutLatency = total.CheckoutLatencies.Percentiles()
+
+	if totalTasks := total.TasksCompleted + total.TasksFailed; totalTasks > 0 {
+		total.AverageDuration = total.TotalDuration / time.Duration(totalTasks)
+		total.ErrorRate = float64(total.TasksFailed) / float64(totalTasks) * 100
+		total.RetryRate = float64(total.RetryAttempts) / float64(totalTasks) * 100
+	}
+
+	return total
+}
+
 // ================================================================================
 // LoggerMetrics - Logs metrics using slog
 // ================================================================================
@@ -501,6 +674,8 @@ func (l *LoggerMetrics) logMetrics(ctx context.Context, snapshot MetricsSnapshot
 			slog.Int64("failed", snapshot.TasksFailed),
 			slog.Int64("in_progress", snapshot.TasksInProgress),
 			slog.Int64("checkout_errors", snapshot.CheckoutErrors),
+			slog.Int64("timed_out", snapshot.TasksTimedOut),
+			slog.Int64("cancelled", snapshot.TasksCancelled),
 		),
 
 		// Performance group
@@ -508,9 +683,21 @@ func (l *LoggerMetrics) logMetrics(ctx context.Context, snapshot MetricsSnapshot
 			slog.Duration("avg_duration", snapshot.AverageDuration),
 			slog.Duration("min_duration", snapshot.MinDuration),
 			slog.Duration("max_duration", snapshot.MaxDuration),
+			slog.Duration("p50_duration", snapshot.TaskDuration.P50),
+			slog.Duration("p90_duration", snapshot.TaskDuration.P90),
+			slog.Duration("p99_duration", snapshot.TaskDuration.P99),
 			slog.Float64("throughput_per_sec", snapshot.Throughput),
+			slog.Float64("throughput_1m_per_sec", snapshot.Throughput1m),
+			slog.Float64("throughput_5m_per_sec", snapshot.Throughput5m),
 			slog.Float64("error_rate_pct", snapshot.ErrorRate),
 		),
+
+		// Checkout group
+		slog.Group("checkout",
+			slog.Duration("p50_latency", snapshot.CheckoutLatency.P50),
+			slog.Duration("p90_latency", snapshot.CheckoutLatency.P90),
+			slog.Duration("p99_latency", snapshot.CheckoutLatency.P99),
+		),
 	}
 
 	// Add retry metrics if present
@@ -523,5 +710,35 @@ func (l *LoggerMetrics) logMetrics(ctx context.Context, snapshot MetricsSnapshot
 		))
 	}
 
+	// Add circuit breaker metrics if present
+	if snapshot.CircuitState != "" {
+		attrs = append(attrs, slog.Group("circuit",
+			slog.String("state", string(snapshot.CircuitState)),
+			slog.Int64("opened", snapshot.CircuitOpened),
+		))
+	}
+
+	// Add the breakdown by task type if present, the one by worker is left to the snapshot
+	if len(snapshot.ByTaskType) > 0 {
+		types := make([]any, 0, len(snapshot.ByTaskType))
+		for taskType, labelled := range snapshot.ByTaskType {
+			types = append(types, slog.Group(taskType,
+				slog.Int64("completed", labelled.Completed),
+				slog.Int64("failed", labelled.Failed),
+				slog.Int64("retries", labelled.Retries),
+				slog.Duration("p99_duration", labelled.Duration.P99),
+			))
+		}
+		attrs = append(attrs, slog.Group("task_types", types...))
+	}
+
+	// Add throttling metrics if present
+	if snapshot.TimesThrottled > 0 {
+		attrs = append(attrs, slog.Group("throttling",
+			slog.Int64("times", snapshot.TimesThrottled),
+			slog.Duration("duration", snapshot.ThrottledDuration),
+		))
+	}
+
 	l.logger.LogAttrs(ctx, l.level, "worker_pool_metrics", attrs...)
 }
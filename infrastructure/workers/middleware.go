@@ -3,13 +3,28 @@ package workers
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"sync"
 )
 
+// poolKey carries poolValues in the context work runs with
+type poolKey struct{}
+
+// poolValues lets middleware report to the pool that runs it, e.g. limiters record throttled
+// time to its metrics
+type poolValues struct {
+	name    string
+	log     *slog.Logger
+	metrics WorkerPoolMetrics
+}
+
 // buildMiddlewareChain creates the middleware chain
 func (wp *WorkerPool[T]) buildMiddlewareChain() {
 	// Start with the base work function
 	wp.workFunc = wp.work
+	if wp.batchHandler != nil {
+		wp.workFunc = wp.workBatch
+	}
 
 	// Apply middlewares in reverse order (so first added = outermost)
 	for i := len(wp.middlewares) - 1; i >= 0; i-- {
@@ -374,3 +374,59 @@ func BenchmarkMiddleware_Overhead(b *testing.B) {
 		})
 	}
 }
+
+func TestMiddleware_CircuitBreaker(t *testing.T) {
+	var down atomic.Bool
+	var calls atomic.Int32
+	down.Store(true)
+
+	processor := NewStubProcessor()
+	processor.infiniteTasks = true
+	processor.processFunc = func(ctx context.Context, task TestTask) (TestTask, error) {
+		calls.Add(1)
+		if down.Load() {
+			return task, errors.New("dependency unavailable")
+		}
+		return task, nil
+	}
+	metrics := workers.NewInMemoryMetrics()
+
+	pool, err := workers.NewWorkerPool("test-pool", 2, processor,
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(time.Millisecond),
+		workers.WithIdleInterval(5*time.Millisecond),
+		workers.WithMaxRetries(1),
+		workers.WithMetrics(metrics),
+		workers.WithMiddleware(workers.CircuitBreaker(3, 100*time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	defer func() {
+		pool.Stop()
+		<-done
+	}()
+
+	state := func() workers.CircuitState { return metrics.GetSnapshot().CircuitState }
+	eventually(t, time.Second, func() bool { return state() == workers.CircuitOpen }, "expected the breaker to open, state %q", state())
+
+	// Open: checkouts are paused, nothing reaches the dependency until the cooldown is over
+	before := calls.Load()
+	time.Sleep(50 * time.Millisecond)
+	if after := calls.Load(); after > before+1 {
+		t.Errorf("expected checkouts to pause while open, %d calls went through", after-before)
+	}
+
+	// Probes fail while the dependency is down, then close the breaker once it is back
+	eventually(t, time.Second, func() bool { return metrics.GetSnapshot().CircuitOpened >= 2 }, "expected a failed probe to reopen the breaker")
+	down.Store(false)
+	eventually(t, time.Second, func() bool { return state() == workers.CircuitClosed }, "expected the breaker to close, state %q", state())
+
+	completed := processor.completeCount.Load()
+	eventually(t, time.Second, func() bool { return processor.completeCount.Load() > completed+10 }, "expected work to resume after closing")
+}
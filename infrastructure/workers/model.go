@@ -1,12 +1,22 @@
 package workers
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Task interface - any task must have an ID
 type Task interface {
 	GetID() string
 }
 
+// TimeoutTask is implemented by tasks that need a different timeout than the pool's (see
+// WithTaskTimeout). A positive TaskTimeout overrides the pool's (and any TaskPolicy) timeout,
+// zero keeps it.
+type TimeoutTask interface {
+	TaskTimeout() time.Duration
+}
+
 // Processor handles the business logic for processing tasks
 type Processor[T Task] interface {
 	// Checkout gets the next available task (must be atomic for concurrent workers)
@@ -33,3 +43,72 @@ type PreProcessHook[T Task] func(ctx context.Context, task T) error
 
 // PostProcessHook runs after Process (gets the result or error)
 type PostProcessHook[T Task] func(ctx context.Context, task T, err error) error
+
+// Queue is the storage side of a Processor: it hands out tasks and records how they ended.
+// Checkout must be atomic across concurrent workers (and processes, for shared stores).
+type Queue[T Task] interface {
+	Checkout(ctx context.Context, workerID string) (T, error)
+	Complete(ctx context.Context, task T, processingTimeMS int) error
+	Fail(ctx context.Context, task T, err error) error
+}
+
+// Handler is the business logic side of a Processor
+type Handler[T Task] interface {
+	Process(ctx context.Context, task T) (T, error)
+}
+
+// HandlerFunc adapts a plain function to a Handler
+type HandlerFunc[T Task] func(ctx context.Context, task T) (T, error)
+
+// Process calls f(ctx, task)
+func (f HandlerFunc[T]) Process(ctx context.Context, task T) (T, error) {
+	return f(ctx, task)
+}
+
+// Leaser is implemented by queues that lease checked out tasks for a limited time. When the
+// processor (or the queue behind a QueueProcessor) implements it, the pool checks out through
+// CheckoutLeased, extends the lease on a heartbeat while Process runs and periodically calls
+// ReapExpired so tasks abandoned by crashed workers go back to the queue.
+type Leaser[T Task] interface {
+	// CheckoutLeased is Checkout that also leases the task to workerID
+	CheckoutLeased(ctx context.Context, workerID string, lease time.Duration) (T, error)
+
+	// ExtendLease pushes the lease out by lease. Returns ErrLeaseLost if workerID no longer holds the
+	// task, and ErrTaskCancelled if the task was asked to cancel; Process is then cancelled.
+	ExtendLease(ctx context.Context, task T, workerID string, lease time.Duration) error
+
+	// ReapExpired returns tasks with expired leases to the queue and reports how many were reclaimed
+	ReapExpired(ctx context.Context) (int, error)
+}
+
+// Notifier wakes idle workers as soon as new work may be available, instead of leaving them
+// asleep until the next idle poll. Polling stays on as the fallback for missed notifications.
+type Notifier interface {
+	// Listen blocks until ctx is cancelled, calling notify whenever new work may be available.
+	// A returned error means the notifier broke down (e.g. lost its connection); the pool will
+	// call Listen again after a backoff.
+	Listen(ctx context.Context, notify func()) error
+}
+
+// Releaser is implemented by queues that can hand a task back without counting it as a failure.
+// When a pool's drain deadline passes, the tasks it abandons are released if the processor (or the
+// queue behind a QueueProcessor) implements Releaser, and failed otherwise.
+type Releaser[T Task] interface {
+	Release(ctx context.Context, task T) error
+}
+
+// RetryingQueue is implemented by queues that retry failed tasks themselves, putting them back
+// until the task's own retry budget is used up. A pool over such a queue (or a QueueProcessor
+// wrapping one) makes a single attempt per checkout unless WithMaxRetries is given, so its
+// in-process retries don't multiply with the queue's.
+type RetryingQueue interface {
+	RetriesFailedTasks() bool
+}
+
+// Canceller is implemented by queues that can end a task as cancelled. A leased task is cancelled
+// when ExtendLease reports ErrTaskCancelled: its Process context is cancelled and the task is
+// handed to Cancel instead of Fail, so it isn't retried. Without a Canceller the task is failed
+// with a permanent ErrTaskCancelled.
+type Canceller[T Task] interface {
+	Cancel(ctx context.Context, task T) error
+}
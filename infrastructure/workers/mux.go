@@ -0,0 +1,219 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownTaskType is returned (as a permanent error) for tasks no TaskMux route handles
+var ErrUnknownTaskType = errors.New("unknown task type")
+
+// TypedTask is a task that names its type and carries a JSON payload, e.g. a row of the tasks
+// table with its task_type and metadata columns. TaskMux routes on it.
+type TypedTask interface {
+	Task
+	TaskTyper
+	GetPayload() []byte
+}
+
+// TaskPolicy overrides the pool's retry and timeout settings for some tasks. Zero fields keep the
+// pool's setting.
+type TaskPolicy struct {
+	MaxRetries int           // See WithMaxRetries
+	Backoff    BackoffPolicy // See WithBackoff
+	Timeout    time.Duration // See WithTaskTimeout
+}
+
+// PolicyProvider is implemented by processors (or the handler behind a QueueProcessor) that pick
+// retry and timeout settings per task. TaskMux implements it with its per-type policies.
+type PolicyProvider[T Task] interface {
+	TaskPolicy(task T) TaskPolicy
+}
+
+// RouteOption configures a TaskMux route
+type RouteOption func(*TaskPolicy)
+
+// WithRouteMaxRetries sets the attempts for tasks of the route's type
+func WithRouteMaxRetries(maxRetries int) RouteOption {
+	return func(p *TaskPolicy) {
+		p.MaxRetries = maxRetries
+	}
+}
+
+// WithRouteBackoff sets the retry backoff for tasks of the route's type
+func WithRouteBackoff(policy BackoffPolicy) RouteOption {
+	return func(p *TaskPolicy) {
+		p.Backoff = policy
+	}
+}
+
+// WithRouteTimeout sets the per-attempt timeout for tasks of the route's type
+func WithRouteTimeout(timeout time.Duration) RouteOption {
+	return func(p *TaskPolicy) {
+		p.Timeout = timeout
+	}
+}
+
+// muxRoute is a registered handler and its policy
+type muxRoute[T TypedTask] struct {
+	handler  Handler[T]
+	policy   TaskPolicy
+	validate func(payload []byte) error // nil for routes registered without a payload type
+}
+
+// TaskMux is a Handler that routes tasks to the handler registered for their type, so one pool
+// can serve many kinds of jobs. Register typed handlers with Register; tasks of a type nobody
+// registered go to the fallback handler, or fail permanently with ErrUnknownTaskType.
+// A TaskMux is safe for concurrent use, routes are usually registered before the pool starts.
+type TaskMux[T TypedTask] struct {
+	mu       sync.RWMutex
+	routes   map[string]muxRoute[T]
+	fallback *muxRoute[T]
+}
+
+// NewTaskMux creates an empty mux
+func NewTaskMux[T TypedTask]() *TaskMux[T] {
+	return &TaskMux[T]{
+		routes: make(map[string]muxRoute[T]),
+	}
+}
+
+// Handle registers handler for taskType. It panics if taskType is empty or already registered.
+func (m *TaskMux[T]) Handle(taskType string, handler Handler[T], opts ...RouteOption) {
+	m.handle(taskType, muxRoute[T]{handler: handler}, opts...)
+}
+
+// handle registers a route, see Handle
+func (m *TaskMux[T]) handle(taskType string, route muxRoute[T], opts ...RouteOption) {
+	if taskType == "" {
+		panic("workers: empty task type")
+	}
+	if route.handler == nil {
+		panic("workers: nil handler for task type " + taskType)
+	}
+
+	for _, opt := range opts {
+		opt(&route.policy)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.routes[taskType]; exists {
+		panic("workers: multiple registrations for task type " + taskType)
+	}
+	m.routes[taskType] = route
+}
+
+// HandleFallback sets the handler for task types without a route
+func (m *TaskMux[T]) HandleFallback(handler Handler[T], opts ...RouteOption) {
+	var policy TaskPolicy
+	for _, opt := range opts {
+		opt(&policy)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if handler == nil {
+		m.fallback = nil
+		return
+	}
+	m.fallback = &muxRoute[T]{handler: handler, policy: policy}
+}
+
+// Register adds a handler for taskType that receives the task's payload decoded into P. A payload
+// that doesn't decode, or whose Validate method (if P has one) fails, fails the task permanently;
+// an empty payload leaves P at its zero value. It panics if taskType is empty or already registered.
+func Register[T TypedTask, P any](m *TaskMux[T], taskType string, handler func(ctx context.Context, task T, payload P) (T, error), opts ...RouteOption) {
+	m.handle(taskType, muxRoute[T]{
+		handler: HandlerFunc[T](func(ctx context.Context, task T) (T, error) {
+			payload, err := decodePayload[P](task.GetPayload())
+			if err != nil {
+				return task, Permanent(fmt.Errorf("%s payload: %w", taskType, err))
+			}
+			return handler(ctx, task, payload)
+		}),
+		validate: func(raw []byte) error {
+			_, err := decodePayload[P](raw)
+			return err
+		},
+	}, opts...)
+}
+
+// decodePayload decodes a JSON payload into P and runs its Validate method, if any
+func decodePayload[P any](raw []byte) (P, error) {
+	var payload P
+	if raw = bytes.TrimSpace(raw); len(raw) > 0 {
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return payload, fmt.Errorf("decode: %w", err)
+		}
+	}
+	if v, ok := any(&payload).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return payload, fmt.Errorf("validate: %w", err)
+		}
+	}
+	return payload, nil
+}
+
+// Process runs the task through the handler registered for its type
+func (m *TaskMux[T]) Process(ctx context.Context, task T) (T, error) {
+	route, ok := m.route(task.GetTaskType())
+	if !ok {
+		return task, Permanent(fmt.Errorf("%w %q", ErrUnknownTaskType, task.GetTaskType()))
+	}
+	return route.handler.Process(ctx, task)
+}
+
+// ValidatePayload checks that payload is acceptable to the handler registered for taskType, i.e.
+// that a task enqueued with it would not fail on decoding. Payloads of routes registered with
+// Handle, and of the fallback, are not checked. Returns ErrUnknownTaskType when nothing would
+// handle the type.
+func (m *TaskMux[T]) ValidatePayload(taskType string, payload []byte) error {
+	route, ok := m.route(taskType)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownTaskType, taskType)
+	}
+	if route.validate == nil {
+		return nil
+	}
+	return route.validate(payload)
+}
+
+// TaskPolicy returns the retry and timeout settings registered for the task's type
+func (m *TaskMux[T]) TaskPolicy(task T) TaskPolicy {
+	route, _ := m.route(task.GetTaskType())
+	return route.policy
+}
+
+// TaskTypes returns the registered task types, sorted
+func (m *TaskMux[T]) TaskTypes() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	types := make([]string, 0, len(m.routes))
+	for taskType := range m.routes {
+		types = append(types, taskType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// route finds the route for taskType, falling back to the fallback handler
+func (m *TaskMux[T]) route(taskType string) (muxRoute[T], bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if route, ok := m.routes[taskType]; ok {
+		return route, true
+	}
+	if m.fallback != nil {
+		return *m.fallback, true
+	}
+	return muxRoute[T]{}, false
+}
@@ -0,0 +1,175 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+// muxTask is a TypedTask for exercising TaskMux
+type muxTask struct {
+	ID      string
+	Type    string
+	Payload string
+}
+
+func (t muxTask) GetID() string       { return t.ID }
+func (t muxTask) GetTaskType() string { return t.Type }
+func (t muxTask) GetPayload() []byte  { return []byte(t.Payload) }
+
+type emailPayload struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+}
+
+func TestTaskMux_RoutesByType(t *testing.T) {
+	mux := workers.NewTaskMux[muxTask]()
+
+	var got emailPayload
+	workers.Register(mux, "email", func(ctx context.Context, task muxTask, payload emailPayload) (muxTask, error) {
+		got = payload
+		return task, nil
+	})
+	workers.Register(mux, "noop", func(ctx context.Context, task muxTask, payload struct{}) (muxTask, error) {
+		return task, nil
+	})
+
+	if _, err := mux.Process(context.Background(), muxTask{ID: "1", Type: "email", Payload: `{"to":"a@example.com","subject":"hi"}`}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.To != "a@example.com" || got.Subject != "hi" {
+		t.Errorf("payload not decoded, got %+v", got)
+	}
+
+	// Empty payloads decode to the zero value
+	if _, err := mux.Process(context.Background(), muxTask{ID: "2", Type: "noop"}); err != nil {
+		t.Errorf("unexpected error for empty payload: %v", err)
+	}
+
+	// Bad payloads and unknown types are permanent failures
+	_, err := mux.Process(context.Background(), muxTask{ID: "3", Type: "email", Payload: `{"to":`})
+	if err == nil || workers.IsRetryable(err) {
+		t.Errorf("expected a permanent error for a bad payload, got %v", err)
+	}
+	_, err = mux.Process(context.Background(), muxTask{ID: "4", Type: "sms"})
+	if !errors.Is(err, workers.ErrUnknownTaskType) || workers.IsRetryable(err) {
+		t.Errorf("expected a permanent ErrUnknownTaskType, got %v", err)
+	}
+
+	if types := mux.TaskTypes(); len(types) != 2 || types[0] != "email" || types[1] != "noop" {
+		t.Errorf("unexpected task types %v", types)
+	}
+}
+
+func TestTaskMux_Fallback(t *testing.T) {
+	mux := workers.NewTaskMux[muxTask]()
+	var fallbackCalls int
+	mux.HandleFallback(workers.HandlerFunc[muxTask](func(ctx context.Context, task muxTask) (muxTask, error) {
+		fallbackCalls++
+		return task, nil
+	}))
+
+	if _, err := mux.Process(context.Background(), muxTask{ID: "1", Type: "anything"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fallbackCalls != 1 {
+		t.Errorf("expected the fallback to run once, ran %d times", fallbackCalls)
+	}
+}
+
+func TestTaskMux_DuplicateRegistrationPanics(t *testing.T) {
+	mux := workers.NewTaskMux[muxTask]()
+	handler := func(ctx context.Context, task muxTask, payload struct{}) (muxTask, error) { return task, nil }
+	workers.Register(mux, "email", handler)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic on duplicate registration")
+		}
+	}()
+	workers.Register(mux, "email", handler)
+}
+
+// muxQueue is a minimal queue of muxTasks that records failures by task ID
+type muxQueue struct {
+	mu      sync.Mutex
+	pending []muxTask
+	failed  map[string]error
+}
+
+func (q *muxQueue) Checkout(ctx context.Context, workerID string) (muxTask, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return muxTask{}, workers.ErrNoWorkAvailable
+	}
+	task := q.pending[0]
+	q.pending = q.pending[1:]
+	return task, nil
+}
+
+func (q *muxQueue) Complete(ctx context.Context, task muxTask, processingTimeMS int) error {
+	return nil
+}
+
+func (q *muxQueue) Fail(ctx context.Context, task muxTask, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.failed[task.ID] = err
+	return nil
+}
+
+func TestTaskMux_PerTypePolicy(t *testing.T) {
+	mux := workers.NewTaskMux[muxTask]()
+	failing := func(ctx context.Context, task muxTask, payload struct{}) (muxTask, error) {
+		return task, errors.New("boom")
+	}
+	workers.Register(mux, "flaky", failing, workers.WithRouteMaxRetries(4))
+	workers.Register(mux, "default", failing)
+	workers.Register(mux, "slow", func(ctx context.Context, task muxTask, payload struct{}) (muxTask, error) {
+		<-ctx.Done()
+		return task, ctx.Err()
+	}, workers.WithRouteTimeout(20*time.Millisecond), workers.WithRouteMaxRetries(1))
+
+	queue := &muxQueue{
+		failed:  make(map[string]error),
+		pending: []muxTask{{ID: "a", Type: "flaky"}, {ID: "b", Type: "default"}, {ID: "c", Type: "slow"}},
+	}
+
+	pool, err := workers.NewWorkerPool("mux-pool", 3, workers.NewQueueProcessor(queue, mux),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(2),
+		workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	time.Sleep(300 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if got := len(workers.AttemptsOf(queue.failed["a"])); got != 4 {
+		t.Errorf("expected the route's 4 attempts for flaky, got %d", got)
+	}
+	if got := len(workers.AttemptsOf(queue.failed["b"])); got != 2 {
+		t.Errorf("expected the pool's 2 attempts for default, got %d", got)
+	}
+	if err := queue.failed["c"]; !errors.Is(err, workers.ErrTaskTimeout) {
+		t.Errorf("expected the route's timeout for slow, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package workers_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// chanNotifier is a workers.Notifier driven by a channel
+type chanNotifier struct {
+	signals chan struct{}
+}
+
+func (n *chanNotifier) Listen(ctx context.Context, notify func()) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-n.signals:
+			notify()
+		}
+	}
+}
+
+func TestWorkerPool_NotifierWakesIdleWorkers(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	notifier := &chanNotifier{signals: make(chan struct{}, 1)}
+
+	completed := make(chan time.Time, 1)
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		completed <- time.Now()
+		return task, nil
+	})
+
+	pool, err := workers.NewWorkerPool("notified-pool", 2, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithIdleInterval(time.Minute),
+		workers.WithNotifier(notifier),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	defer func() {
+		pool.Stop()
+		<-done
+	}()
+
+	// Let the workers find the queue empty and go idle for a minute
+	time.Sleep(100 * time.Millisecond)
+
+	enqueue(t, queue, TestTask{ID: "urgent-task"})
+	notified := time.Now()
+	notifier.signals <- struct{}{}
+
+	select {
+	case at := <-completed:
+		if latency := at.Sub(notified); latency > 500*time.Millisecond {
+			t.Errorf("task took %v to be picked up after the notification", latency)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle workers were not woken by the notifier")
+	}
+}
@@ -0,0 +1,129 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ProgressReport is how far a task has got, as last reported through its Progress
+type ProgressReport struct {
+	Percent    float64   `json:"percent"`
+	Step       string    `json:"step,omitempty"`
+	Checkpoint []byte    `json:"-"` // Only set when a checkpoint is saved, see ProgressSaver
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ProgressSaver is implemented by queues that persist the progress of the tasks being processed.
+// When the processor (or the queue behind a QueueProcessor) implements it, every Report and
+// SaveCheckpoint on a task's Progress is saved. A report without a checkpoint keeps the one
+// saved before.
+type ProgressSaver[T Task] interface {
+	SaveProgress(ctx context.Context, task T, report ProgressReport) error
+}
+
+// CheckpointTask is implemented by tasks that carry the checkpoint saved by an earlier run, so a
+// run after a failure resumes where the last one stopped. Retries within a run get the last
+// checkpoint whether the task implements it or not.
+type CheckpointTask interface {
+	GetCheckpoint() []byte
+}
+
+// Progress lets Process report how far a task has got and save checkpoints to resume from.
+// Get it with ProgressFrom. All methods are safe for concurrent use, and on a nil Progress they
+// do nothing, so a processor can report progress whether it runs in a pool or not.
+type Progress struct {
+	save func(ctx context.Context, report ProgressReport) error // nil when the pool doesn't persist progress
+
+	mu         sync.Mutex
+	report     ProgressReport
+	checkpoint []byte
+}
+
+type progressKey struct{}
+
+// ProgressFrom returns the Progress of the task being processed, nil outside a worker pool
+func ProgressFrom(ctx context.Context) *Progress {
+	progress, _ := ctx.Value(progressKey{}).(*Progress)
+	return progress
+}
+
+// withProgress returns ctx carrying progress
+func withProgress(ctx context.Context, progress *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// newProgress creates the Progress of task, starting from the checkpoint it carries
+func (wp *WorkerPool[T]) newProgress(task T) *Progress {
+	progress := &Progress{}
+	if checkpointed, ok := any(task).(CheckpointTask); ok {
+		progress.checkpoint = checkpointed.GetCheckpoint()
+	}
+	if wp.progressSaver != nil {
+		progress.save = func(ctx context.Context, report ProgressReport) error {
+			return wp.progressSaver.SaveProgress(ctx, task, report)
+		}
+	}
+	return progress
+}
+
+// Report records how far the task has got: percent (0-100) and, optionally, the step it is on.
+// An empty step keeps the previous one.
+func (p *Progress) Report(ctx context.Context, percent float64, step string) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	p.report.Percent = min(max(percent, 0), 100)
+	if step != "" {
+		p.report.Step = step
+	}
+	p.report.UpdatedAt = time.Now()
+	report := p.report
+	p.mu.Unlock()
+
+	if p.save == nil {
+		return nil
+	}
+	return p.save(ctx, report)
+}
+
+// SaveCheckpoint saves data as the point to resume from if the task is retried, along with the
+// current progress. It returns once the checkpoint is persisted (when the pool persists progress).
+func (p *Progress) SaveCheckpoint(ctx context.Context, data []byte) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	p.checkpoint = append([]byte(nil), data...)
+	p.report.UpdatedAt = time.Now()
+	report := p.report
+	report.Checkpoint = p.checkpoint
+	p.mu.Unlock()
+
+	if p.save == nil {
+		return nil
+	}
+	return p.save(ctx, report)
+}
+
+// Checkpoint returns the last saved checkpoint, from this run or an earlier one; nil when the
+// task starts from scratch
+func (p *Progress) Checkpoint() []byte {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.checkpoint
+}
+
+// Snapshot returns the last report
+func (p *Progress) Snapshot() ProgressReport {
+	if p == nil {
+		return ProgressReport{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.report
+}
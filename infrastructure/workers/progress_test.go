@@ -0,0 +1,115 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+func TestProgress_RetryResumesFromCheckpoint(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	enqueue(t, queue, TestTask{ID: "resumable-task"})
+
+	var resumedFrom []byte
+	var saved, final memqueue.Entry[TestTask]
+	attempts := 0
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		progress := workers.ProgressFrom(ctx)
+		attempts++
+		if attempts == 1 {
+			progress.Report(ctx, 50, "first half")
+			progress.SaveCheckpoint(ctx, []byte("halfway"))
+			return task, errors.New("boom")
+		}
+		saved, _ = queue.Get(task.ID)
+		resumedFrom = progress.Checkpoint()
+		progress.Report(ctx, 100, "")
+		final, _ = queue.Get(task.ID)
+		return task, nil
+	})
+
+	pool, err := workers.NewWorkerPool("progress-pool", 1, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(2),
+		workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	time.Sleep(150 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	if string(resumedFrom) != "halfway" {
+		t.Errorf("expected the retry to resume from the checkpoint, got %q", resumedFrom)
+	}
+	if stats := queue.Stats(); stats.Completed != 1 {
+		t.Fatalf("expected the task to complete on retry, got %+v", stats)
+	}
+	if saved.Progress == nil || saved.Progress.Percent != 50 || string(saved.Checkpoint) != "halfway" {
+		t.Fatalf("expected the first run's progress and checkpoint saved, got %+v with %q", saved.Progress, saved.Checkpoint)
+	}
+	if final.Progress == nil || final.Progress.Percent != 100 || final.Progress.Step != "first half" {
+		t.Errorf("expected 100%% with the step kept, got %+v", final.Progress)
+	}
+	if string(final.Checkpoint) != "halfway" {
+		t.Errorf("expected a report without a checkpoint to keep the saved one, got %q", final.Checkpoint)
+	}
+}
+
+func TestProgress_VisibleInWorkerStatus(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	enqueue(t, queue, TestTask{ID: "reporting-task"})
+
+	release := make(chan struct{})
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		workers.ProgressFrom(ctx).Report(ctx, 42, "crunching")
+		<-release
+		return task, nil
+	})
+
+	pool, err := workers.NewWorkerPool("progress-pool", 1, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	statuses := pool.Workers()
+	close(release)
+	pool.Stop()
+	<-done
+
+	if len(statuses) != 1 || statuses[0].Progress == nil {
+		t.Fatalf("expected the worker to show the task's progress, got %+v", statuses)
+	}
+	if progress := statuses[0].Progress; progress.Percent != 42 || progress.Step != "crunching" {
+		t.Errorf("expected 42%% at crunching, got %v%% at %q", progress.Percent, progress.Step)
+	}
+
+	// Outside a pool there is no Progress, and reporting is a no-op
+	progress := workers.ProgressFrom(context.Background())
+	if err := progress.Report(context.Background(), 10, "step"); err != nil || progress.Checkpoint() != nil {
+		t.Errorf("expected a nil Progress to do nothing, got %v", err)
+	}
+}
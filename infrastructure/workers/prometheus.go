@@ -0,0 +1,105 @@
+package workers
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jrazmi/envoker/sdk/promtext"
+)
+
+// PrometheusMetrics is InMemoryMetrics that Prometheus can scrape: register it with a
+// promtext.Registry and serve the registry on /metrics. Every series carries a pool label, so the
+// metrics of several pools can share a registry. Task types and workers get their own series,
+// from the labelled breakdown.
+type PrometheusMetrics struct {
+	*InMemoryMetrics
+}
+
+// NewPrometheusMetrics creates metrics for a pool to be scraped by Prometheus
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		InMemoryMetrics: &InMemoryMetrics{
+			minDuration: time.Duration(1<<63 - 1),
+		},
+	}
+}
+
+// Collect writes the current metrics of the pool, it implements promtext.Collector
+func (p *PrometheusMetrics) Collect(w *promtext.Writer) {
+	p.mu.RLock()
+	pool := promtext.Label{Name: "pool", Value: p.poolName}
+	p.mu.RUnlock()
+	snapshot := p.GetSnapshot()
+
+	// Workers
+	w.Gauge("worker_pool_workers_active", "Workers currently running.", float64(snapshot.WorkersActive), pool)
+	w.Counter("worker_pool_workers_started_total", "Workers started.", float64(snapshot.WorkersStarted), pool)
+	w.Counter("worker_pool_worker_panics_total", "Panics recovered in workers and tasks.", float64(snapshot.WorkerPanics), pool)
+
+	// Tasks
+	w.Counter("worker_pool_tasks_checked_out_total", "Tasks checked out.", float64(snapshot.TasksCheckedOut), pool)
+	w.Counter("worker_pool_tasks_completed_total", "Tasks completed.", float64(snapshot.TasksCompleted), pool)
+	w.Counter("worker_pool_tasks_failed_total", "Tasks failed.", float64(snapshot.TasksFailed), pool)
+	w.Gauge("worker_pool_tasks_in_progress", "Tasks checked out and not finished yet.", float64(snapshot.TasksInProgress), pool)
+	w.Counter("worker_pool_checkout_errors_total", "Checkouts that found no work or failed.", float64(snapshot.CheckoutErrors), pool)
+	w.Counter("worker_pool_task_timeouts_total", "Process attempts that ran past their timeout.", float64(snapshot.TasksTimedOut), pool)
+	w.Counter("worker_pool_tasks_cancelled_total", "Tasks cancelled on request.", float64(snapshot.TasksCancelled), pool)
+	w.Histogram("worker_pool_task_duration_seconds", "Time from checkout to completion or failure.", promHistogram(snapshot.TaskDurations), pool)
+	w.Histogram("worker_pool_checkout_duration_seconds", "Time a checkout took.", promHistogram(snapshot.CheckoutLatencies), pool)
+
+	// Retries
+	w.Counter("worker_pool_retry_attempts_total", "Retries of failed process attempts.", float64(snapshot.RetryAttempts), pool)
+	w.Counter("worker_pool_retry_successes_total", "Tasks that succeeded after a retry.", float64(snapshot.RetrySuccesses), pool)
+	w.Counter("worker_pool_retries_exhausted_total", "Tasks that failed every attempt.", float64(snapshot.RetriesExhausted), pool)
+
+	// Throttling and circuit breaking
+	w.Counter("worker_pool_throttled_total", "Waits on a rate or concurrency limiter.", float64(snapshot.TimesThrottled), pool)
+	w.Counter("worker_pool_throttled_seconds_total", "Time spent waiting on limiters.", snapshot.ThrottledDuration.Seconds(), pool)
+	if snapshot.CircuitState != "" {
+		for _, state := range []CircuitState{CircuitClosed, CircuitOpen, CircuitHalfOpen} {
+			value := 0.0
+			if state == snapshot.CircuitState {
+				value = 1
+			}
+			w.Gauge("worker_pool_circuit_state", "State of the pool's circuit breaker, 1 for the current state.", value, pool, promtext.Label{Name: "state", Value: string(state)})
+		}
+		w.Counter("worker_pool_circuit_opened_total", "Times the circuit breaker opened.", float64(snapshot.CircuitOpened), pool)
+	}
+
+	// Breakdown by task type and worker
+	for _, taskType := range slices.Sorted(maps.Keys(snapshot.ByTaskType)) {
+		labelled := snapshot.ByTaskType[taskType]
+		label := promtext.Label{Name: "task_type", Value: taskType}
+		w.Counter("worker_pool_task_type_completed_total", "Tasks completed per task type.", float64(labelled.Completed), pool, label)
+		w.Counter("worker_pool_task_type_failed_total", "Tasks failed per task type.", float64(labelled.Failed), pool, label)
+		w.Counter("worker_pool_task_type_retries_total", "Retries per task type.", float64(labelled.Retries), pool, label)
+		w.Histogram("worker_pool_task_type_duration_seconds", "Task duration per task type.", promHistogram(labelled.Durations), pool, label)
+	}
+	for _, workerID := range slices.Sorted(maps.Keys(snapshot.ByWorker)) {
+		labelled := snapshot.ByWorker[workerID]
+		label := promtext.Label{Name: "worker", Value: workerID}
+		w.Counter("worker_pool_worker_completed_total", "Tasks completed per worker.", float64(labelled.Completed), pool, label)
+		w.Counter("worker_pool_worker_failed_total", "Tasks failed per worker.", float64(labelled.Failed), pool, label)
+	}
+}
+
+// promHistogram converts a HistogramSnapshot to cumulative Prometheus buckets in seconds. Every
+// fourth bound is kept, the bounds double from 100µs, which is plenty for dashboards and keeps
+// the series count down.
+func promHistogram(snapshot HistogramSnapshot) promtext.Histogram {
+	h := promtext.Histogram{
+		Count: uint64(snapshot.Count),
+		Sum:   snapshot.Sum.Seconds(),
+	}
+	var cumulative int64
+	for i, bound := range histogramBounds {
+		if i < len(snapshot.Counts) {
+			cumulative += snapshot.Counts[i]
+		}
+		if i%4 == 0 {
+			h.Buckets = append(h.Buckets, promtext.Bucket{UpperBound: bound.Seconds(), Count: uint64(cumulative)})
+		}
+	}
+	return h
+}
@@ -0,0 +1,55 @@
+package workers_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/sdk/promtext"
+)
+
+func TestPrometheusMetrics_Exposition(t *testing.T) {
+	emails, reports := workers.NewPrometheusMetrics(), workers.NewPrometheusMetrics()
+	emails.Start(context.Background(), "emails")
+	reports.Start(context.Background(), "reports")
+
+	emails.RecordTaskCompleted(3 * time.Millisecond)
+	emails.RecordLabelledCompleted(workers.TaskLabels{TaskType: "email", WorkerID: "emails-worker-1"}, 3*time.Millisecond)
+	reports.RecordTaskFailed(2 * time.Second)
+	reports.RecordCircuitState(workers.CircuitOpen)
+
+	registry := promtext.NewRegistry()
+	registry.Register(emails, reports)
+	var buf bytes.Buffer
+	if _, err := registry.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, line := range []string{
+		`worker_pool_tasks_completed_total{pool="emails"} 1`,
+		`worker_pool_tasks_failed_total{pool="reports"} 1`,
+		`worker_pool_task_duration_seconds_bucket{pool="emails",le="0.0016"} 0`,
+		`worker_pool_task_duration_seconds_bucket{pool="emails",le="0.0032"} 1`,
+		`worker_pool_task_duration_seconds_bucket{pool="reports",le="+Inf"} 1`,
+		`worker_pool_task_duration_seconds_count{pool="reports"} 1`,
+		`worker_pool_circuit_state{pool="reports",state="open"} 1`,
+		`worker_pool_task_type_completed_total{pool="emails",task_type="email"} 1`,
+		`worker_pool_worker_completed_total{pool="emails",worker="emails-worker-1"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+
+	// Pools share a registry: each metric is declared once with the samples of both
+	if n := strings.Count(out, "# TYPE worker_pool_tasks_completed_total counter\n"); n != 1 {
+		t.Errorf("expected one TYPE line for a shared metric, got %d", n)
+	}
+	if strings.Contains(out, `worker_pool_circuit_state{pool="emails"`) {
+		t.Error("expected no circuit state for a pool without a breaker")
+	}
+}
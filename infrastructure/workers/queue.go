@@ -0,0 +1,65 @@
+package workers
+
+import "context"
+
+// QueueProcessor joins a Queue and a Handler into a Processor, so ready-made queues
+// (e.g. a Postgres backed one) can be reused with any business logic.
+type QueueProcessor[T Task] struct {
+	queue   Queue[T]
+	handler Handler[T]
+}
+
+// NewQueueProcessor creates a Processor that checks tasks out of queue and runs them through handler
+func NewQueueProcessor[T Task](queue Queue[T], handler Handler[T]) *QueueProcessor[T] {
+	return &QueueProcessor[T]{
+		queue:   queue,
+		handler: handler,
+	}
+}
+
+// Queue returns the underlying queue
+func (p *QueueProcessor[T]) Queue() Queue[T] {
+	return p.queue
+}
+
+// Handler returns the underlying handler
+func (p *QueueProcessor[T]) Handler() Handler[T] {
+	return p.handler
+}
+
+func (p *QueueProcessor[T]) Checkout(ctx context.Context, workerID string) (T, error) {
+	return p.queue.Checkout(ctx, workerID)
+}
+
+func (p *QueueProcessor[T]) Process(ctx context.Context, task T) (T, error) {
+	return p.handler.Process(ctx, task)
+}
+
+func (p *QueueProcessor[T]) Complete(ctx context.Context, task T, processingTimeMS int) error {
+	return p.queue.Complete(ctx, task, processingTimeMS)
+}
+
+func (p *QueueProcessor[T]) Fail(ctx context.Context, task T, err error) error {
+	return p.queue.Fail(ctx, task, err)
+}
+
+// processorAs returns the processor as I, falling back to the queue, then the handler, behind a
+// QueueProcessor. This lets the pool discover optional capabilities (e.g. Leaser) of wrapped
+// queues and handlers.
+func processorAs[I any, T Task](processor Processor[T]) (I, bool) {
+	if v, ok := any(processor).(I); ok {
+		return v, true
+	}
+	if qp, ok := processor.(interface{ Queue() Queue[T] }); ok {
+		if v, ok := any(qp.Queue()).(I); ok {
+			return v, true
+		}
+	}
+	if hp, ok := processor.(interface{ Handler() Handler[T] }); ok {
+		if v, ok := any(hp.Handler()).(I); ok {
+			return v, true
+		}
+	}
+	var zero I
+	return zero, false
+}
@@ -0,0 +1,145 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// sliceQueue is a minimal workers.Queue with none of the optional capabilities, for tests of
+// what the pool does without them
+type sliceQueue struct {
+	mu       sync.Mutex
+	pending  []TestTask
+	failErrs []error
+}
+
+func (q *sliceQueue) Checkout(ctx context.Context, workerID string) (TestTask, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return TestTask{}, workers.ErrNoWorkAvailable
+	}
+	task := q.pending[0]
+	q.pending = q.pending[1:]
+	return task, nil
+}
+
+func (q *sliceQueue) Complete(ctx context.Context, task TestTask, processingTimeMS int) error {
+	return nil
+}
+
+func (q *sliceQueue) Fail(ctx context.Context, task TestTask, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.failErrs = append(q.failErrs, err)
+	return nil
+}
+
+// enqueue fills a memqueue for a test
+func enqueue(t *testing.T, queue *memqueue.Queue[TestTask], tasks ...TestTask) {
+	t.Helper()
+	for _, task := range tasks {
+		if err := queue.Enqueue(task); err != nil {
+			t.Fatalf("failed to enqueue %s: %v", task.ID, err)
+		}
+	}
+}
+
+func TestQueueProcessor_RoutesOutcomesToQueue(t *testing.T) {
+	queue := memqueue.New[TestTask](memqueue.WithMaxRetries(0))
+	for i := 0; i < 4; i++ {
+		enqueue(t, queue, TestTask{ID: fmt.Sprintf("task-%d", i), ShouldErr: i%2 == 1})
+	}
+
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		if task.ShouldErr {
+			return task, errors.New("handler failed")
+		}
+		return task, nil
+	})
+
+	pool, err := workers.NewWorkerPool("queue-pool", 2, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	if stats := queue.Stats(); stats.Completed != 2 || stats.Dead != 2 || stats.Pending != 0 {
+		t.Errorf("expected 2 completed and 2 failed tasks, got %+v", stats)
+	}
+	for _, entry := range queue.Dead() {
+		if !entry.Task.ShouldErr || !strings.HasSuffix(entry.LastError, "handler failed") {
+			t.Errorf("expected only the failing tasks dead with their error, got %+v", entry)
+		}
+	}
+}
+
+func TestQueueProcessor_LeavesRetriesToRetryingQueue(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []workers.Option
+		want int32
+	}{
+		{"queue retries", nil, 2},
+		{"explicit max retries", []workers.Option{workers.WithMaxRetries(3)}, 6},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The queue checks the task out twice before dead-lettering it
+			queue := memqueue.New[TestTask](memqueue.WithMaxRetries(1))
+			enqueue(t, queue, TestTask{ID: "failing-task"})
+
+			var attempts atomic.Int32
+			handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+				attempts.Add(1)
+				return task, errors.New("handler failed")
+			})
+
+			opts := append([]workers.Option{
+				workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+				workers.WithPollInterval(10 * time.Millisecond),
+				workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+			}, tt.opts...)
+			pool, err := workers.NewWorkerPool("retrying-pool", 1, workers.NewQueueProcessor[TestTask](queue, handler), opts...)
+			if err != nil {
+				t.Fatalf("failed to create pool: %v", err)
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- pool.Start(context.Background())
+			}()
+			time.Sleep(100 * time.Millisecond)
+			pool.Stop()
+			<-done
+
+			if got := attempts.Load(); got != tt.want {
+				t.Errorf("expected %d attempts over the queue's 2 checkouts, got %d", tt.want, got)
+			}
+			if dead := queue.Dead(); len(dead) != 1 || dead[0].RetryCount != 2 {
+				t.Errorf("expected the task dead after 2 failed checkouts, got %+v", dead)
+			}
+		})
+	}
+}
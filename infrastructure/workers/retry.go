@@ -0,0 +1,157 @@
+package workers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrPermanent marks a failure that retrying cannot fix (bad input, validation, missing records...).
+// Wrap errors with Permanent; errors.Is(err, ErrPermanent) reports whether an error is permanent.
+var ErrPermanent = errors.New("permanent failure")
+
+// Retryable can be implemented by errors that know whether they are worth retrying.
+// It takes precedence over ErrPermanent for the error that implements it.
+type Retryable interface {
+	Retryable() bool
+}
+
+// permanentError wraps an error so it matches both ErrPermanent and the original error
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string   { return e.err.Error() }
+func (e *permanentError) Unwrap() []error { return []error{ErrPermanent, e.err} }
+
+// Permanent wraps err so the pool fails the task without retrying it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// RetryAfterError asks the pool to wait at least Delay before the next attempt,
+// e.g. when a downstream answered with a rate limit and a Retry-After header.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.Err, e.Delay)
+}
+
+func (e *RetryAfterError) Unwrap() error { return e.Err }
+
+// RetryAfter wraps err with a hint to wait delay before retrying
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryAfterError{Err: err, Delay: delay}
+}
+
+// IsRetryable reports whether the pool should retry after err
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var retryable Retryable
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	return !errors.Is(err, ErrPermanent)
+}
+
+// retryAfterHint returns the delay requested through RetryAfter, if any
+func retryAfterHint(err error) (time.Duration, bool) {
+	var hint *RetryAfterError
+	if errors.As(err, &hint) && hint.Delay > 0 {
+		return hint.Delay, true
+	}
+	return 0, false
+}
+
+// BackoffPolicy decides how long to wait before a retry. retry is 1 for the first retry, 2 for the second...
+type BackoffPolicy interface {
+	Delay(retry int) time.Duration
+}
+
+// BackoffFunc adapts a plain function to a BackoffPolicy
+type BackoffFunc func(retry int) time.Duration
+
+// Delay calls f(retry)
+func (f BackoffFunc) Delay(retry int) time.Duration {
+	return f(retry)
+}
+
+// ConstantBackoff waits the same delay before every retry
+func ConstantBackoff(delay time.Duration) BackoffPolicy {
+	return BackoffFunc(func(retry int) time.Duration {
+		return delay
+	})
+}
+
+// ExponentialBackoff doubles the delay on every retry starting at initial. A max of 0 means no cap.
+func ExponentialBackoff(initial, max time.Duration) BackoffPolicy {
+	return BackoffFunc(func(retry int) time.Duration {
+		if retry < 1 {
+			retry = 1
+		}
+		delay := initial
+		for i := 1; i < retry; i++ {
+			if delay > math.MaxInt64/2 {
+				delay = math.MaxInt64
+				break
+			}
+			delay *= 2
+		}
+		if max > 0 && delay > max {
+			return max
+		}
+		return delay
+	})
+}
+
+// FullJitter picks a random delay between 0 and what policy would wait, which spreads out
+// retries from many workers failing at once.
+func FullJitter(policy BackoffPolicy) BackoffPolicy {
+	return BackoffFunc(func(retry int) time.Duration {
+		delay := policy.Delay(retry)
+		if delay <= 0 {
+			return 0
+		}
+		return rand.N(delay + 1)
+	})
+}
+
+// Attempt records a failed run of Process
+type Attempt struct {
+	Number    int       `json:"attempt"`
+	StartedAt time.Time `json:"started_at"`
+	FailedAt  time.Time `json:"failed_at"`
+	Error     string    `json:"error"`
+}
+
+// ProcessError is the error handed to Processor.Fail when processing fails. It keeps every
+// attempt of the run so queues can persist the history, e.g. when dead-lettering a task.
+type ProcessError struct {
+	Attempts []Attempt
+	Err      error
+}
+
+func (e *ProcessError) Error() string { return e.Err.Error() }
+func (e *ProcessError) Unwrap() error { return e.Err }
+
+// AttemptsOf returns the attempts recorded in err, or nil if err doesn't carry any
+func AttemptsOf(err error) []Attempt {
+	var processErr *ProcessError
+	if errors.As(err, &processErr) {
+		return processErr.Attempts
+	}
+	return nil
+}
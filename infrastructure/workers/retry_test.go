@@ -0,0 +1,219 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+// notRetryable is an error that opts out of retries through the Retryable interface
+type notRetryable struct{}
+
+func (notRetryable) Error() string   { return "not retryable" }
+func (notRetryable) Retryable() bool { return false }
+
+func TestIsRetryable(t *testing.T) {
+	base := errors.New("boom")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", base, true},
+		{"permanent", workers.Permanent(base), false},
+		{"wrapped permanent", fmt.Errorf("validating: %w", workers.Permanent(base)), false},
+		{"retryable interface", notRetryable{}, false},
+		{"retry after", workers.RetryAfter(base, time.Second), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workers.IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !errors.Is(workers.Permanent(base), base) {
+		t.Error("Permanent should keep the original error in the chain")
+	}
+}
+
+func TestBackoffPolicies(t *testing.T) {
+	constant := workers.ConstantBackoff(50 * time.Millisecond)
+	for retry := 1; retry <= 3; retry++ {
+		if got := constant.Delay(retry); got != 50*time.Millisecond {
+			t.Errorf("constant retry %d: got %v", retry, got)
+		}
+	}
+
+	exponential := workers.ExponentialBackoff(100*time.Millisecond, time.Second)
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := exponential.Delay(i + 1); got != w {
+			t.Errorf("exponential retry %d: got %v, want %v", i+1, got, w)
+		}
+	}
+	if got := workers.ExponentialBackoff(time.Second, 0).Delay(100); got <= 0 {
+		t.Errorf("uncapped exponential backoff overflowed: %v", got)
+	}
+
+	jitter := workers.FullJitter(workers.ConstantBackoff(100 * time.Millisecond))
+	for i := 0; i < 100; i++ {
+		if got := jitter.Delay(1); got < 0 || got > 100*time.Millisecond {
+			t.Fatalf("jittered delay out of range: %v", got)
+		}
+	}
+}
+
+func TestWorkerPool_RetryClassification(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int32
+	}{
+		{"permanent error is not retried", workers.Permanent(errors.New("invalid payload")), 1},
+		{"transient error is retried", errors.New("connection reset"), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewStubProcessor()
+			processor.AddTask(TestTask{ID: "classified-task"})
+
+			var attempts atomic.Int32
+			processor.processFunc = func(ctx context.Context, task TestTask) (TestTask, error) {
+				attempts.Add(1)
+				return task, tt.err
+			}
+
+			pool, err := workers.NewWorkerPool("retry-pool", 1, processor,
+				workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+				workers.WithPollInterval(10*time.Millisecond),
+				workers.WithMaxRetries(3),
+				workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+			)
+			if err != nil {
+				t.Fatalf("failed to create pool: %v", err)
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- pool.Start(context.Background())
+			}()
+
+			time.Sleep(150 * time.Millisecond)
+			pool.Stop()
+			<-done
+
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+			if processor.GetFailCount() != 1 {
+				t.Errorf("expected the task to fail once, got %d", processor.GetFailCount())
+			}
+		})
+	}
+}
+
+func TestWorkerPool_RetryAfterHint(t *testing.T) {
+	processor := NewStubProcessor()
+	processor.AddTask(TestTask{ID: "rate-limited-task"})
+
+	var attempts atomic.Int32
+	var firstAttempt, secondAttempt atomic.Int64
+	processor.processFunc = func(ctx context.Context, task TestTask) (TestTask, error) {
+		if attempts.Add(1) == 1 {
+			firstAttempt.Store(time.Now().UnixNano())
+			return task, workers.RetryAfter(errors.New("429 too many requests"), 200*time.Millisecond)
+		}
+		secondAttempt.Store(time.Now().UnixNano())
+		return task, nil
+	}
+
+	pool, err := workers.NewWorkerPool("retry-after-pool", 1, processor,
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(2),
+		workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	time.Sleep(400 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	if processor.GetCompleteCount() != 1 {
+		t.Fatalf("expected the task to complete after the hinted delay, got %d completes", processor.GetCompleteCount())
+	}
+	if waited := time.Duration(secondAttempt.Load() - firstAttempt.Load()); waited < 200*time.Millisecond {
+		t.Errorf("expected the retry to wait for the hint, waited %v", waited)
+	}
+}
+
+func TestWorkerPool_FailCarriesAttemptHistory(t *testing.T) {
+	queue := &sliceQueue{}
+	queue.pending = []TestTask{{ID: "doomed-task"}}
+
+	var calls atomic.Int32
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		return task, fmt.Errorf("attempt %d failed", calls.Add(1))
+	})
+
+	pool, err := workers.NewWorkerPool("history-pool", 1, workers.NewQueueProcessor(queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithMaxRetries(3),
+		workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	time.Sleep(150 * time.Millisecond)
+	pool.Stop()
+	<-done
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.failErrs) != 1 {
+		t.Fatalf("expected 1 failed task, got %d", len(queue.failErrs))
+	}
+
+	attempts := workers.AttemptsOf(queue.failErrs[0])
+	if len(attempts) != 3 {
+		t.Fatalf("expected 3 recorded attempts, got %d", len(attempts))
+	}
+	for i, attempt := range attempts {
+		if attempt.Number != i+1 {
+			t.Errorf("attempt %d: got number %d", i+1, attempt.Number)
+		}
+		if want := fmt.Sprintf("attempt %d failed", i+1); attempt.Error != want {
+			t.Errorf("attempt %d: got error %q, want %q", i+1, attempt.Error, want)
+		}
+		if attempt.StartedAt.IsZero() || attempt.FailedAt.Before(attempt.StartedAt) {
+			t.Errorf("attempt %d: bad timestamps %v - %v", i+1, attempt.StartedAt, attempt.FailedAt)
+		}
+	}
+}
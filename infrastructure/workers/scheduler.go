@@ -0,0 +1,285 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jrazmi/envoker/sdk/environment"
+)
+
+// Schedule describes a recurring task
+type Schedule struct {
+	// Name identifies the schedule. Runs are keyed by name and time, so it must be stable
+	// across deploys and unique among schedules.
+	Name string
+
+	// Spec is a cron expression, see ParseCron
+	Spec string
+}
+
+// ScheduledRun is one activation of a schedule
+type ScheduledRun struct {
+	Schedule string
+	At       time.Time // UTC
+}
+
+// Key identifies the run. Every replica computes the same key for the same activation, so an
+// Enqueuer that only creates a run whose key doesn't exist yet gets each run exactly once.
+func (r ScheduledRun) Key() string {
+	return r.Schedule + "@" + r.At.UTC().Format(time.RFC3339)
+}
+
+// Enqueuer materializes scheduled runs as tasks. Enqueue must be idempotent on run.Key(),
+// e.g. by using it as the task's primary key, and reports whether the run was created.
+// Tasks should not become available to workers before run.At.
+type Enqueuer interface {
+	Enqueue(ctx context.Context, run ScheduledRun) (bool, error)
+}
+
+// SchedulerOptions represents the exportable scheduler configuration
+type SchedulerOptions struct {
+	// Schedules are name=spec entries, e.g. "cleanup=@hourly;report=CRON_TZ=Europe/Berlin 0 6 * * 1-5"
+	Schedules     []string      `env:"SCHEDULER_SCHEDULES" separator:";"`
+	Timezone      string        `env:"SCHEDULER_TIMEZONE" default:"UTC"`
+	PollInterval  time.Duration `env:"SCHEDULER_POLL_INTERVAL" default:"15s"`
+	MisfireWindow time.Duration `env:"SCHEDULER_MISFIRE_WINDOW" default:"1m"`
+}
+
+// schedulerOptions holds the internal runtime configuration
+type schedulerOptions struct {
+	schedules     []Schedule
+	location      *time.Location
+	pollInterval  time.Duration
+	misfireWindow time.Duration
+
+	logger *slog.Logger
+}
+
+// SchedulerOption is a functional option for configuring the scheduler
+type SchedulerOption func(*schedulerOptions)
+
+// WithSchedule registers a recurring task
+func WithSchedule(name string, spec string) SchedulerOption {
+	return func(o *schedulerOptions) {
+		o.schedules = append(o.schedules, Schedule{Name: name, Spec: spec})
+	}
+}
+
+// WithSchedulerLogger sets the logger
+func WithSchedulerLogger(logger *slog.Logger) SchedulerOption {
+	return func(o *schedulerOptions) {
+		o.logger = logger
+	}
+}
+
+// WithSchedulerTimezone sets the timezone for schedules that don't set CRON_TZ
+func WithSchedulerTimezone(loc *time.Location) SchedulerOption {
+	return func(o *schedulerOptions) {
+		o.location = loc
+	}
+}
+
+// WithSchedulerPollInterval sets how often schedules are evaluated. Runs due within the next
+// interval are enqueued ahead of time so workers can pick them up on the dot.
+func WithSchedulerPollInterval(interval time.Duration) SchedulerOption {
+	return func(o *schedulerOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// WithMisfireWindow sets how late a run may still be enqueued, e.g. after a restart.
+// Older runs are skipped.
+func WithMisfireWindow(window time.Duration) SchedulerOption {
+	return func(o *schedulerOptions) {
+		o.misfireWindow = window
+	}
+}
+
+// scheduleEntry is a parsed schedule and the next run it will enqueue
+type scheduleEntry struct {
+	name     string
+	schedule CronSchedule
+	next     time.Time
+}
+
+// Scheduler materializes recurring tasks from cron schedules. Run it in every replica: runs are
+// keyed deterministically, so the Enqueuer creates each of them once no matter how many
+// schedulers race for it. Attach it to a WorkerPool with WithScheduler or call Run directly.
+type Scheduler struct {
+	enqueuer      Enqueuer
+	entries       []*scheduleEntry
+	pollInterval  time.Duration
+	misfireWindow time.Duration
+	log           *slog.Logger
+}
+
+// NewSchedulerFromEnv creates a scheduler using environment variables
+func NewSchedulerFromEnv(prefix string, enqueuer Enqueuer, opts ...SchedulerOption) (*Scheduler, error) {
+	var cfg SchedulerOptions
+	if err := environment.ParseEnvTags(prefix, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing scheduler config: %w", err)
+	}
+
+	return newScheduler(enqueuer, cfg, opts...)
+}
+
+// NewScheduler creates a scheduler for the schedules registered with WithSchedule
+func NewScheduler(enqueuer Enqueuer, opts ...SchedulerOption) (*Scheduler, error) {
+	cfg := SchedulerOptions{
+		Timezone:      "UTC",
+		PollInterval:  15 * time.Second,
+		MisfireWindow: 1 * time.Minute,
+	}
+
+	scheduler, err := newScheduler(enqueuer, cfg, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler setup failure: %w", err)
+	}
+	return scheduler, nil
+}
+
+// newScheduler creates a scheduler with given config and applies options
+func newScheduler(enqueuer Enqueuer, cfg SchedulerOptions, opts ...SchedulerOption) (*Scheduler, error) {
+	if enqueuer == nil {
+		return nil, errors.New("enqueuer is required")
+	}
+
+	internalOpts := &schedulerOptions{
+		pollInterval:  cfg.PollInterval,
+		misfireWindow: cfg.MisfireWindow,
+	}
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+		}
+		internalOpts.location = loc
+	}
+
+	for _, entry := range cfg.Schedules {
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid schedule %q, expected name=spec", entry)
+		}
+		internalOpts.schedules = append(internalOpts.schedules, Schedule{Name: strings.TrimSpace(name), Spec: spec})
+	}
+
+	// Apply functional options to override config
+	for _, opt := range opts {
+		opt(internalOpts)
+	}
+
+	// Ensure reasonable defaults
+	if internalOpts.logger == nil {
+		internalOpts.logger = slog.Default()
+	}
+	if internalOpts.location == nil {
+		internalOpts.location = time.UTC
+	}
+	if internalOpts.pollInterval <= 0 {
+		internalOpts.pollInterval = 15 * time.Second
+	}
+	if internalOpts.misfireWindow < 0 {
+		internalOpts.misfireWindow = 0
+	}
+
+	scheduler := &Scheduler{
+		enqueuer:      enqueuer,
+		pollInterval:  internalOpts.pollInterval,
+		misfireWindow: internalOpts.misfireWindow,
+		log:           internalOpts.logger,
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range internalOpts.schedules {
+		if seen[s.Name] {
+			return nil, fmt.Errorf("duplicate schedule %q", s.Name)
+		}
+		seen[s.Name] = true
+
+		parsed, err := ParseCron(s.Spec, internalOpts.location)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", s.Name, err)
+		}
+		scheduler.entries = append(scheduler.entries, &scheduleEntry{name: s.Name, schedule: parsed})
+	}
+
+	return scheduler, nil
+}
+
+// Run enqueues scheduled runs until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.entries) == 0 {
+		s.log.InfoContext(ctx, "scheduler has no schedules, not starting")
+		return nil
+	}
+
+	s.log.InfoContext(ctx, "starting scheduler",
+		"schedules", len(s.entries),
+		"poll_interval", s.pollInterval)
+
+	// Runs missed within the misfire window (e.g. during a restart) are still enqueued
+	start := time.Now().Add(-s.misfireWindow)
+	for _, entry := range s.entries {
+		entry.next = entry.schedule.Next(start)
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			s.log.InfoContext(context.Background(), "scheduler stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick enqueues every run due before the next tick
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	horizon := now.Add(s.pollInterval)
+	oldest := now.Add(-s.misfireWindow)
+
+	for _, entry := range s.entries {
+		for !entry.next.IsZero() && !entry.next.After(horizon) {
+			if ctx.Err() != nil {
+				return
+			}
+
+			run := ScheduledRun{Schedule: entry.name, At: entry.next.UTC()}
+			if run.At.Before(oldest) {
+				s.log.WarnContext(ctx, "skipping misfired scheduled run",
+					"schedule", run.Schedule,
+					"run_at", run.At)
+				entry.next = entry.schedule.Next(entry.next)
+				continue
+			}
+
+			created, err := s.enqueuer.Enqueue(ctx, run)
+			if err != nil {
+				// Leave entry.next alone so the run is retried on the next tick
+				s.log.ErrorContext(ctx, "failed to enqueue scheduled run",
+					"schedule", run.Schedule,
+					"run_at", run.At,
+					"error", err)
+				break
+			}
+			if created {
+				s.log.InfoContext(ctx, "enqueued scheduled run",
+					"schedule", run.Schedule,
+					"run_at", run.At,
+					"key", run.Key())
+			}
+			entry.next = entry.schedule.Next(entry.next)
+		}
+	}
+}
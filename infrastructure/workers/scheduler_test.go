@@ -0,0 +1,158 @@
+package workers_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+// dedupeEnqueuer is a workers.Enqueuer that, like a primary key, only accepts each run once
+type dedupeEnqueuer struct {
+	mu      sync.Mutex
+	runs    map[string]workers.ScheduledRun
+	created int
+	skipped int
+}
+
+func newDedupeEnqueuer() *dedupeEnqueuer {
+	return &dedupeEnqueuer{runs: make(map[string]workers.ScheduledRun)}
+}
+
+func (e *dedupeEnqueuer) Enqueue(ctx context.Context, run workers.ScheduledRun) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.runs[run.Key()]; exists {
+		e.skipped++
+		return false, nil
+	}
+	e.runs[run.Key()] = run
+	e.created++
+	return true, nil
+}
+
+func TestScheduler_ExactlyOnceAcrossReplicas(t *testing.T) {
+	enqueuer := newDedupeEnqueuer()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		// Every minute with a 3 minute misfire window: each replica sees the same 3 past runs
+		scheduler, err := workers.NewScheduler(enqueuer,
+			workers.WithSchedule("heartbeat", "* * * * *"),
+			workers.WithSchedulerLogger(logger),
+			workers.WithSchedulerPollInterval(10*time.Millisecond),
+			workers.WithMisfireWindow(3*time.Minute),
+		)
+		if err != nil {
+			t.Fatalf("failed to create scheduler: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scheduler.Run(ctx)
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	enqueuer.mu.Lock()
+	defer enqueuer.mu.Unlock()
+	// A 4th run appears if a minute boundary passes while the test runs
+	if enqueuer.created < 3 || enqueuer.created > 4 || enqueuer.created != len(enqueuer.runs) {
+		t.Errorf("expected each of the 3-4 due runs to be created once, got %d creates for %d runs",
+			enqueuer.created, len(enqueuer.runs))
+	}
+	if enqueuer.skipped < 6 {
+		t.Errorf("expected the other replicas to hit existing runs, got %d skips", enqueuer.skipped)
+	}
+	for key, run := range enqueuer.runs {
+		if run.At.Second() != 0 || run.At.Location() != time.UTC {
+			t.Errorf("run %s should be on a whole minute in UTC, got %v", key, run.At)
+		}
+	}
+}
+
+func TestNewScheduler_Validation(t *testing.T) {
+	enqueuer := newDedupeEnqueuer()
+
+	if _, err := workers.NewScheduler(nil); err == nil {
+		t.Error("expected an error without an enqueuer")
+	}
+	if _, err := workers.NewScheduler(enqueuer, workers.WithSchedule("bad", "not a cron")); err == nil {
+		t.Error("expected an error for an invalid spec")
+	}
+	if _, err := workers.NewScheduler(enqueuer,
+		workers.WithSchedule("twice", "@hourly"),
+		workers.WithSchedule("twice", "@daily"),
+	); err == nil {
+		t.Error("expected an error for duplicate schedule names")
+	}
+}
+
+func TestNewSchedulerFromEnv(t *testing.T) {
+	t.Setenv("TEST_SCHEDULER_SCHEDULES", "cleanup=@hourly;report=CRON_TZ=Europe/Berlin 0 6 * * 1-5")
+	t.Setenv("TEST_SCHEDULER_POLL_INTERVAL", "10ms")
+
+	scheduler, err := workers.NewSchedulerFromEnv("TEST", newDedupeEnqueuer())
+	if err != nil {
+		t.Fatalf("failed to create scheduler from env: %v", err)
+	}
+	if scheduler == nil {
+		t.Fatal("expected a scheduler")
+	}
+
+	t.Setenv("TEST_SCHEDULER_SCHEDULES", "missing-spec")
+	if _, err := workers.NewSchedulerFromEnv("TEST", newDedupeEnqueuer()); err == nil {
+		t.Error("expected an error for an entry without a spec")
+	}
+}
+
+func TestWorkerPool_WithScheduler(t *testing.T) {
+	enqueuer := newDedupeEnqueuer()
+	scheduler, err := workers.NewScheduler(enqueuer,
+		workers.WithSchedule("every-minute", "* * * * *"),
+		workers.WithSchedulerLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithSchedulerPollInterval(10*time.Millisecond),
+		workers.WithMisfireWindow(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("failed to create scheduler: %v", err)
+	}
+
+	pool, err := workers.NewWorkerPool("scheduled-pool", 1, NewStubProcessor(),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithScheduler(scheduler),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	pool.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pool did not stop its scheduler")
+	}
+
+	enqueuer.mu.Lock()
+	defer enqueuer.mu.Unlock()
+	if enqueuer.created == 0 {
+		t.Error("scheduler attached to the pool never enqueued a run")
+	}
+}
@@ -0,0 +1,269 @@
+package workers
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WorkerState is what a worker is doing right now
+type WorkerState string
+
+const (
+	WorkerIdle       WorkerState = "idle"       // Waiting for the next poll
+	WorkerProcessing WorkerState = "processing" // Running a task
+	WorkerPaused     WorkerState = "paused"     // Waiting for the pool to be resumed
+	WorkerStuck      WorkerState = "stuck"      // Waiting for a timed out task to return, see WithTimeoutGrace
+)
+
+// WorkerStatus is a point-in-time view of a worker
+type WorkerStatus struct {
+	WorkerID       string          `json:"worker_id"`
+	State          WorkerState     `json:"state"`
+	StartedAt      time.Time       `json:"started_at"`
+	TaskID         string          `json:"task_id,omitempty"`
+	TaskStartedAt  *time.Time      `json:"task_started_at,omitempty"`
+	Progress       *ProgressReport `json:"progress,omitempty"` // Once the task has reported progress
+	PollIntervalMs int64           `json:"poll_interval_ms"`   // Wait before the next poll, idle or active
+}
+
+// FailureRecord is a task that failed in the pool, see WorkerPool.RecentFailures
+type FailureRecord struct {
+	TaskID     string    `json:"task_id"`
+	TaskType   string    `json:"task_type,omitempty"`
+	WorkerID   string    `json:"worker_id"`
+	Error      string    `json:"error"`
+	Attempts   []Attempt `json:"attempts,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// WithRecentFailures sets how many failed tasks the pool keeps for RecentFailures (default 20)
+func WithRecentFailures(n int) Option {
+	return func(o *options) {
+		o.recentFailures = n
+	}
+}
+
+// ================================================================================
+// Worker state
+// ================================================================================
+
+// workerHandle lets Resize retire a single worker and tracks what the worker is doing
+type workerHandle struct {
+	id        string
+	seq       int
+	quit      chan struct{}
+	startedAt time.Time
+
+	mu            sync.Mutex
+	state         WorkerState
+	taskID        string
+	taskStartedAt time.Time
+	progress      *Progress
+	pollInterval  time.Duration
+	stuck         chan struct{} // Closed when the timed out task the worker is stuck on returns
+}
+
+func (h *workerHandle) setState(state WorkerState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = state
+}
+
+func (h *workerHandle) setPollInterval(interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pollInterval = interval
+}
+
+// startTask marks the worker as processing taskID
+func (h *workerHandle) startTask(taskID string, progress *Progress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = WorkerProcessing
+	h.taskID = taskID
+	h.taskStartedAt = time.Now()
+	h.progress = progress
+}
+
+// finishTask marks the worker as idle again, or stuck if its task didn't return
+func (h *workerHandle) finishTask() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = WorkerIdle
+	if h.stuck != nil {
+		h.state = WorkerStuck
+	}
+	h.taskID = ""
+	h.taskStartedAt = time.Time{}
+	h.progress = nil
+}
+
+// setStuck marks the worker as stuck until returned is closed
+func (h *workerHandle) setStuck(returned chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = WorkerStuck
+	h.stuck = returned
+}
+
+// stuckOn returns the channel closed when the worker is no longer stuck, nil when it isn't
+func (h *workerHandle) stuckOn() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stuck
+}
+
+// unstick marks the worker as idle again once its stuck task returned
+func (h *workerHandle) unstick() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = WorkerIdle
+	h.stuck = nil
+}
+
+func (h *workerHandle) status() WorkerStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := WorkerStatus{
+		WorkerID:       h.id,
+		State:          h.state,
+		StartedAt:      h.startedAt,
+		TaskID:         h.taskID,
+		PollIntervalMs: h.pollInterval.Milliseconds(),
+	}
+	if !h.taskStartedAt.IsZero() {
+		taskStartedAt := h.taskStartedAt
+		status.TaskStartedAt = &taskStartedAt
+	}
+	if report := h.progress.Snapshot(); !report.UpdatedAt.IsZero() {
+		status.Progress = &report
+	}
+	return status
+}
+
+// handle returns the handle of a running worker, nil when it has exited
+func (wp *WorkerPool[T]) handle(workerID string) *workerHandle {
+	if h, ok := wp.handles.Load(workerID); ok {
+		return h.(*workerHandle)
+	}
+	return nil
+}
+
+// Workers returns the state of every running worker, in the order they were started. Workers
+// retired by Resize are listed until their current task is done.
+func (wp *WorkerPool[T]) Workers() []WorkerStatus {
+	var handles []*workerHandle
+	wp.handles.Range(func(_, h any) bool {
+		handles = append(handles, h.(*workerHandle))
+		return true
+	})
+	slices.SortFunc(handles, func(a, b *workerHandle) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	statuses := make([]WorkerStatus, 0, len(handles))
+	for _, h := range handles {
+		statuses = append(statuses, h.status())
+	}
+	return statuses
+}
+
+// ================================================================================
+// Pause and resume
+// ================================================================================
+
+// Pause stops the workers from checking out tasks until Resume is called. Tasks already being
+// processed run to completion. A paused pool stays paused across Stop and Start.
+func (wp *WorkerPool[T]) Pause() {
+	wp.pauseMutex.Lock()
+	defer wp.pauseMutex.Unlock()
+	if wp.resumed != nil {
+		return
+	}
+	wp.resumed = make(chan struct{})
+	wp.log.Info("worker pool paused", "name", wp.name)
+}
+
+// Resume lets the workers of a paused pool check out tasks again
+func (wp *WorkerPool[T]) Resume() {
+	wp.pauseMutex.Lock()
+	defer wp.pauseMutex.Unlock()
+	if wp.resumed == nil {
+		return
+	}
+	close(wp.resumed)
+	wp.resumed = nil
+	wp.log.Info("worker pool resumed", "name", wp.name)
+}
+
+// Paused reports whether the pool is paused
+func (wp *WorkerPool[T]) Paused() bool {
+	return wp.pausedUntil() != nil
+}
+
+// pausedUntil returns a channel that is closed on Resume, nil when the pool isn't paused
+func (wp *WorkerPool[T]) pausedUntil() <-chan struct{} {
+	wp.pauseMutex.Lock()
+	defer wp.pauseMutex.Unlock()
+	if wp.resumed == nil {
+		return nil
+	}
+	return wp.resumed
+}
+
+// ================================================================================
+// Recent failures
+// ================================================================================
+
+// failureLog keeps the last failures in a ring
+type failureLog struct {
+	mu      sync.Mutex
+	records []FailureRecord
+	next    int
+	full    bool
+}
+
+func newFailureLog(size int) *failureLog {
+	return &failureLog{records: make([]FailureRecord, size)}
+}
+
+func (l *failureLog) add(record FailureRecord) {
+	if len(l.records) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records[l.next] = record
+	l.next = (l.next + 1) % len(l.records)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// recent returns up to n records, newest first; n <= 0 returns all of them
+func (l *failureLog) recent(n int) []FailureRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	count := l.next
+	if l.full {
+		count = len(l.records)
+	}
+	if n > 0 {
+		count = min(count, n)
+	}
+
+	records := make([]FailureRecord, 0, count)
+	for i := 1; i <= count; i++ {
+		records = append(records, l.records[(l.next-i+len(l.records))%len(l.records)])
+	}
+	return records
+}
+
+// RecentFailures returns up to n of the last tasks that failed, newest first; n <= 0 returns
+// every failure kept (see WithRecentFailures).
+func (wp *WorkerPool[T]) RecentFailures(n int) []FailureRecord {
+	return wp.failures.recent(n)
+}
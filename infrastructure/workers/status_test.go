@@ -0,0 +1,146 @@
+package workers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+func startStatusPool(t *testing.T, processor *StubProcessor, opts ...workers.Option) *workers.WorkerPool[TestTask] {
+	t.Helper()
+	opts = append([]workers.Option{
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10 * time.Millisecond),
+		workers.WithIdleInterval(10 * time.Millisecond),
+		workers.WithMaxRetries(1),
+	}, opts...)
+	pool, err := workers.NewWorkerPool("status-pool", 1, processor, opts...)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	t.Cleanup(func() {
+		pool.Stop()
+		<-done
+	})
+	return pool
+}
+
+func TestWorkerPool_WorkerStatus(t *testing.T) {
+	processor := NewStubProcessor()
+	processor.AddTask(TestTask{ID: "slow-task", Delay: 300 * time.Millisecond})
+	pool := startStatusPool(t, processor)
+
+	eventually(t, time.Second, func() bool {
+		statuses := pool.Workers()
+		return len(statuses) == 1 && statuses[0].State == workers.WorkerProcessing
+	}, "worker never started processing")
+
+	status := pool.Workers()[0]
+	if status.WorkerID != "status-pool-worker-1" {
+		t.Errorf("expected worker status-pool-worker-1, got %s", status.WorkerID)
+	}
+	if status.TaskID != "slow-task" {
+		t.Errorf("expected task slow-task, got %q", status.TaskID)
+	}
+	if status.TaskStartedAt == nil || status.StartedAt.After(*status.TaskStartedAt) {
+		t.Errorf("expected the task to start after the worker, got %v and %v", status.StartedAt, status.TaskStartedAt)
+	}
+
+	eventually(t, time.Second, func() bool {
+		return pool.Workers()[0].State == workers.WorkerIdle
+	}, "worker never went back to idle")
+
+	status = pool.Workers()[0]
+	if status.TaskID != "" || status.TaskStartedAt != nil {
+		t.Errorf("expected no task on an idle worker, got %q", status.TaskID)
+	}
+	if status.PollIntervalMs != 10 {
+		t.Errorf("expected a 10ms poll interval, got %dms", status.PollIntervalMs)
+	}
+	if encoded, _ := json.Marshal(status); !strings.Contains(string(encoded), `"poll_interval_ms":10}`) {
+		t.Errorf("expected the poll interval in milliseconds, got %s", encoded)
+	}
+}
+
+func TestWorkerPool_RecentFailures(t *testing.T) {
+	processor := NewStubProcessor()
+	for _, id := range []string{"fail-1", "fail-2", "fail-3"} {
+		processor.AddTask(TestTask{ID: id, ShouldErr: true})
+	}
+	pool := startStatusPool(t, processor, workers.WithRecentFailures(2))
+
+	eventually(t, time.Second, func() bool {
+		return processor.GetFailCount() == 3
+	}, "tasks never failed")
+
+	failures := pool.RecentFailures(0)
+	if len(failures) != 2 {
+		t.Fatalf("expected the last 2 failures, got %d", len(failures))
+	}
+	if failures[0].TaskID != "fail-3" || failures[1].TaskID != "fail-2" {
+		t.Errorf("expected fail-3 then fail-2, got %s then %s", failures[0].TaskID, failures[1].TaskID)
+	}
+	if failures[0].WorkerID != "status-pool-worker-1" || failures[0].Error == "" || len(failures[0].Attempts) != 1 {
+		t.Errorf("unexpected failure record %+v", failures[0])
+	}
+
+	if limited := pool.RecentFailures(1); len(limited) != 1 || limited[0].TaskID != "fail-3" {
+		t.Errorf("expected only fail-3, got %+v", limited)
+	}
+}
+
+func TestWorkerPool_PauseResume(t *testing.T) {
+	processor := NewStubProcessor()
+	for _, id := range []string{"task-1", "task-2"} {
+		processor.AddTask(TestTask{ID: id})
+	}
+
+	pool, err := workers.NewWorkerPool("status-pool", 1, processor,
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	pool.Pause()
+	if !pool.Paused() {
+		t.Fatal("expected the pool to be paused")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	defer func() {
+		pool.Stop()
+		<-done
+	}()
+
+	eventually(t, time.Second, func() bool {
+		statuses := pool.Workers()
+		return len(statuses) == 1 && statuses[0].State == workers.WorkerPaused
+	}, "worker never paused")
+	time.Sleep(50 * time.Millisecond)
+	if processor.GetCheckoutCount() != 0 {
+		t.Errorf("expected no checkouts while paused, got %d", processor.GetCheckoutCount())
+	}
+
+	pool.Resume()
+	eventually(t, time.Second, func() bool {
+		return processor.GetCompleteCount() == 2
+	}, "tasks never completed after resume")
+	if pool.Paused() {
+		t.Error("expected the pool to be resumed")
+	}
+}
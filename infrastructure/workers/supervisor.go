@@ -0,0 +1,331 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrShutdownTimeout is returned by Supervisor.Run when pools are still running at the shutdown deadline
+var ErrShutdownTimeout = errors.New("shutdown deadline exceeded")
+
+// shutdownReleaseWait is how long Run waits past the shutdown deadline for pools to release or fail
+// the tasks they abandon
+const shutdownReleaseWait = 5 * time.Second
+
+// Pool is a worker pool as seen by the Supervisor. *WorkerPool[T] implements it for any T.
+type Pool interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop() DrainReport
+	StopContext(ctx context.Context) DrainReport
+	GetMetrics() MetricsSnapshot
+}
+
+// RestartPolicy decides whether the supervisor restarts a pool after its Start returns
+type RestartPolicy int
+
+const (
+	// RestartNever leaves a stopped pool stopped
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts a pool that stopped on a critical error
+	RestartOnFailure
+	// RestartAlways restarts a pool whenever it stops, unless the supervisor is shutting down
+	RestartAlways
+)
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartNever:
+		return "never"
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartAlways:
+		return "always"
+	default:
+		return fmt.Sprintf("RestartPolicy(%d)", int(p))
+	}
+}
+
+// supervisorOptions holds the internal runtime configuration
+type supervisorOptions struct {
+	restartPolicy   RestartPolicy
+	restartBackoff  BackoffPolicy
+	maxRestarts     int
+	shutdownTimeout time.Duration
+
+	logger *slog.Logger
+}
+
+// SupervisorOption is a functional option for configuring the supervisor
+type SupervisorOption func(*supervisorOptions)
+
+// WithRestartPolicy sets when pools are restarted (default RestartOnFailure)
+func WithRestartPolicy(policy RestartPolicy) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.restartPolicy = policy
+	}
+}
+
+// WithRestartBackoff sets how long to wait before restarting a pool
+func WithRestartBackoff(policy BackoffPolicy) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.restartBackoff = policy
+	}
+}
+
+// WithMaxRestarts caps the restarts of each pool, 0 means unlimited
+func WithMaxRestarts(maxRestarts int) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.maxRestarts = maxRestarts
+	}
+}
+
+// WithShutdownTimeout sets how long Run waits for all pools to stop once shutdown begins
+func WithShutdownTimeout(timeout time.Duration) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// WithSupervisorLogger sets the logger
+func WithSupervisorLogger(logger *slog.Logger) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.logger = logger
+	}
+}
+
+// supervisedPool tracks one pool and how it has been doing
+type supervisedPool struct {
+	pool     Pool
+	mu       sync.Mutex
+	restarts int
+	lastErr  error
+}
+
+// PoolStatus describes a supervised pool
+type PoolStatus struct {
+	Name      string          `json:"name"`
+	Restarts  int             `json:"restarts"`
+	LastError string          `json:"last_error,omitempty"`
+	Metrics   MetricsSnapshot `json:"metrics"`
+}
+
+// Supervisor runs several worker pools, of any task type, as one unit. It watches each pool's
+// critical errors (the error returned by Start), restarts pools according to its restart policy
+// and stops them all together on shutdown.
+type Supervisor struct {
+	pools []*supervisedPool
+
+	restartPolicy   RestartPolicy
+	restartBackoff  BackoffPolicy
+	maxRestarts     int
+	shutdownTimeout time.Duration
+	log             *slog.Logger
+}
+
+// NewSupervisor creates a supervisor for the given pools
+func NewSupervisor(pools []Pool, opts ...SupervisorOption) (*Supervisor, error) {
+	internalOpts := &supervisorOptions{
+		restartPolicy:   RestartOnFailure,
+		shutdownTimeout: 30 * time.Second,
+	}
+
+	// Apply functional options
+	for _, opt := range opts {
+		opt(internalOpts)
+	}
+
+	// Ensure reasonable defaults
+	if internalOpts.logger == nil {
+		internalOpts.logger = slog.Default()
+	}
+	if internalOpts.restartBackoff == nil {
+		internalOpts.restartBackoff = FullJitter(ExponentialBackoff(1*time.Second, 1*time.Minute))
+	}
+	if internalOpts.shutdownTimeout <= 0 {
+		internalOpts.shutdownTimeout = 30 * time.Second
+	}
+
+	supervisor := &Supervisor{
+		restartPolicy:   internalOpts.restartPolicy,
+		restartBackoff:  internalOpts.restartBackoff,
+		maxRestarts:     internalOpts.maxRestarts,
+		shutdownTimeout: internalOpts.shutdownTimeout,
+		log:             internalOpts.logger,
+	}
+
+	names := make(map[string]bool)
+	for _, pool := range pools {
+		if pool == nil {
+			return nil, errors.New("nil pool")
+		}
+		if names[pool.Name()] {
+			return nil, fmt.Errorf("duplicate pool name %q", pool.Name())
+		}
+		names[pool.Name()] = true
+		supervisor.pools = append(supervisor.pools, &supervisedPool{pool: pool})
+	}
+
+	return supervisor, nil
+}
+
+// Run starts every pool and blocks until ctx is cancelled or no pool is left running. On
+// cancellation all pools are stopped and drained together, within the shutdown timeout. Tasks still
+// in flight at the deadline are abandoned, and Run returns ErrShutdownTimeout. Otherwise it returns the errors of pools that
+// stopped for good on a critical error, joined.
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.log.InfoContext(ctx, "starting supervisor",
+		"pools", len(s.pools),
+		"restart_policy", s.restartPolicy)
+
+	var wg sync.WaitGroup
+	for _, sp := range s.pools {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.supervise(ctx, sp)
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		s.log.InfoContext(context.Background(), "supervisor shutting down",
+			"pools", len(s.pools),
+			"timeout", s.shutdownTimeout)
+
+		// Pools drain concurrently so one slow pool doesn't eat into the others' deadline. They
+		// abandon their in-flight tasks once the deadline passes.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+		for _, sp := range s.pools {
+			go func() {
+				report := sp.pool.StopContext(shutdownCtx)
+				s.log.InfoContext(context.Background(), "pool stopped",
+					"pool", sp.pool.Name(),
+					"drained", report.Drained,
+					"abandoned", report.Abandoned)
+			}()
+		}
+
+		select {
+		case <-finished:
+		case <-shutdownCtx.Done():
+			s.log.ErrorContext(context.Background(), "pools did not stop before the shutdown deadline",
+				"timeout", s.shutdownTimeout)
+			// Give the pools a moment to hand back the tasks they abandoned
+			select {
+			case <-finished:
+			case <-time.After(shutdownReleaseWait):
+			}
+			return ErrShutdownTimeout
+		}
+	}
+
+	s.log.InfoContext(context.Background(), "supervisor stopped")
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	var errs []error
+	for _, sp := range s.pools {
+		sp.mu.Lock()
+		if sp.lastErr != nil {
+			errs = append(errs, fmt.Errorf("pool %s: %w", sp.pool.Name(), sp.lastErr))
+		}
+		sp.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// supervise runs a pool, restarting it per the restart policy until ctx is cancelled
+func (s *Supervisor) supervise(ctx context.Context, sp *supervisedPool) {
+	name := sp.pool.Name()
+
+	for {
+		err := sp.pool.Start(ctx)
+
+		sp.mu.Lock()
+		sp.lastErr = err
+		restarts := sp.restarts
+		sp.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		restart := s.restartPolicy == RestartAlways || (s.restartPolicy == RestartOnFailure && err != nil)
+		if !restart {
+			if err != nil {
+				s.log.ErrorContext(ctx, "pool failed, not restarting", "pool", name, "error", err)
+			} else {
+				s.log.InfoContext(ctx, "pool stopped, not restarting", "pool", name)
+			}
+			return
+		}
+		if s.maxRestarts > 0 && restarts >= s.maxRestarts {
+			s.log.ErrorContext(ctx, "pool reached max restarts, giving up",
+				"pool", name,
+				"restarts", restarts,
+				"error", err)
+			return
+		}
+
+		delay := s.restartBackoff.Delay(restarts + 1)
+		s.log.WarnContext(ctx, "restarting pool",
+			"pool", name,
+			"restart", restarts+1,
+			"delay", delay,
+			"error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		sp.mu.Lock()
+		sp.restarts++
+		sp.mu.Unlock()
+	}
+}
+
+// Status reports every supervised pool with its restarts, last error and metrics
+func (s *Supervisor) Status() []PoolStatus {
+	statuses := make([]PoolStatus, 0, len(s.pools))
+	for _, sp := range s.pools {
+		sp.mu.Lock()
+		status := PoolStatus{
+			Name:     sp.pool.Name(),
+			Restarts: sp.restarts,
+		}
+		if sp.lastErr != nil {
+			status.LastError = sp.lastErr.Error()
+		}
+		sp.mu.Unlock()
+
+		status.Metrics = sp.pool.GetMetrics()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// GetMetrics returns the metrics of every supervised pool combined, see AggregateSnapshots
+func (s *Supervisor) GetMetrics() MetricsSnapshot {
+	snapshots := make([]MetricsSnapshot, 0, len(s.pools))
+	for _, sp := range s.pools {
+		snapshots = append(snapshots, sp.pool.GetMetrics())
+	}
+	return AggregateSnapshots(snapshots...)
+}
@@ -0,0 +1,231 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+	"github.com/jrazmi/envoker/infrastructure/workers/memqueue"
+)
+
+// failingRuns makes the first n runs of a pool stop with ErrPoolShutdown
+func failingRuns(n int32) (workers.Middleware, *atomic.Int32) {
+	var runs atomic.Int32
+	return func(next workers.WorkFunc) workers.WorkFunc {
+		return func(ctx context.Context, workerID string) error {
+			if runs.Load() < n {
+				runs.Add(1)
+				return workers.ErrPoolShutdown
+			}
+			return next(ctx, workerID)
+		}
+	}, &runs
+}
+
+func newSupervisedPool(t *testing.T, name string, processor *StubProcessor, opts ...workers.Option) *workers.WorkerPool[TestTask] {
+	t.Helper()
+	opts = append([]workers.Option{
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10 * time.Millisecond),
+		workers.WithMetrics(workers.NewInMemoryMetrics()),
+	}, opts...)
+	pool, err := workers.NewWorkerPool(name, 1, processor, opts...)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	return pool
+}
+
+func TestWorkerPool_StartReturnsCriticalError(t *testing.T) {
+	failing, _ := failingRuns(1)
+	pool := newSupervisedPool(t, "critical-pool", NewStubProcessor(), workers.WithMiddleware(failing))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, workers.ErrPoolShutdown) {
+			t.Errorf("expected ErrPoolShutdown from Start, got %v", err)
+		}
+	case <-time.After(time.Second):
+		pool.Stop()
+		t.Fatal("pool kept running after a critical error")
+	}
+
+	// A stopped pool can be started again
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if !pool.Running() {
+		t.Error("expected the restarted pool to be running")
+	}
+	pool.Stop()
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean stop, got %v", err)
+	}
+}
+
+func TestSupervisor_RestartsFailedPools(t *testing.T) {
+	failing, runs := failingRuns(2)
+	processor := NewStubProcessor()
+	processor.AddTask(TestTask{ID: "after-restart"})
+	flaky := newSupervisedPool(t, "flaky-pool", processor, workers.WithMiddleware(failing))
+	steady := newSupervisedPool(t, "steady-pool", NewStubProcessor())
+
+	supervisor, err := workers.NewSupervisor([]workers.Pool{flaky, steady},
+		workers.WithSupervisorLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithRestartPolicy(workers.RestartOnFailure),
+		workers.WithRestartBackoff(workers.ConstantBackoff(time.Millisecond)),
+		workers.WithShutdownTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("failed to create supervisor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- supervisor.Run(ctx)
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+
+	if runs.Load() != 2 {
+		t.Errorf("expected 2 failed runs, got %d", runs.Load())
+	}
+	for _, status := range supervisor.Status() {
+		switch status.Name {
+		case "flaky-pool":
+			if status.Restarts != 2 {
+				t.Errorf("expected flaky-pool to be restarted twice, got %d", status.Restarts)
+			}
+		case "steady-pool":
+			if status.Restarts != 0 {
+				t.Errorf("expected steady-pool not to be restarted, got %d", status.Restarts)
+			}
+		}
+	}
+	if processor.GetCompleteCount() != 1 {
+		t.Errorf("expected the restarted pool to process its task, got %d completes", processor.GetCompleteCount())
+	}
+	if got := supervisor.GetMetrics().WorkersStarted; got != 4 {
+		t.Errorf("expected 4 worker starts across pools and restarts, got %d", got)
+	}
+}
+
+func TestSupervisor_RestartNeverReportsFailure(t *testing.T) {
+	failing, _ := failingRuns(1)
+	pool := newSupervisedPool(t, "doomed-pool", NewStubProcessor(), workers.WithMiddleware(failing))
+
+	supervisor, err := workers.NewSupervisor([]workers.Pool{pool},
+		workers.WithSupervisorLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithRestartPolicy(workers.RestartNever),
+	)
+	if err != nil {
+		t.Fatalf("failed to create supervisor: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- supervisor.Run(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, workers.ErrPoolShutdown) {
+			t.Errorf("expected the pool's critical error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("supervisor kept running without pools")
+	}
+}
+
+func TestSupervisor_ShutdownDeadline(t *testing.T) {
+	processor := NewStubProcessor()
+	processor.AddTask(TestTask{ID: "stubborn-task"})
+	processor.processFunc = func(ctx context.Context, task TestTask) (TestTask, error) {
+		time.Sleep(500 * time.Millisecond) // ignores cancellation
+		return task, nil
+	}
+	pool := newSupervisedPool(t, "stubborn-pool", processor)
+
+	supervisor, err := workers.NewSupervisor([]workers.Pool{pool},
+		workers.WithSupervisorLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithShutdownTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create supervisor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- supervisor.Run(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, workers.ErrShutdownTimeout) {
+		t.Errorf("expected ErrShutdownTimeout, got %v", err)
+	}
+}
+
+func TestSupervisor_ShutdownDeadlineReleasesTasks(t *testing.T) {
+	queue := memqueue.New[TestTask]()
+	queue.Enqueue(TestTask{ID: "long-task"})
+	handler := workers.HandlerFunc[TestTask](func(ctx context.Context, task TestTask) (TestTask, error) {
+		<-ctx.Done()
+		return task, ctx.Err()
+	})
+	// The pool would drain for 30s, the supervisor's deadline cuts it short
+	pool, err := workers.NewWorkerPool("releasing-pool", 1, workers.NewQueueProcessor[TestTask](queue, handler),
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithDrainTimeout(30*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	supervisor, err := workers.NewSupervisor([]workers.Pool{pool},
+		workers.WithSupervisorLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithShutdownTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create supervisor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- supervisor.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, workers.ErrShutdownTimeout) {
+			t.Errorf("expected ErrShutdownTimeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("supervisor waited for the pool's own drain timeout")
+	}
+
+	if entry, _ := queue.Get("long-task"); entry.State != memqueue.StatePending || entry.RetryCount != 0 {
+		t.Errorf("expected the abandoned task released back to pending, got %s after %d", entry.State, entry.RetryCount)
+	}
+}
@@ -0,0 +1,159 @@
+package workers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrazmi/envoker/infrastructure/workers"
+)
+
+// timedTask carries its own timeout
+type timedTask struct {
+	ID          string
+	Timeout     time.Duration
+	Runtime     time.Duration
+	Cooperative bool // Returns as soon as ctx is cancelled
+}
+
+func (t timedTask) GetID() string { return t.ID }
+
+func (t timedTask) TaskTimeout() time.Duration { return t.Timeout }
+
+// timedProcessor runs timedTasks for their runtime, only cooperative ones watch ctx. It notes
+// whether two runs of a task ever overlapped.
+type timedProcessor struct {
+	mu         sync.Mutex
+	pending    []timedTask
+	completed  []string
+	failErrs   map[string]error
+	running    map[string]int
+	overlapped bool
+}
+
+func (p *timedProcessor) Checkout(ctx context.Context, workerID string) (timedTask, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.pending) == 0 {
+		return timedTask{}, workers.ErrNoWorkAvailable
+	}
+	task := p.pending[0]
+	p.pending = p.pending[1:]
+	return task, nil
+}
+
+func (p *timedProcessor) Process(ctx context.Context, task timedTask) (timedTask, error) {
+	p.mu.Lock()
+	p.running[task.ID]++
+	p.overlapped = p.overlapped || p.running[task.ID] > 1
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running[task.ID]--
+		p.mu.Unlock()
+	}()
+
+	if !task.Cooperative {
+		time.Sleep(task.Runtime)
+		return task, nil
+	}
+	select {
+	case <-ctx.Done():
+		return task, ctx.Err()
+	case <-time.After(task.Runtime):
+		return task, nil
+	}
+}
+
+func (p *timedProcessor) Complete(ctx context.Context, task timedTask, processingTimeMS int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed = append(p.completed, task.ID)
+	return nil
+}
+
+func (p *timedProcessor) Fail(ctx context.Context, task timedTask, err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failErrs[task.ID] = err
+	return nil
+}
+
+func TestWorkerPool_TaskTimeout(t *testing.T) {
+	processor := &timedProcessor{
+		failErrs: make(map[string]error),
+		running:  make(map[string]int),
+		pending: []timedTask{
+			{ID: "fast", Runtime: 5 * time.Millisecond},
+			{ID: "hung", Runtime: 2 * time.Second},
+			{ID: "slow-but-allowed", Runtime: 150 * time.Millisecond, Timeout: time.Second},
+			{ID: "cancellable", Runtime: 2 * time.Second, Cooperative: true},
+		},
+	}
+	metrics := workers.NewInMemoryMetrics()
+
+	pool, err := workers.NewWorkerPool("timeout-pool", 4, processor,
+		workers.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		workers.WithPollInterval(10*time.Millisecond),
+		workers.WithTaskTimeout(50*time.Millisecond),
+		workers.WithTimeoutGrace(50*time.Millisecond),
+		workers.WithMaxRetries(2),
+		workers.WithBackoff(workers.ConstantBackoff(time.Millisecond)),
+		workers.WithMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.Start(context.Background())
+	}()
+
+	// The hung task must not hold up the pool for its full runtime
+	time.Sleep(400 * time.Millisecond)
+	stuck := 0
+	for _, status := range pool.Workers() {
+		if status.State == workers.WorkerStuck {
+			stuck++
+		}
+	}
+	pool.Stop()
+	<-done
+
+	processor.mu.Lock()
+	defer processor.mu.Unlock()
+
+	if len(processor.completed) != 2 {
+		t.Errorf("expected fast and slow-but-allowed to complete, got %v", processor.completed)
+	}
+	if processor.overlapped {
+		t.Error("expected a retry never to run alongside the attempt that timed out")
+	}
+
+	// A timed out attempt that returns is retried
+	if err := processor.failErrs["cancellable"]; !errors.Is(err, workers.ErrTaskTimeout) {
+		t.Errorf("expected cancellable to fail with ErrTaskTimeout, got %v", err)
+	}
+	if got := workers.AttemptsOf(processor.failErrs["cancellable"]); len(got) != 2 {
+		t.Errorf("expected the timeout to be retried once, got %d attempts", len(got))
+	}
+
+	// One that doesn't return within the grace fails for good and leaves its worker stuck
+	if err := processor.failErrs["hung"]; !errors.Is(err, workers.ErrTaskStuck) || workers.IsRetryable(err) {
+		t.Errorf("expected hung to fail permanently with ErrTaskStuck, got %v", err)
+	}
+	if got := workers.AttemptsOf(processor.failErrs["hung"]); len(got) != 1 {
+		t.Errorf("expected hung not to be retried, got %d attempts", len(got))
+	}
+	if stuck != 1 {
+		t.Errorf("expected 1 stuck worker, got %d", stuck)
+	}
+	if got := metrics.GetSnapshot().TasksTimedOut; got != 3 {
+		t.Errorf("expected 3 timed out attempts, got %d", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jrazmi/envoker/sdk/environment"
@@ -17,6 +18,11 @@ var (
 	ErrWorkerShutdown  = errors.New("worker should shutdown")
 	ErrPoolShutdown    = errors.New("pool should shutdown")
 	ErrNoWorkAvailable = errors.New("no work available")
+	ErrLeaseLost       = errors.New("task lease lost")
+	ErrDrainTimeout    = errors.New("drain deadline exceeded")
+	ErrTaskTimeout     = errors.New("task timed out")
+	ErrTaskCancelled   = errors.New("task cancelled")
+	ErrTaskStuck       = errors.New("task did not return after its timeout")
 )
 
 // Options represents the exportable worker configuration
@@ -26,17 +32,65 @@ type Options struct {
 	PollInterval time.Duration `env:"WORKER_POLL_INTERVAL" default:"5s"`
 	IdleInterval time.Duration `env:"WORKER_IDLE_INTERVAL" default:"30s"`
 	MaxRetries   int           `env:"WORKER_MAX_RETRIES" default:"3"`
+	TaskTimeout  time.Duration `env:"WORKER_TASK_TIMEOUT" default:"0"`  // Per Process attempt, 0 means no timeout
+	TimeoutGrace time.Duration `env:"WORKER_TIMEOUT_GRACE" default:"0"` // For a timed out attempt to return, 0 waits for it
+
+	// How long Stop lets in-flight tasks finish before cancelling them; 0 cancels right away
+	DrainTimeout time.Duration `env:"WORKER_DRAIN_TIMEOUT" default:"30s"`
+
+	// Retry backoff - exponential with full jitter unless overridden with WithBackoff
+	RetryInitialDelay time.Duration `env:"WORKER_RETRY_INITIAL_DELAY" default:"1s"`
+	RetryMaxDelay     time.Duration `env:"WORKER_RETRY_MAX_DELAY" default:"1m"`
+
+	// Leasing - only used when the processor implements Leaser
+	LeaseDuration     time.Duration `env:"WORKER_LEASE_DURATION" default:"5m"`
+	HeartbeatInterval time.Duration `env:"WORKER_HEARTBEAT_INTERVAL" default:"1m"`
+	ReapInterval      time.Duration `env:"WORKER_REAP_INTERVAL" default:"1m"`
+
+	// Autoscaling - enabled when AutoscaleMax is set, see WithAutoscale
+	AutoscaleMin         int           `env:"WORKER_AUTOSCALE_MIN" default:"1"`
+	AutoscaleMax         int           `env:"WORKER_AUTOSCALE_MAX" default:"0"`
+	AutoscaleInterval    time.Duration `env:"WORKER_AUTOSCALE_INTERVAL" default:"10s"`
+	AutoscaleIdlePeriods int           `env:"WORKER_AUTOSCALE_IDLE_PERIODS" default:"3"`
+
+	// How many failed tasks to keep for RecentFailures
+	RecentFailures int `env:"WORKER_RECENT_FAILURES" default:"20"`
+
+	// Batching - only used when the processor implements BatchHandler
+	BatchSize    int           `env:"WORKER_BATCH_SIZE" default:"10"`
+	BatchMaxWait time.Duration `env:"WORKER_BATCH_MAX_WAIT" default:"0"`
 }
 
 // options holds the internal runtime configuration
 type options struct {
-	name         string
-	workerCount  int
-	pollInterval time.Duration
-	idleInterval time.Duration
-	maxRetries   int
-	middlewares  []Middleware
-	metrics      WorkerPoolMetrics // Add metrics to options
+	name          string
+	workerCount   int
+	pollInterval  time.Duration
+	idleInterval  time.Duration
+	maxRetries    int
+	maxRetriesSet bool // WithMaxRetries was given, see RetryingQueue
+	taskTimeout   time.Duration
+	timeoutGrace  time.Duration
+	drainTimeout  time.Duration
+	middlewares   []Middleware
+	metrics       WorkerPoolMetrics // Add metrics to options
+	backoff       BackoffPolicy
+	scheduler     *Scheduler
+	notifier      Notifier
+
+	leaseDuration     time.Duration
+	heartbeatInterval time.Duration
+	reapInterval      time.Duration
+
+	autoscaleMin         int
+	autoscaleMax         int
+	autoscaleInterval    time.Duration
+	autoscaleIdlePeriods int
+
+	recentFailures int
+
+	batchSize    int
+	batchMaxWait time.Duration
 
 	logger *slog.Logger
 }
@@ -49,27 +103,74 @@ type WorkerPool[T Task] struct {
 	// configuration
 	processor    Processor[T]
 	name         string
-	workerCount  int
+	workerCount  int // Target number of workers, guarded by sizeMutex
 	pollInterval time.Duration
 	idleInterval time.Duration
 	maxRetries   int // Add this field
+	taskTimeout  time.Duration
+	timeoutGrace time.Duration
+	backoff      BackoffPolicy
+	drainTimeout time.Duration
 	log          *slog.Logger
 
+	// leasing
+	leaser            Leaser[T]         // nil when the processor doesn't lease tasks
+	releaser          Releaser[T]       // nil when abandoned tasks can't be handed back
+	canceller         Canceller[T]      // nil when cancelled tasks are failed instead
+	progressSaver     ProgressSaver[T]  // nil when task progress isn't persisted
+	policies          PolicyProvider[T] // nil when every task uses the pool's retry settings
+	leaseDuration     time.Duration
+	heartbeatInterval time.Duration
+	reapInterval      time.Duration
+
+	// batching
+	batchHandler BatchHandler[T] // nil when tasks are processed one at a time
+	batchQueue   BatchQueue[T]   // nil when batches are checked out a task at a time
+	batchSize    int
+	batchMaxWait time.Duration
+
+	// scheduling
+	scheduler *Scheduler // nil when the pool doesn't run a scheduler
+
+	// autoscaling
+	autoscaler *autoscaler // nil when the pool runs a fixed number of workers
+
+	// notifications
+	notifier Notifier      // nil when the pool relies on polling alone
+	wake     chan struct{} // wakes idle workers, nil without a notifier
+
 	// work
 	workFunc         WorkFunc // The final wrapped work function
 	middlewares      []Middleware
 	preProcessHooks  []PreProcessHook[T]
 	postProcessHooks []PostProcessHook[T]
 	metrics          WorkerPoolMetrics // Add metrics to options
+	labelled         LabelledMetrics   // nil when the metrics don't break down by task type and worker
+
+	// introspection
+	handles    sync.Map // Worker ID to *workerHandle of every running worker
+	failures   *failureLog
+	pauseMutex sync.Mutex    // Protects resumed
+	resumed    chan struct{} // Closed on Resume, nil when the pool isn't paused
 
 	// control
-	ctx        context.Context
-	cancel     context.CancelFunc
-	workers    sync.WaitGroup // Counter to track active workers
-	stopMutex  sync.Mutex     // Ensures Stop() only runs once
-	startMutex sync.Mutex     // Protects against multiple Start() calls
-	running    bool           // Track if pool is running
-	startTime  time.Time
+	ctx         context.Context // Cancelled on Stop: workers stop checking out tasks
+	cancel      context.CancelFunc
+	taskCtx     context.Context // Passed to in-flight tasks, cancelled at the drain deadline
+	cancelTasks context.CancelCauseFunc
+	workers     sync.WaitGroup // Counter to track active workers
+	sizeMutex   sync.Mutex     // Protects workerCount, active and nextWorker
+	active      []*workerHandle
+	nextWorker  int
+	background  sync.WaitGroup // Counter to track pool level goroutines (e.g. the lease reaper)
+	stopMutex   sync.Mutex     // Ensures Stop() only runs once
+	startMutex  sync.Mutex     // Protects against multiple Start() calls
+	running     bool           // Track if pool is running
+	startTime   time.Time
+	stopped     chan struct{} // Closed when Start returns
+	drained     atomic.Int64  // Tasks that finished after Stop, within the drain deadline
+	abandoned   atomic.Int64  // Tasks cancelled at the drain deadline
+	lastDrain   DrainReport
 	// Communication
 	errors chan error // Tube for workers to report critical errors
 
@@ -110,10 +211,105 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
-// WithMaxRetries sets the maximum number of retry attempts
+// WithMaxRetries sets the maximum number of retry attempts. It also applies to queues that retry
+// failed tasks themselves (see RetryingQueue), which otherwise get a single attempt per checkout.
 func WithMaxRetries(maxRetries int) Option {
 	return func(o *options) {
 		o.maxRetries = maxRetries
+		o.maxRetriesSet = true
+	}
+}
+
+// WithLeaseDuration sets how long a checked out task is leased to a worker
+func WithLeaseDuration(lease time.Duration) Option {
+	return func(o *options) {
+		o.leaseDuration = lease
+	}
+}
+
+// WithHeartbeatInterval sets how often a worker extends the lease of the task it is processing
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.heartbeatInterval = interval
+	}
+}
+
+// WithReapInterval sets how often expired leases are reclaimed
+func WithReapInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.reapInterval = interval
+	}
+}
+
+// WithTaskTimeout bounds each Process attempt. An attempt that runs past it is cancelled and
+// fails with ErrTaskTimeout once Process returns, which is retried like any other error. Tasks can
+// override it by implementing TimeoutTask. 0 disables the timeout.
+func WithTaskTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.taskTimeout = timeout
+	}
+}
+
+// WithTimeoutGrace bounds how long a timed out Process call gets to return once cancelled. A call
+// still running after it fails the task with ErrTaskStuck, without retrying, and leaves the worker
+// stuck: it takes no new task until the call returns. 0 (the default) waits for the call.
+func WithTimeoutGrace(grace time.Duration) Option {
+	return func(o *options) {
+		o.timeoutGrace = grace
+	}
+}
+
+// WithDrainTimeout sets how long Stop lets in-flight tasks finish. Tasks still running after
+// that are cancelled and released (see Releaser) or failed. 0 cancels them right away.
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.drainTimeout = timeout
+	}
+}
+
+// WithBackoff sets the policy used to wait between retries of a failed task
+func WithBackoff(policy BackoffPolicy) Option {
+	return func(o *options) {
+		o.backoff = policy
+	}
+}
+
+// WithScheduler runs the scheduler alongside the pool: it starts with Start and stops with Stop
+func WithScheduler(scheduler *Scheduler) Option {
+	return func(o *options) {
+		o.scheduler = scheduler
+	}
+}
+
+// WithNotifier wakes idle workers when the notifier reports new work, so they don't wait out
+// the idle interval. Polling continues as the fallback.
+func WithNotifier(notifier Notifier) Option {
+	return func(o *options) {
+		o.notifier = notifier
+	}
+}
+
+// WithAutoscale lets the pool resize itself between minWorkers and maxWorkers: it grows while
+// every checkout finds work and shrinks after sustained idle polling. The decisions are based on
+// the pool's metrics, so a pool without metrics (see WithMetrics) gets InMemoryMetrics.
+func WithAutoscale(minWorkers int, maxWorkers int) Option {
+	return func(o *options) {
+		o.autoscaleMin = minWorkers
+		o.autoscaleMax = maxWorkers
+	}
+}
+
+// WithAutoscaleInterval sets how often the autoscaler looks at the metrics
+func WithAutoscaleInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.autoscaleInterval = interval
+	}
+}
+
+// WithAutoscaleIdlePeriods sets how many idle intervals in a row it takes to remove a worker
+func WithAutoscaleIdlePeriods(periods int) Option {
+	return func(o *options) {
+		o.autoscaleIdlePeriods = periods
 	}
 }
 
@@ -149,6 +345,22 @@ func NewWorkerPool[T Task](name string, workerCount int, processor Processor[T],
 		PollInterval: 1 * time.Second,
 		IdleInterval: 30 * time.Second,
 		MaxRetries:   3,
+		DrainTimeout: 30 * time.Second,
+
+		RetryInitialDelay: 1 * time.Second,
+		RetryMaxDelay:     1 * time.Minute,
+
+		LeaseDuration:     5 * time.Minute,
+		HeartbeatInterval: 1 * time.Minute,
+		ReapInterval:      1 * time.Minute,
+
+		AutoscaleMin:         1,
+		AutoscaleInterval:    10 * time.Second,
+		AutoscaleIdlePeriods: 3,
+
+		RecentFailures: 20,
+
+		BatchSize: 10,
 	}
 
 	// Prepend the processor to the options
@@ -168,8 +380,24 @@ func newWorkerPool[T Task](processor Processor[T], cfg Options, opts ...Option)
 		pollInterval: cfg.PollInterval,
 		idleInterval: cfg.IdleInterval,
 		maxRetries:   cfg.MaxRetries,
+		taskTimeout:  cfg.TaskTimeout,
+		timeoutGrace: cfg.TimeoutGrace,
+		drainTimeout: cfg.DrainTimeout,
 		metrics:      NewNoOpMetrics(), // Default to no-op metrics
 
+		leaseDuration:     cfg.LeaseDuration,
+		heartbeatInterval: cfg.HeartbeatInterval,
+		reapInterval:      cfg.ReapInterval,
+
+		autoscaleMin:         cfg.AutoscaleMin,
+		autoscaleMax:         cfg.AutoscaleMax,
+		autoscaleInterval:    cfg.AutoscaleInterval,
+		autoscaleIdlePeriods: cfg.AutoscaleIdlePeriods,
+
+		recentFailures: cfg.RecentFailures,
+
+		batchSize:    cfg.BatchSize,
+		batchMaxWait: cfg.BatchMaxWait,
 	}
 
 	// Apply functional options to override config
@@ -192,6 +420,55 @@ func newWorkerPool[T Task](processor Processor[T], cfg Options, opts ...Option)
 	if internalOpts.idleInterval <= 0 {
 		internalOpts.idleInterval = 30 * time.Second
 	}
+	if internalOpts.drainTimeout < 0 {
+		internalOpts.drainTimeout = 0
+	}
+	if internalOpts.timeoutGrace < 0 {
+		internalOpts.timeoutGrace = 0
+	}
+	if internalOpts.backoff == nil {
+		initialDelay := cfg.RetryInitialDelay
+		if initialDelay <= 0 {
+			initialDelay = 1 * time.Second
+		}
+		internalOpts.backoff = FullJitter(ExponentialBackoff(initialDelay, cfg.RetryMaxDelay))
+	}
+	if internalOpts.leaseDuration <= 0 {
+		internalOpts.leaseDuration = 5 * time.Minute
+	}
+	// The lease has to be extended well before it lapses
+	if internalOpts.heartbeatInterval <= 0 || internalOpts.heartbeatInterval >= internalOpts.leaseDuration {
+		internalOpts.heartbeatInterval = internalOpts.leaseDuration / 3
+	}
+	if internalOpts.reapInterval <= 0 {
+		internalOpts.reapInterval = 1 * time.Minute
+	}
+	if internalOpts.recentFailures < 0 {
+		internalOpts.recentFailures = 0
+	}
+	if internalOpts.batchSize <= 0 {
+		internalOpts.batchSize = 10
+	}
+	if internalOpts.autoscaleMax > 0 {
+		if internalOpts.autoscaleMin <= 0 {
+			internalOpts.autoscaleMin = 1
+		}
+		if internalOpts.autoscaleMin > internalOpts.autoscaleMax {
+			return nil, fmt.Errorf("autoscale min %d is above max %d", internalOpts.autoscaleMin, internalOpts.autoscaleMax)
+		}
+		if internalOpts.autoscaleInterval <= 0 {
+			internalOpts.autoscaleInterval = 10 * time.Second
+		}
+		if internalOpts.autoscaleIdlePeriods <= 0 {
+			internalOpts.autoscaleIdlePeriods = 3
+		}
+		// Start within bounds
+		internalOpts.workerCount = min(max(internalOpts.workerCount, internalOpts.autoscaleMin), internalOpts.autoscaleMax)
+		// The autoscaler needs real counters
+		if _, ok := internalOpts.metrics.(*NoOpMetrics); ok {
+			internalOpts.metrics = NewInMemoryMetrics()
+		}
+	}
 
 	pool := &WorkerPool[T]{
 		processor:    processor,
@@ -201,70 +478,287 @@ func newWorkerPool[T Task](processor Processor[T], cfg Options, opts ...Option)
 		idleInterval: internalOpts.idleInterval,
 		log:          internalOpts.logger,
 		maxRetries:   internalOpts.maxRetries,
+		taskTimeout:  internalOpts.taskTimeout,
+		timeoutGrace: internalOpts.timeoutGrace,
+		backoff:      internalOpts.backoff,
+		drainTimeout: internalOpts.drainTimeout,
+		scheduler:    internalOpts.scheduler,
+		notifier:     internalOpts.notifier,
+
+		leaseDuration:     internalOpts.leaseDuration,
+		heartbeatInterval: internalOpts.heartbeatInterval,
+		reapInterval:      internalOpts.reapInterval,
+
+		batchSize:    internalOpts.batchSize,
+		batchMaxWait: internalOpts.batchMaxWait,
 
 		middlewares: internalOpts.middlewares,
 		metrics:     internalOpts.metrics,
+		failures:    newFailureLog(internalOpts.recentFailures),
 		errors:      make(chan error, internalOpts.workerCount),
 	}
+	pool.leaser, _ = processorAs[Leaser[T]](processor)
+	pool.releaser, _ = processorAs[Releaser[T]](processor)
+	pool.canceller, _ = processorAs[Canceller[T]](processor)
+	pool.progressSaver, _ = processorAs[ProgressSaver[T]](processor)
+	pool.policies, _ = processorAs[PolicyProvider[T]](processor)
+	pool.batchHandler, _ = processorAs[BatchHandler[T]](processor)
+	pool.batchQueue, _ = processorAs[BatchQueue[T]](processor)
+	pool.labelled, _ = pool.metrics.(LabelledMetrics)
+	// Leave retries to a queue that retries failed tasks itself
+	if rq, ok := processorAs[RetryingQueue](processor); ok && rq.RetriesFailedTasks() && !internalOpts.maxRetriesSet {
+		pool.maxRetries = 1
+	}
+	if internalOpts.autoscaleMax > 0 {
+		pool.autoscaler = &autoscaler{
+			min:         internalOpts.autoscaleMin,
+			max:         internalOpts.autoscaleMax,
+			interval:    internalOpts.autoscaleInterval,
+			idlePeriods: internalOpts.autoscaleIdlePeriods,
+		}
+	}
+	if pool.notifier != nil {
+		pool.wake = make(chan struct{}, max(pool.workerCount, internalOpts.autoscaleMax))
+	}
 	pool.buildMiddlewareChain()
 
 	return pool, nil
 
 }
 
+// Start runs the pool until ctx is cancelled, Stop is called, or a worker reports ErrPoolShutdown.
+// Either way the pool drains: in-flight tasks get up to the drain timeout to finish.
+// It blocks the caller and returns the critical errors reported by workers, joined, or nil after
+// a normal stop. A stopped pool can be started again.
 func (wp *WorkerPool[T]) Start(ctx context.Context) error {
-	wp.startTime = time.Now()
 	wp.startMutex.Lock()
 	defer wp.startMutex.Unlock()
+
+	wp.stopMutex.Lock()
+	wp.startTime = time.Now()
+	wp.ctx, wp.cancel = context.WithCancel(ctx)
+	// Tasks outlive ctx so they can drain, they are only cancelled at the drain deadline.
+	// They carry the pool's logger and metrics for middleware to report to.
+	values := poolValues{name: wp.name, log: wp.log, metrics: wp.metrics}
+	wp.taskCtx, wp.cancelTasks = context.WithCancelCause(context.WithValue(context.WithoutCancel(ctx), poolKey{}, values))
+	wp.errors = make(chan error, wp.WorkerCount())
+	wp.stopped = make(chan struct{})
+	wp.drained.Store(0)
+	wp.abandoned.Store(0)
+	wp.running = true
+	wp.stopMutex.Unlock()
+
 	wp.log.Info(strings.Repeat("=", 60))
 	wp.log.InfoContext(ctx,
 		"starting worker pool",
 		"name", wp.name,
-		"worker_count", wp.workerCount,
+		"worker_count", wp.WorkerCount(),
 		"poll_interval", wp.pollInterval,
 	)
 	wp.log.Info(strings.Repeat("=", 60))
 	wp.metrics.Start(ctx, wp.name)
 
-	wp.ctx, wp.cancel = context.WithCancel(ctx)
+	wp.sizeMutex.Lock()
+	wp.nextWorker = 0
 	for i := 0; i < wp.workerCount; i++ {
-		workerID := fmt.Sprintf("%s-worker-%d", wp.name, i+1)
-		wp.workers.Add(1)
-		go wp.worker(workerID)
+		wp.spawnWorker()
 	}
-	wp.running = true
+	wp.sizeMutex.Unlock()
+
+	if wp.leaser != nil {
+		wp.background.Add(1)
+		go wp.reaper()
+	}
+	if wp.notifier != nil {
+		wp.background.Add(1)
+		go wp.listen()
+	}
+	if wp.autoscaler != nil {
+		wp.background.Add(1)
+		go wp.autoscale()
+	}
+	if wp.scheduler != nil {
+		wp.background.Add(1)
+		go func() {
+			defer wp.background.Done()
+			wp.scheduler.Run(wp.ctx)
+		}()
+	}
+	workersDone := make(chan struct{})
+	wp.background.Add(1)
+	go wp.drainWatch(workersDone)
+
 	wp.workers.Wait()
+	close(workersDone)
+
+	// Workers can also all exit on their own (ErrWorkerShutdown), take the pool down with them
+	wp.cancel()
+	wp.background.Wait()
+	wp.cancelTasks(nil)
+
+	report := DrainReport{
+		Drained:   int(wp.drained.Load()),
+		Abandoned: int(wp.abandoned.Load()),
+	}
 
 	close(wp.errors)
+	var critical []error
+	for err := range wp.errors {
+		critical = append(critical, err)
+	}
 	wp.metrics.Stop(ctx)
 
-	wp.log.InfoContext(ctx, "worker pool stopped", "name", wp.name, "total_runtime", time.Since(wp.startTime))
+	wp.stopMutex.Lock()
 	wp.running = false
+	wp.lastDrain = report
+	close(wp.stopped)
+	wp.stopMutex.Unlock()
+
+	if report.Drained > 0 || report.Abandoned > 0 {
+		wp.log.InfoContext(ctx, "worker pool drained", "name", wp.name, "drained", report.Drained, "abandoned", report.Abandoned)
+	}
+	if len(critical) > 0 {
+		wp.log.ErrorContext(ctx, "worker pool stopped on critical error", "name", wp.name, "total_runtime", time.Since(wp.startTime), "error", errors.Join(critical...))
+		return errors.Join(critical...)
+	}
+	wp.log.InfoContext(ctx, "worker pool stopped", "name", wp.name, "total_runtime", time.Since(wp.startTime))
 	return nil
 }
 
-// Stop gracefully stops the worker pool
-func (wp *WorkerPool[T]) Stop() {
+// DrainReport describes how the in-flight tasks fared when a pool stopped
+type DrainReport struct {
+	Drained   int `json:"drained"`   // Tasks that finished within the drain deadline
+	Abandoned int `json:"abandoned"` // Tasks cancelled at the deadline and released or failed
+}
+
+// Stop gracefully stops the worker pool. Workers stop checking out tasks right away, in-flight
+// tasks get up to the drain timeout to finish. Stop blocks until the pool has stopped and reports
+// how many tasks were drained or abandoned. Don't call it from inside a task or hook, it would
+// wait for itself.
+func (wp *WorkerPool[T]) Stop() DrainReport {
+	return wp.StopContext(context.Background())
+}
+
+// StopContext is Stop with a deadline of its own: in-flight tasks are abandoned (released or
+// failed) when ctx is done, even if the drain timeout hasn't passed yet. The Supervisor uses it
+// to hold every pool to its shutdown deadline.
+func (wp *WorkerPool[T]) StopContext(ctx context.Context) DrainReport {
 	wp.stopMutex.Lock()
-	defer wp.stopMutex.Unlock()
 
 	// Check if already stopped
 	if !wp.running {
-		wp.log.InfoContext(wp.ctx, "pool already stopped", "name", wp.name)
-		return
+		wp.stopMutex.Unlock()
+		wp.log.Info("pool already stopped", "name", wp.name)
+		return DrainReport{}
+	}
+
+	wp.log.InfoContext(wp.ctx, "stopping worker pool", "name", wp.name, "drain_timeout", wp.drainTimeout)
+	wp.cancel()
+	wp.running = false
+	stopped := wp.stopped
+	cancelTasks := wp.cancelTasks
+	wp.stopMutex.Unlock()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		wp.log.WarnContext(context.Background(), "stop deadline exceeded, cancelling in-flight tasks",
+			"pool", wp.name)
+		cancelTasks(ErrDrainTimeout)
+		<-stopped
+	}
+
+	wp.stopMutex.Lock()
+	defer wp.stopMutex.Unlock()
+	return wp.lastDrain
+}
+
+// Name returns the pool's name
+func (wp *WorkerPool[T]) Name() string {
+	return wp.name
+}
+
+// Running reports whether the pool has been started and not stopped yet
+func (wp *WorkerPool[T]) Running() bool {
+	wp.stopMutex.Lock()
+	defer wp.stopMutex.Unlock()
+	return wp.running
+}
+
+// WorkerCount returns the number of workers the pool runs
+func (wp *WorkerPool[T]) WorkerCount() int {
+	wp.sizeMutex.Lock()
+	defer wp.sizeMutex.Unlock()
+	return wp.workerCount
+}
+
+// Resize changes the number of workers. On a running pool, new workers start right away and
+// removed workers finish their current task before they exit; on a stopped pool the count is
+// used by the next Start. With autoscaling enabled the autoscaler keeps adjusting from n.
+func (wp *WorkerPool[T]) Resize(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid worker count %d", n)
+	}
+
+	wp.sizeMutex.Lock()
+	defer wp.sizeMutex.Unlock()
+
+	previous := wp.workerCount
+	wp.workerCount = n
+
+	// Only adjust a running pool; once every worker has exited the pool is on its way down
+	if len(wp.active) > 0 && wp.ctx.Err() == nil {
+		for len(wp.active) < n {
+			wp.spawnWorker()
+		}
+		for len(wp.active) > n {
+			last := wp.active[len(wp.active)-1]
+			wp.active = wp.active[:len(wp.active)-1]
+			close(last.quit)
+		}
+	}
+
+	if previous != n {
+		wp.log.Info("worker pool resized", "name", wp.name, "from", previous, "to", n)
+	}
+	return nil
+}
+
+// spawnWorker starts one worker, the caller must hold sizeMutex
+func (wp *WorkerPool[T]) spawnWorker() {
+	wp.nextWorker++
+	handle := &workerHandle{
+		id:        fmt.Sprintf("%s-worker-%d", wp.name, wp.nextWorker),
+		seq:       wp.nextWorker,
+		quit:      make(chan struct{}),
+		startedAt: time.Now(),
+		state:     WorkerIdle,
 	}
+	wp.active = append(wp.active, handle)
+	wp.handles.Store(handle.id, handle)
+	wp.workers.Add(1)
+	go wp.worker(handle)
+}
 
-	wp.log.InfoContext(wp.ctx, "stopping worker pool", "name", wp.name)
-	if wp.cancel != nil {
-		wp.cancel()
-		wp.running = false
+// removeWorker drops a worker that exited on its own from the active set
+func (wp *WorkerPool[T]) removeWorker(handle *workerHandle) {
+	wp.handles.Delete(handle.id)
+	wp.sizeMutex.Lock()
+	defer wp.sizeMutex.Unlock()
+	for i, h := range wp.active {
+		if h == handle {
+			wp.active = append(wp.active[:i], wp.active[i+1:]...)
+			return
+		}
 	}
 }
 
 // infrastructure/workers/worker.go
 
-func (wp *WorkerPool[T]) worker(workerID string) {
+func (wp *WorkerPool[T]) worker(handle *workerHandle) {
+	workerID := handle.id
 	defer wp.workers.Done()
+	defer wp.removeWorker(handle)
 	defer wp.metrics.RecordWorkerStopped()
 
 	wp.log.InfoContext(wp.ctx, "worker started",
@@ -283,6 +777,7 @@ func (wp *WorkerPool[T]) worker(workerID string) {
 
 	ticker := time.NewTicker(currentInterval)
 	defer ticker.Stop()
+	handle.setPollInterval(currentInterval)
 
 	for {
 		select {
@@ -291,65 +786,114 @@ func (wp *WorkerPool[T]) worker(workerID string) {
 				"worker_id", workerID)
 			return
 
+		case <-handle.quit:
+			wp.log.InfoContext(wp.ctx, "worker retired by resize",
+				"worker_id", workerID)
+			return
+
+		case <-wp.wake:
+			// Woken by the notifier, check for work right away
+			ticker.Reset(currentInterval)
+
 		case <-ticker.C:
-			// Wrap the entire work function with panic recovery
-			err := wp.workWithPanicRecovery(wp.ctx, workerID)
+		}
 
-			// Determine next polling interval based on result
-			var newInterval time.Duration
+		// Stopping or retired: don't pick up anything new, even if the ticker won the race
+		if wp.ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-handle.quit:
+			return
+		default:
+		}
 
-			if err != nil {
-				// Check for special error types
-				if errors.Is(err, ErrWorkerShutdown) {
-					wp.log.InfoContext(wp.ctx, "worker shutting down as requested",
-						"worker_id", workerID)
-					return
-				}
+		// A paused pool doesn't check out tasks, wait for Resume
+		if resumed := wp.pausedUntil(); resumed != nil {
+			handle.setState(WorkerPaused)
+			select {
+			case <-wp.ctx.Done():
+				return
+			case <-handle.quit:
+				return
+			case <-resumed:
+			}
+			handle.setState(WorkerIdle)
+		}
 
-				if errors.Is(err, ErrPoolShutdown) {
-					wp.log.ErrorContext(wp.ctx, "worker requesting pool shutdown",
-						"worker_id", workerID,
-						"error", err)
+		// Wrap the entire work function with panic recovery. Tasks run on taskCtx so they can
+		// finish while the pool drains.
+		err := wp.workWithPanicRecovery(wp.taskCtx, workerID)
 
-					select {
-					case wp.errors <- fmt.Errorf("worker %s: %w", workerID, err):
-					default:
-						wp.log.ErrorContext(wp.ctx, "error channel full, critical error not sent",
-							"worker_id", workerID)
-					}
-					return
-				}
+		// A worker stuck on a timed out task takes nothing new until the call returns
+		if returned := handle.stuckOn(); returned != nil {
+			select {
+			case <-wp.ctx.Done():
+				return
+			case <-handle.quit:
+				return
+			case <-returned:
+			}
+			handle.unstick()
+		}
 
-				if errors.Is(err, ErrNoWorkAvailable) {
-					newInterval = idleInterval
-					if currentInterval != idleInterval {
-						wp.log.InfoContext(wp.ctx, "no work available, switching to idle polling",
-							"worker_id", workerID,
-							"active_interval", activePollInterval,
-							"idle_interval", idleInterval)
-					}
-				} else {
-					newInterval = activePollInterval
-					wp.log.ErrorContext(wp.ctx, "task processing error",
-						"worker_id", workerID,
-						"error", err)
+		// Determine next polling interval based on result
+		var newInterval time.Duration
+
+		if err != nil {
+			// Check for special error types
+			if errors.Is(err, ErrWorkerShutdown) {
+				wp.log.InfoContext(wp.ctx, "worker shutting down as requested",
+					"worker_id", workerID)
+				return
+			}
+
+			if errors.Is(err, ErrPoolShutdown) {
+				wp.log.ErrorContext(wp.ctx, "worker requesting pool shutdown",
+					"worker_id", workerID,
+					"error", err)
+
+				select {
+				case wp.errors <- fmt.Errorf("worker %s: %w", workerID, err):
+				default:
+					wp.log.ErrorContext(wp.ctx, "error channel full, critical error not sent",
+						"worker_id", workerID)
 				}
-			} else {
-				// Success! Work was processed
-				newInterval = activePollInterval
-				if currentInterval != activePollInterval {
-					wp.log.InfoContext(wp.ctx, "work completed, switching to active polling",
+				// The rest of the pool goes down too, Start reports the error
+				wp.cancel()
+				return
+			}
+
+			if errors.Is(err, ErrNoWorkAvailable) {
+				newInterval = idleInterval
+				if currentInterval != idleInterval {
+					wp.log.InfoContext(wp.ctx, "no work available, switching to idle polling",
 						"worker_id", workerID,
 						"active_interval", activePollInterval,
 						"idle_interval", idleInterval)
 				}
+			} else {
+				newInterval = activePollInterval
+				wp.log.ErrorContext(wp.ctx, "task processing error",
+					"worker_id", workerID,
+					"error", err)
 			}
-
-			if newInterval != currentInterval {
-				currentInterval = newInterval
-				ticker.Reset(newInterval)
+		} else {
+			// Success! Work was processed
+			newInterval = activePollInterval
+			if currentInterval != activePollInterval {
+				wp.log.InfoContext(wp.ctx, "work completed, switching to active polling",
+					"worker_id", workerID,
+					"active_interval", activePollInterval,
+					"idle_interval", idleInterval)
 			}
 		}
+
+		if newInterval != currentInterval {
+			currentInterval = newInterval
+			ticker.Reset(newInterval)
+			handle.setPollInterval(newInterval)
+		}
 	}
 }
 
@@ -376,7 +920,9 @@ func (wp *WorkerPool[T]) workWithPanicRecovery(ctx context.Context, workerID str
 
 // work runs the process Checkout -> Process -> Complete/Fail. The process function is wrapped in its own panic recovery to distinguish between task panics and worker panics.
 func (wp *WorkerPool[T]) work(ctx context.Context, workerID string) error {
-	task, err := wp.processor.Checkout(ctx, workerID)
+	checkoutStart := time.Now()
+	task, err := wp.checkout(ctx, workerID)
+	wp.metrics.RecordCheckoutLatency(time.Since(checkoutStart))
 	if err != nil {
 		if errors.Is(err, ErrNoWorkAvailable) {
 			wp.metrics.RecordCheckoutError()
@@ -386,6 +932,12 @@ func (wp *WorkerPool[T]) work(ctx context.Context, workerID string) error {
 		return fmt.Errorf("checkout failed: %w", err)
 	}
 	wp.metrics.RecordTaskCheckedOut()
+	labels := taskLabels(task, workerID)
+	progress := wp.newProgress(task)
+	if handle := wp.handle(workerID); handle != nil {
+		handle.startTask(task.GetID(), progress)
+		defer handle.finishTask()
+	}
 
 	// Track processing state
 	var processErr error
@@ -393,6 +945,14 @@ func (wp *WorkerPool[T]) work(ctx context.Context, workerID string) error {
 	var duration time.Duration
 	startTime := time.Now()
 
+	// Process gets its own context so a lost lease can abort it without stopping the worker. It
+	// carries the task's Progress, which outlives the retries of this run.
+	processCtx, cancelProcess := context.WithCancelCause(withProgress(ctx, progress))
+	defer cancelProcess(nil)
+
+	// The outcome is recorded even if the task was cancelled at the drain deadline
+	finishCtx := context.WithoutCancel(ctx)
+
 	defer func() {
 		duration = time.Since(startTime)
 
@@ -425,22 +985,7 @@ func (wp *WorkerPool[T]) work(ctx context.Context, workerID string) error {
 			}
 		}
 
-		// Handle result (error or success)
-		if processErr != nil {
-			wp.metrics.RecordTaskFailed(duration)
-			if failErr := wp.processor.Fail(ctx, task, processErr); failErr != nil {
-				wp.log.ErrorContext(ctx, "failed to mark task as failed",
-					"task_id", task.GetID(),
-					"error", failErr)
-			}
-		} else {
-			wp.metrics.RecordTaskCompleted(duration)
-			if completeErr := wp.processor.Complete(ctx, processedTask, int(duration.Milliseconds())); completeErr != nil {
-				wp.log.ErrorContext(ctx, "failed to mark task as complete",
-					"task_id", task.GetID(),
-					"error", completeErr)
-			}
-		}
+		wp.finish(ctx, finishCtx, workerID, task, processedTask, processErr, context.Cause(processCtx), labels, duration)
 	}()
 
 	// Run pre-process hooks
@@ -456,8 +1001,13 @@ func (wp *WorkerPool[T]) work(ctx context.Context, workerID string) error {
 		"worker_id", workerID,
 		"task_id", task.GetID())
 
+	// Keep the lease alive while processing
+	stopHeartbeat := wp.startHeartbeat(processCtx, cancelProcess, workerID, task)
+	defer stopHeartbeat()
+
 	// Process with retry logic
-	processedTask, processErr = wp.processWithRetry(ctx, task)
+	processedTask, processErr = wp.processWithRetry(processCtx, task, labels)
+	stopHeartbeat()
 
 	// Log the outcome
 	if processErr != nil {
@@ -477,27 +1027,113 @@ func (wp *WorkerPool[T]) work(ctx context.Context, workerID string) error {
 	return nil
 }
 
-// processWithRetry handles retry logic with metrics (no panic recovery here)
-func (wp *WorkerPool[T]) processWithRetry(ctx context.Context, task T) (T, error) {
-	maxAttempts := wp.maxRetries
+// finish records how a task ended and hands it to the processor. cause is why its processing was
+// cancelled, if it was: a lost lease leaves the task to the queue, a requested cancellation
+// cancels it and the drain deadline abandons it. Otherwise the task is completed or failed.
+func (wp *WorkerPool[T]) finish(ctx context.Context, finishCtx context.Context, workerID string, task T, processedTask T, processErr error, cause error, labels TaskLabels, duration time.Duration) {
+	// A worker that lost its lease no longer owns the task, someone else may be running it
+	if errors.Is(cause, ErrLeaseLost) {
+		wp.recordTaskFailed(labels, duration)
+		wp.log.WarnContext(ctx, "task lease lost, leaving task to the queue",
+			"worker_id", workerID,
+			"task_id", task.GetID())
+		return
+	}
+
+	// Cancelled on request, the task ends as cancelled rather than failed and retried
+	if errors.Is(cause, ErrTaskCancelled) {
+		wp.metrics.RecordTaskCancelled()
+		wp.recordTaskFailed(labels, duration)
+		wp.cancelTask(finishCtx, workerID, task)
+		return
+	}
+
+	// Cancelled at the drain deadline, hand the task back
+	if errors.Is(cause, ErrDrainTimeout) {
+		wp.abandoned.Add(1)
+		wp.recordTaskFailed(labels, duration)
+		wp.abandon(finishCtx, workerID, task)
+		return
+	}
+	if wp.ctx.Err() != nil {
+		wp.drained.Add(1)
+	}
+
+	// Handle result (error or success)
+	if processErr != nil {
+		wp.recordTaskFailed(labels, duration)
+		wp.failures.add(FailureRecord{
+			TaskID:     task.GetID(),
+			TaskType:   labels.TaskType,
+			WorkerID:   workerID,
+			Error:      processErr.Error(),
+			Attempts:   AttemptsOf(processErr),
+			DurationMs: duration.Milliseconds(),
+			FailedAt:   time.Now(),
+		})
+		if failErr := wp.processor.Fail(finishCtx, task, processErr); failErr != nil {
+			wp.log.ErrorContext(ctx, "failed to mark task as failed",
+				"task_id", task.GetID(),
+				"error", failErr)
+		}
+	} else {
+		wp.recordTaskCompleted(labels, duration)
+		if completeErr := wp.processor.Complete(finishCtx, processedTask, int(duration.Milliseconds())); completeErr != nil {
+			wp.log.ErrorContext(ctx, "failed to mark task as complete",
+				"task_id", task.GetID(),
+				"error", completeErr)
+		}
+	}
+}
+
+// recordTaskCompleted records a completed task in the pool-wide and the labelled metrics
+func (wp *WorkerPool[T]) recordTaskCompleted(labels TaskLabels, duration time.Duration) {
+	wp.metrics.RecordTaskCompleted(duration)
+	if wp.labelled != nil {
+		wp.labelled.RecordLabelledCompleted(labels, duration)
+	}
+}
+
+// recordTaskFailed records a failed task in the pool-wide and the labelled metrics
+func (wp *WorkerPool[T]) recordTaskFailed(labels TaskLabels, duration time.Duration) {
+	wp.metrics.RecordTaskFailed(duration)
+	if wp.labelled != nil {
+		wp.labelled.RecordLabelledFailed(labels, duration)
+	}
+}
+
+// processWithRetry handles retry logic with metrics (no panic recovery here).
+// Errors that are not retryable (see IsRetryable) fail the task straight away; a RetryAfter hint
+// overrides the backoff policy for the next attempt. Failures are returned as a *ProcessError
+// holding every attempt.
+func (wp *WorkerPool[T]) processWithRetry(ctx context.Context, task T, labels TaskLabels) (T, error) {
+	policy := wp.policyFor(task)
+	maxAttempts := policy.MaxRetries
 	if maxAttempts <= 0 {
 		maxAttempts = 1
 	}
-	initialDelay := 1 * time.Second
 
 	var lastErr error
 	var processedTask T
+	var attempts []Attempt
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		if attempt > 1 {
+			delay, hinted := retryAfterHint(lastErr)
+			if !hinted {
+				delay = policy.Backoff.Delay(attempt - 1)
+			}
+
 			wp.metrics.RecordRetryAttempt()
+			if wp.labelled != nil {
+				wp.labelled.RecordLabelledRetry(labels)
+			}
 			wp.log.InfoContext(ctx, "retrying task",
 				"task_id", task.GetID(),
 				"attempt", attempt,
-				"max_attempts", maxAttempts)
+				"max_attempts", maxAttempts,
+				"delay", delay)
 
-			// Exponential backoff
-			delay := initialDelay * time.Duration(1<<(attempt-2))
 			select {
 			case <-ctx.Done():
 				return processedTask, ctx.Err()
@@ -505,8 +1141,8 @@ func (wp *WorkerPool[T]) processWithRetry(ctx context.Context, task T) (T, error
 			}
 		}
 
-		// Just call processor.Process directly - panic recovery is at the top level
-		processedTask, lastErr = wp.processor.Process(ctx, task)
+		startedAt := time.Now()
+		processedTask, lastErr = wp.processAttempt(ctx, labels.WorkerID, task, policy.Timeout)
 
 		if lastErr == nil {
 			if attempt > 1 {
@@ -519,18 +1155,386 @@ func (wp *WorkerPool[T]) processWithRetry(ctx context.Context, task T) (T, error
 			return processedTask, ctx.Err()
 		}
 
+		attempts = append(attempts, Attempt{
+			Number:    attempt,
+			StartedAt: startedAt,
+			FailedAt:  time.Now(),
+			Error:     lastErr.Error(),
+		})
 		wp.log.ErrorContext(ctx, "task processing attempt failed",
 			"task_id", task.GetID(),
 			"attempt", attempt,
 			"error", lastErr)
+
+		if !IsRetryable(lastErr) {
+			return processedTask, &ProcessError{
+				Attempts: attempts,
+				Err:      fmt.Errorf("failed permanently on attempt %d: %w", attempt, lastErr),
+			}
+		}
 	}
 
 	if maxAttempts > 1 {
 		wp.metrics.RecordRetryExhausted()
 	}
 
-	return processedTask, fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
+	return processedTask, &ProcessError{
+		Attempts: attempts,
+		Err:      fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr),
+	}
+}
+
+// policyFor returns the retry and timeout settings for a task: the pool's, overridden by the
+// processor's PolicyProvider and then by the task's own TimeoutTask
+func (wp *WorkerPool[T]) policyFor(task T) TaskPolicy {
+	policy := TaskPolicy{
+		MaxRetries: wp.maxRetries,
+		Backoff:    wp.backoff,
+		Timeout:    wp.taskTimeout,
+	}
+	if wp.policies != nil {
+		override := wp.policies.TaskPolicy(task)
+		if override.MaxRetries > 0 {
+			policy.MaxRetries = override.MaxRetries
+		}
+		if override.Backoff != nil {
+			policy.Backoff = override.Backoff
+		}
+		if override.Timeout > 0 {
+			policy.Timeout = override.Timeout
+		}
+	}
+	if tt, ok := any(task).(TimeoutTask); ok && tt.TaskTimeout() > 0 {
+		policy.Timeout = tt.TaskTimeout()
+	}
+	return policy
+}
+
+// processAttempt runs one Process call, bounded by timeout. A call that overruns is cancelled and
+// waited for, so a retry never runs alongside it: the attempt fails with ErrTaskTimeout once
+// Process returns. If it doesn't return within the timeout grace, the attempt fails for good with
+// ErrTaskStuck and the worker is marked stuck until the call returns.
+func (wp *WorkerPool[T]) processAttempt(ctx context.Context, workerID string, task T, timeout time.Duration) (T, error) {
+	if timeout <= 0 {
+		// Just call processor.Process directly - panic recovery is at the top level
+		return wp.processor.Process(ctx, task)
+	}
+
+	attemptCtx, cancel := context.WithTimeoutCause(ctx, timeout, ErrTaskTimeout)
+	defer cancel()
+
+	done := make(chan attemptResult[T], 1)
+	go func() {
+		var r attemptResult[T]
+		defer func() {
+			r.panic = recover()
+			done <- r
+		}()
+		r.task, r.err = wp.processor.Process(attemptCtx, task)
+	}()
+
+	var r attemptResult[T]
+	select {
+	case r = <-done:
+	case <-attemptCtx.Done():
+		if ctx.Err() != nil {
+			// Cancelled from above (lost lease, drain deadline), let Process wind down as usual
+			r = <-done
+			break
+		}
+		wp.metrics.RecordTaskTimeout()
+		wp.log.WarnContext(ctx, "task attempt timed out, waiting for it to return",
+			"task_id", task.GetID(),
+			"timeout", timeout,
+			"grace", wp.timeoutGrace)
+
+		var grace <-chan time.Time
+		if wp.timeoutGrace > 0 {
+			timer := time.NewTimer(wp.timeoutGrace)
+			defer timer.Stop()
+			grace = timer.C
+		}
+		select {
+		case r = <-done:
+		case <-grace:
+			wp.stuck(workerID, task, done)
+			return task, Permanent(fmt.Errorf("%w after %s: %w", ErrTaskTimeout, timeout, ErrTaskStuck))
+		}
+
+		// Re-raise on the worker goroutine so the usual recovery handles it
+		if r.panic != nil {
+			panic(r.panic)
+		}
+		if r.err != nil {
+			return task, fmt.Errorf("%w after %s: %w", ErrTaskTimeout, timeout, r.err)
+		}
+		return task, fmt.Errorf("%w after %s", ErrTaskTimeout, timeout)
+	}
+
+	// Re-raise on the worker goroutine so the usual recovery handles it
+	if r.panic != nil {
+		panic(r.panic)
+	}
+	// Process noticed the deadline itself
+	if r.err != nil && ctx.Err() == nil && errors.Is(context.Cause(attemptCtx), ErrTaskTimeout) {
+		wp.metrics.RecordTaskTimeout()
+		return r.task, fmt.Errorf("%w after %s: %w", ErrTaskTimeout, timeout, r.err)
+	}
+	return r.task, r.err
+}
+
+// attemptResult is how a Process call run by processAttempt ended
+type attemptResult[T Task] struct {
+	task  T
+	err   error
+	panic any
+}
+
+// stuck marks the worker as stuck on a Process call that didn't return within the timeout grace.
+// The call is watched until it returns, when its panic (if any) is reported and the worker is
+// let go.
+func (wp *WorkerPool[T]) stuck(workerID string, task T, done <-chan attemptResult[T]) {
+	wp.log.ErrorContext(context.Background(), "task did not return after its timeout, worker is stuck",
+		"worker_id", workerID,
+		"task_id", task.GetID(),
+		"grace", wp.timeoutGrace)
+
+	returned := make(chan struct{})
+	if handle := wp.handle(workerID); handle != nil {
+		handle.setStuck(returned)
+	}
+	go func() {
+		defer close(returned)
+		r := <-done
+		if r.panic != nil {
+			wp.log.ErrorContext(context.Background(), "panic recovered in stuck task",
+				"worker_id", workerID,
+				"task_id", task.GetID(),
+				"panic", r.panic)
+			wp.metrics.RecordWorkerPanic()
+			return
+		}
+		wp.log.WarnContext(context.Background(), "stuck task returned",
+			"worker_id", workerID,
+			"task_id", task.GetID())
+	}()
 }
+
 func (wp *WorkerPool[T]) GetMetrics() MetricsSnapshot {
 	return wp.metrics.GetSnapshot()
 }
+
+// checkout claims the next task, leasing it when the processor supports leases
+func (wp *WorkerPool[T]) checkout(ctx context.Context, workerID string) (T, error) {
+	if wp.leaser != nil {
+		return wp.leaser.CheckoutLeased(ctx, workerID, wp.leaseDuration)
+	}
+	return wp.processor.Checkout(ctx, workerID)
+}
+
+// startHeartbeat extends the task's lease every heartbeatInterval until the returned stop function
+// is called. If the lease is lost, the process context is cancelled with ErrLeaseLost, and if the
+// task was cancelled with ErrTaskCancelled.
+// The stop function is safe to call more than once.
+func (wp *WorkerPool[T]) startHeartbeat(ctx context.Context, cancel context.CancelCauseFunc, workerID string, task T) func() {
+	if wp.leaser == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(wp.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := wp.leaser.ExtendLease(ctx, task, workerID, wp.leaseDuration)
+				if err == nil {
+					continue
+				}
+				if errors.Is(err, ErrLeaseLost) {
+					cancel(ErrLeaseLost)
+					return
+				}
+				if errors.Is(err, ErrTaskCancelled) {
+					wp.log.InfoContext(ctx, "task cancellation requested",
+						"worker_id", workerID,
+						"task_id", task.GetID())
+					cancel(ErrTaskCancelled)
+					return
+				}
+				// Transient failures are retried on the next beat; the lease outlives a few misses
+				wp.log.ErrorContext(ctx, "failed to extend task lease",
+					"worker_id", workerID,
+					"task_id", task.GetID(),
+					"error", err)
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// reaper periodically returns tasks with expired leases to the queue
+func (wp *WorkerPool[T]) reaper() {
+	defer wp.background.Done()
+
+	ticker := time.NewTicker(wp.reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wp.ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := wp.leaser.ReapExpired(wp.ctx)
+			if err != nil {
+				if wp.ctx.Err() == nil {
+					wp.log.ErrorContext(wp.ctx, "failed to reap expired leases",
+						"pool", wp.name,
+						"error", err)
+				}
+				continue
+			}
+			if count > 0 {
+				wp.log.InfoContext(wp.ctx, "reclaimed tasks with expired leases",
+					"pool", wp.name,
+					"count", count)
+			}
+		}
+	}
+}
+
+// listen runs the notifier, re-subscribing with a backoff when it fails
+func (wp *WorkerPool[T]) listen() {
+	defer wp.background.Done()
+
+	backoff := ExponentialBackoff(1*time.Second, 30*time.Second)
+	for failures := 0; ; {
+		err := wp.notifier.Listen(wp.ctx, wp.wakeWorkers)
+		if wp.ctx.Err() != nil {
+			return
+		}
+
+		failures++
+		delay := backoff.Delay(failures)
+		wp.log.ErrorContext(wp.ctx, "notifier stopped, falling back to polling until it reconnects",
+			"pool", wp.name,
+			"retry_in", delay,
+			"error", err)
+
+		select {
+		case <-wp.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		// Work may have arrived while the notifier was down
+		wp.wakeWorkers()
+	}
+}
+
+// wakeWorkers wakes up to workerCount idle workers without blocking
+func (wp *WorkerPool[T]) wakeWorkers() {
+	for i := 0; i < wp.WorkerCount(); i++ {
+		select {
+		case wp.wake <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
+
+// drainWatch cancels in-flight tasks once the pool has been stopping for longer than the drain
+// timeout. done is closed when all workers have returned.
+func (wp *WorkerPool[T]) drainWatch(done <-chan struct{}) {
+	defer wp.background.Done()
+
+	select {
+	case <-done:
+		return
+	case <-wp.ctx.Done():
+	}
+
+	if wp.drainTimeout <= 0 {
+		wp.cancelTasks(ErrDrainTimeout)
+		return
+	}
+
+	wp.log.InfoContext(context.Background(), "draining in-flight tasks",
+		"pool", wp.name,
+		"timeout", wp.drainTimeout)
+
+	timer := time.NewTimer(wp.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		wp.log.WarnContext(context.Background(), "drain deadline exceeded, cancelling in-flight tasks",
+			"pool", wp.name,
+			"timeout", wp.drainTimeout)
+		wp.cancelTasks(ErrDrainTimeout)
+	}
+}
+
+// abandon hands a task cancelled at the drain deadline back to the queue, or fails it when the
+// processor can't release tasks
+func (wp *WorkerPool[T]) abandon(ctx context.Context, workerID string, task T) {
+	if wp.releaser != nil {
+		if err := wp.releaser.Release(ctx, task); err != nil {
+			wp.log.ErrorContext(ctx, "failed to release abandoned task",
+				"worker_id", workerID,
+				"task_id", task.GetID(),
+				"error", err)
+			return
+		}
+		wp.log.WarnContext(ctx, "released task abandoned at the drain deadline",
+			"worker_id", workerID,
+			"task_id", task.GetID())
+		return
+	}
+
+	if err := wp.processor.Fail(ctx, task, ErrDrainTimeout); err != nil {
+		wp.log.ErrorContext(ctx, "failed to mark abandoned task as failed",
+			"task_id", task.GetID(),
+			"error", err)
+	}
+}
+
+// cancelTask records a task cancelled on request, or fails it when the processor can't
+// cancel tasks. The failure isn't retryable, a cancelled task shouldn't run again.
+func (wp *WorkerPool[T]) cancelTask(ctx context.Context, workerID string, task T) {
+	if wp.canceller != nil {
+		if err := wp.canceller.Cancel(ctx, task); err != nil {
+			wp.log.ErrorContext(ctx, "failed to mark task as cancelled",
+				"worker_id", workerID,
+				"task_id", task.GetID(),
+				"error", err)
+			return
+		}
+		wp.log.InfoContext(ctx, "task cancelled",
+			"worker_id", workerID,
+			"task_id", task.GetID())
+		return
+	}
+
+	if err := wp.processor.Fail(ctx, task, Permanent(ErrTaskCancelled)); err != nil {
+		wp.log.ErrorContext(ctx, "failed to mark cancelled task as failed",
+			"task_id", task.GetID(),
+			"error", err)
+	}
+}
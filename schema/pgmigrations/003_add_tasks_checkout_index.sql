@@ -0,0 +1,7 @@
+-- =============================================================================
+-- Tasks Checkout Index
+-- Supports the worker queue checkout, which scans pending tasks by priority
+-- and age using FOR UPDATE SKIP LOCKED.
+-- =============================================================================
+
+CREATE INDEX idx_tasks_checkout ON tasks (priority DESC, created_at) WHERE processing_status = 'pending';
@@ -0,0 +1,12 @@
+-- =============================================================================
+-- Task Leases
+-- A checked out task is leased to a worker until lease_expires_at. Workers
+-- heartbeat to extend the lease; tasks whose lease expires (e.g. the worker
+-- crashed) are reclaimed and returned to the queue.
+-- =============================================================================
+
+ALTER TABLE tasks
+    ADD COLUMN locked_by VARCHAR(255),           -- Worker holding the lease
+    ADD COLUMN lease_expires_at TIMESTAMP;       -- When the lease lapses
+
+CREATE INDEX idx_tasks_lease_expiry ON tasks (lease_expires_at) WHERE processing_status = 'processing';
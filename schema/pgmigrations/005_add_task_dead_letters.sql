@@ -0,0 +1,15 @@
+-- =============================================================================
+-- Task Dead Letters
+-- Tasks that use up their retries (or fail permanently) are moved to the
+-- 'dead' status instead of being dropped as 'failed'. error_history keeps
+-- every failed attempt so dead tasks can be inspected, requeued or purged.
+-- =============================================================================
+
+ALTER TABLE tasks
+    ADD COLUMN error_history JSONB,              -- Failed attempts: error, worker and timestamps
+    ADD COLUMN dead_at TIMESTAMP;                -- When the task was dead-lettered
+
+-- Tasks that exhausted their retries before this migration are dead letters too
+UPDATE tasks SET processing_status = 'dead', dead_at = updated_at WHERE processing_status = 'failed';
+
+CREATE INDEX idx_tasks_dead ON tasks (dead_at) WHERE processing_status = 'dead';
@@ -0,0 +1,11 @@
+-- =============================================================================
+-- Task Scheduling
+-- run_at delays a task: Checkout skips pending tasks until run_at has passed.
+-- NULL means the task can run right away. Recurring tasks are materialized by
+-- the scheduler with a deterministic task_id, which makes them exactly-once.
+-- =============================================================================
+
+ALTER TABLE tasks
+    ADD COLUMN run_at TIMESTAMP;                 -- Not before; NULL = as soon as possible
+
+CREATE INDEX idx_tasks_run_at ON tasks (run_at) WHERE processing_status = 'pending';
@@ -0,0 +1,19 @@
+-- =============================================================================
+-- Task Notifications
+-- NOTIFY tasks_new whenever a task becomes pending and due, so listening
+-- worker pools wake up immediately instead of waiting for their next poll.
+-- Covers inserts as well as retries, reclaimed leases and requeued dead tasks.
+-- =============================================================================
+
+CREATE OR REPLACE FUNCTION notify_tasks_new() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('tasks_new', NEW.task_type);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_notify_new
+    AFTER INSERT OR UPDATE OF processing_status ON tasks
+    FOR EACH ROW
+    WHEN (NEW.processing_status = 'pending' AND (NEW.run_at IS NULL OR NEW.run_at <= now()))
+    EXECUTE FUNCTION notify_tasks_new();
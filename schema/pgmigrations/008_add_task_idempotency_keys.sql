@@ -0,0 +1,14 @@
+-- =============================================================================
+-- Task Idempotency Keys
+-- Producers tag a task with an idempotency_key (scoped to its task_type) so a
+-- repeated enqueue returns the existing task instead of creating another one.
+-- A key is taken while its task is pending or processing, or until
+-- idempotency_expires_at when that is set. Uniqueness is enforced by the
+-- store under an advisory lock, the window can't be expressed as an index.
+-- =============================================================================
+
+ALTER TABLE tasks
+    ADD COLUMN idempotency_key VARCHAR(255),        -- Producer supplied de-duplication key
+    ADD COLUMN idempotency_expires_at TIMESTAMP;    -- Key stays taken until then; NULL = while pending or processing
+
+CREATE INDEX idx_tasks_idempotency_key ON tasks (task_type, idempotency_key) WHERE idempotency_key IS NOT NULL;
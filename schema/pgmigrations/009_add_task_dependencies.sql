@@ -0,0 +1,49 @@
+-- =============================================================================
+-- Task Dependencies
+-- A task can wait on other tasks ("run B and C after A, then D after both").
+-- Checkout only hands out a task once every task it depends on is completed
+-- (or skipped). When a parent is dead-lettered or cancelled, the children are
+-- settled by the dependency's on_failure rule:
+--   cancel  the child is cancelled, which fails its own children in turn
+--   skip    the child is skipped, its own children carry on without it
+-- Tasks created together share a workflow_id so their progress can be viewed
+-- as one workflow.
+-- =============================================================================
+
+ALTER TABLE tasks
+    ADD COLUMN workflow_id VARCHAR(255);            -- Groups the tasks of a workflow
+
+CREATE INDEX idx_tasks_workflow ON tasks (workflow_id) WHERE workflow_id IS NOT NULL;
+
+CREATE TABLE task_dependencies (
+    task_id VARCHAR NOT NULL,                       -- The task that waits
+    depends_on_task_id VARCHAR NOT NULL,            -- The task it waits for
+    on_failure VARCHAR(20) NOT NULL DEFAULT 'cancel', -- cancel or skip the task when its parent fails
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CONSTRAINT task_dependencies_pk PRIMARY KEY (task_id, depends_on_task_id),
+    CONSTRAINT task_dependencies_task_fk FOREIGN KEY (task_id) REFERENCES tasks (task_id) ON DELETE CASCADE,
+    CONSTRAINT task_dependencies_parent_fk FOREIGN KEY (depends_on_task_id) REFERENCES tasks (task_id) ON DELETE CASCADE,
+    CONSTRAINT task_dependencies_on_failure_check CHECK (on_failure IN ('cancel', 'skip')),
+    CONSTRAINT task_dependencies_not_self CHECK (task_id <> depends_on_task_id)
+);
+
+-- Failure propagation and dependent notifications look up children by parent
+CREATE INDEX idx_task_dependencies_parent ON task_dependencies (depends_on_task_id);
+
+-- Children waiting on a task become due when it completes or is skipped, wake
+-- the listening pools the same way tasks_notify_new does for new tasks
+CREATE OR REPLACE FUNCTION notify_tasks_dependents() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('tasks_new', child.task_type)
+    FROM task_dependencies d
+    JOIN tasks child ON child.task_id = d.task_id
+    WHERE d.depends_on_task_id = NEW.task_id AND child.processing_status = 'pending';
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_notify_dependents
+    AFTER UPDATE OF processing_status ON tasks
+    FOR EACH ROW
+    WHEN (NEW.processing_status IN ('completed', 'skipped'))
+    EXECUTE FUNCTION notify_tasks_dependents();
@@ -0,0 +1,10 @@
+-- =============================================================================
+-- Task Type Indexes
+-- Fair checkout looks for ready tasks per task_type (or per tenant, from
+-- metadata) and counts the processing tasks of each type that has a
+-- concurrency limit. Partial indexes keep both lookups to the live rows.
+-- =============================================================================
+
+CREATE INDEX idx_tasks_pending_type ON tasks (task_type, priority, created_at) WHERE processing_status = 'pending';
+
+CREATE INDEX idx_tasks_processing_type ON tasks (task_type) WHERE processing_status = 'processing';
@@ -0,0 +1,25 @@
+-- =============================================================================
+-- Limiters
+-- State for rate and concurrency limits shared by every worker process, e.g.
+-- one quota for a third-party API or two tasks at once per customer.
+--   rate_limits         a token bucket per key, refilled from the elapsed
+--                       time on every take
+--   concurrency_leases  a row per holder of a slot; leases that are not
+--                       extended expire, so a crashed worker frees its slot
+-- =============================================================================
+
+CREATE TABLE rate_limits (
+    limit_key VARCHAR(255) PRIMARY KEY,             -- Limiter name and key
+    tokens DOUBLE PRECISION NOT NULL,               -- Tokens left as of updated_at, negative while callers wait
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE concurrency_leases (
+    lease_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    limit_key VARCHAR(255) NOT NULL,                -- Limiter name and key
+    expires_at TIMESTAMP NOT NULL,                  -- Holder must extend the lease before this
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Acquire counts the live leases of a key
+CREATE INDEX idx_concurrency_leases_key ON concurrency_leases (limit_key, expires_at);
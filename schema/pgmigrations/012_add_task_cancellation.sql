@@ -0,0 +1,10 @@
+-- =============================================================================
+-- Task Cancellation
+-- A pending task is cancelled straight away. A processing task can't be
+-- stopped from the database, so cancel_requested_at flags it instead: the
+-- worker holding it sees the flag on its next lease heartbeat, cancels the run
+-- and marks the task cancelled. Cancelled tasks are not retried.
+-- =============================================================================
+
+ALTER TABLE tasks
+    ADD COLUMN cancel_requested_at TIMESTAMP;    -- Set while a processing task waits to be cancelled
@@ -0,0 +1,12 @@
+-- =============================================================================
+-- Task Progress
+-- Long-running tasks report how far they have got and save checkpoints while
+-- they run. A run after a failure is handed the last checkpoint so it resumes
+-- instead of starting over. Progress is kept after the task finishes.
+-- =============================================================================
+
+ALTER TABLE tasks
+    ADD COLUMN progress_percent DOUBLE PRECISION, -- 0 to 100, as last reported
+    ADD COLUMN progress_step VARCHAR(255),        -- What the task was doing, as last reported
+    ADD COLUMN progress_updated_at TIMESTAMP,     -- When progress was last reported
+    ADD COLUMN checkpoint BYTEA;                  -- Opaque resume point saved by the task
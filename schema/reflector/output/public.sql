@@ -1,9 +1,31 @@
 -- =============================================================================
 -- Schema Reflection: postgres.public
--- Reflected at: 2025-10-19 20:42:36
--- Tables: 4
+-- Reflected at: 2026-10-16 15:00:00
+-- Tables: 7
 -- =============================================================================
 
+-- -----------------------------------------------------------------------------
+-- Table: concurrency_leases
+-- -----------------------------------------------------------------------------
+CREATE TABLE public.concurrency_leases (
+    lease_id uuid NOT NULL DEFAULT gen_random_uuid(),
+    limit_key varchar(255) NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (lease_id)
+);
+CREATE INDEX idx_concurrency_leases_key ON public.concurrency_leases USING btree (limit_key, expires_at);
+
+-- -----------------------------------------------------------------------------
+-- Table: rate_limits
+-- -----------------------------------------------------------------------------
+CREATE TABLE public.rate_limits (
+    limit_key varchar(255) NOT NULL,
+    tokens float8 NOT NULL,
+    updated_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (limit_key)
+);
+
 -- -----------------------------------------------------------------------------
 -- Table: schema_migrations
 -- -----------------------------------------------------------------------------
@@ -16,6 +38,22 @@ CREATE TABLE public.schema_migrations (
     PRIMARY KEY (version)
 );
 
+-- -----------------------------------------------------------------------------
+-- Table: task_dependencies
+-- -----------------------------------------------------------------------------
+CREATE TABLE public.task_dependencies (
+    task_id varchar NOT NULL,
+    depends_on_task_id varchar NOT NULL,
+    on_failure varchar(20) NOT NULL DEFAULT cancel,
+    created_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (task_id),
+    FOREIGN KEY (task_id) REFERENCES public.tasks(task_id) ON DELETE CASCADE,
+    FOREIGN KEY (depends_on_task_id) REFERENCES public.tasks(task_id) ON DELETE CASCADE,
+    CONSTRAINT task_dependencies_not_self CHECK (((task_id)::text <> (depends_on_task_id)::text)),
+    CONSTRAINT task_dependencies_on_failure_check CHECK (((on_failure)::text = ANY ((ARRAY['cancel'::character varying, 'skip'::character varying])::text[])))
+);
+CREATE INDEX idx_task_dependencies_parent ON public.task_dependencies USING btree (depends_on_task_id);
+
 -- -----------------------------------------------------------------------------
 -- Table: tasks
 -- -----------------------------------------------------------------------------
@@ -32,8 +70,29 @@ CREATE TABLE public.tasks (
     error_message text,
     processing_time_ms int4,
     last_run_at timestamp,
+    locked_by varchar(255),
+    lease_expires_at timestamp,
+    error_history jsonb,
+    dead_at timestamp,
+    run_at timestamp,
+    idempotency_key varchar(255),
+    idempotency_expires_at timestamp,
+    workflow_id varchar(255),
+    cancel_requested_at timestamp,
+    progress_percent float8,
+    progress_step varchar(255),
+    progress_updated_at timestamp,
+    checkpoint bytea,
     PRIMARY KEY (task_id)
 );
+CREATE INDEX idx_tasks_checkout ON public.tasks USING btree (priority, created_at);
+CREATE INDEX idx_tasks_dead ON public.tasks USING btree (dead_at);
+CREATE INDEX idx_tasks_idempotency_key ON public.tasks USING btree (task_type, idempotency_key);
+CREATE INDEX idx_tasks_lease_expiry ON public.tasks USING btree (lease_expires_at);
+CREATE INDEX idx_tasks_pending_type ON public.tasks USING btree (task_type, priority, created_at);
+CREATE INDEX idx_tasks_processing_type ON public.tasks USING btree (task_type);
+CREATE INDEX idx_tasks_run_at ON public.tasks USING btree (run_at);
+CREATE INDEX idx_tasks_workflow ON public.tasks USING btree (workflow_id);
 
 -- -----------------------------------------------------------------------------
 -- Table: user_sessions
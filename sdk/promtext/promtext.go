@@ -0,0 +1,212 @@
+// Package promtext renders metrics in the Prometheus text exposition format, without pulling
+// in the Prometheus client library. Collectors write samples to a Writer on every scrape; the
+// Writer groups them into metric families so several collectors (e.g. one per worker pool) can
+// write to the same metric with different labels.
+package promtext
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Label is a name/value pair that identifies a sample within its metric
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Bucket is a histogram bucket: Count observations were less than or equal to UpperBound
+// (cumulative, as Prometheus expects)
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Histogram is a histogram sample. The +Inf bucket is added when rendering from Count.
+type Histogram struct {
+	Buckets []Bucket
+	Count   uint64
+	Sum     float64
+}
+
+// Collector writes its current samples on every scrape
+type Collector interface {
+	Collect(w *Writer)
+}
+
+// CollectorFunc adapts a plain function to a Collector
+type CollectorFunc func(w *Writer)
+
+// Collect calls f(w)
+func (f CollectorFunc) Collect(w *Writer) {
+	f(w)
+}
+
+// ================================================================================
+// Writer
+// ================================================================================
+
+// family is a metric and its samples
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples bytes.Buffer
+}
+
+// Writer collects samples grouped by metric. Metrics are rendered in the order they were first
+// written, the HELP and TYPE of the first write win.
+type Writer struct {
+	families []*family
+	byName   map[string]*family
+}
+
+// NewWriter creates an empty Writer
+func NewWriter() *Writer {
+	return &Writer{byName: make(map[string]*family)}
+}
+
+// Counter writes a sample of a counter. By convention counter names end in _total.
+func (w *Writer) Counter(name, help string, value float64, labels ...Label) {
+	w.sample(w.family(name, help, TypeCounter), name, labels, nil, value)
+}
+
+// Gauge writes a sample of a gauge
+func (w *Writer) Gauge(name, help string, value float64, labels ...Label) {
+	w.sample(w.family(name, help, TypeGauge), name, labels, nil, value)
+}
+
+// Histogram writes a histogram sample as its _bucket, _sum and _count series
+func (w *Writer) Histogram(name, help string, h Histogram, labels ...Label) {
+	f := w.family(name, help, TypeHistogram)
+	for _, bucket := range h.Buckets {
+		w.sample(f, name+"_bucket", labels, &Label{Name: "le", Value: formatFloat(bucket.UpperBound)}, float64(bucket.Count))
+	}
+	w.sample(f, name+"_bucket", labels, &Label{Name: "le", Value: "+Inf"}, float64(h.Count))
+	w.sample(f, name+"_sum", labels, nil, h.Sum)
+	w.sample(f, name+"_count", labels, nil, float64(h.Count))
+}
+
+// family returns the family of name, creating it on first use
+func (w *Writer) family(name, help, typ string) *family {
+	if f, ok := w.byName[name]; ok {
+		return f
+	}
+	f := &family{name: name, help: help, typ: typ}
+	w.families = append(w.families, f)
+	w.byName[name] = f
+	return f
+}
+
+// sample renders a sample line into f, with extra appended after labels (the le of a bucket)
+func (w *Writer) sample(f *family, name string, labels []Label, extra *Label, value float64) {
+	f.samples.WriteString(name)
+	if len(labels) > 0 || extra != nil {
+		f.samples.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				f.samples.WriteByte(',')
+			}
+			writeLabel(&f.samples, label)
+		}
+		if extra != nil {
+			if len(labels) > 0 {
+				f.samples.WriteByte(',')
+			}
+			writeLabel(&f.samples, *extra)
+		}
+		f.samples.WriteByte('}')
+	}
+	f.samples.WriteByte(' ')
+	f.samples.WriteString(formatFloat(value))
+	f.samples.WriteByte('\n')
+}
+
+// WriteTo renders every metric in the text format
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, f := range w.families {
+		buf.WriteString("# HELP " + f.name + " " + helpEscaper.Replace(f.help) + "\n")
+		buf.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		buf.Write(f.samples.Bytes())
+	}
+	return buf.WriteTo(out)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func writeLabel(buf *bytes.Buffer, label Label) {
+	buf.WriteString(label.Name)
+	buf.WriteString(`="`)
+	buf.WriteString(valueEscaper.Replace(label.Value))
+	buf.WriteByte('"')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ================================================================================
+// Registry
+// ================================================================================
+
+// Registry holds the collectors to scrape. It is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds collectors to the registry
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Gather collects every registered collector into a Writer
+func (r *Registry) Gather() *Writer {
+	r.mu.RLock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	w := NewWriter()
+	for _, collector := range collectors {
+		collector.Collect(w)
+	}
+	return w
+}
+
+// WriteTo gathers every registered collector and renders them in the text format
+func (r *Registry) WriteTo(out io.Writer) (int64, error) {
+	return r.Gather().WriteTo(out)
+}